@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"syscall/js"
+
+	"github.com/hulkholden/gowebgpu/client/browser"
+)
+
+// InitRenderCallback calls update before each frame is painted.
+func InitRenderCallback(update func()) {
+	frame := js.FuncOf(func(this js.Value, args []js.Value) any {
+		update()
+		InitRenderCallback(update)
+		return nil
+	})
+	browser.Window().RequestAnimationFrame(frame)
+}
+
+// showError displays msg using the page's showError hook, if it has one.
+func showError(msg string) {
+	if fn := js.Global().Get("showError"); fn.Type() == js.TypeFunction {
+		fn.Invoke(msg)
+	}
+}
+
+// showOverlay displays text over the canvas using the page's showOverlay hook, if it has one.
+func showOverlay(text string) {
+	if fn := js.Global().Get("showOverlay"); fn.Type() == js.TypeFunction {
+		fn.Invoke(text)
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !js
+
+package engine
+
+import "time"
+
+// frameInterval is the time between calls to the update function passed to InitRenderCallback.
+const frameInterval = time.Second / 60
+
+// InitRenderCallback calls update 60 times a second from a new goroutine.
+func InitRenderCallback(update func()) {
+	go func() {
+		ticker := time.NewTicker(frameInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			update()
+		}
+	}()
+}
+
+// showError does nothing, as there's no page to display msg on.
+func showError(msg string) {}
+
+// showOverlay does nothing, as there's no page to display text on.
+func showOverlay(text string) {}
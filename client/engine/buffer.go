@@ -1,28 +1,27 @@
 package engine
 
 import (
+	"fmt"
 	"reflect"
-	"syscall/js"
 
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
 	"github.com/hulkholden/gowebgpu/common/wgsltypes"
-	"github.com/mokiat/gog/opt"
-	"github.com/mokiat/wasmgpu"
 )
 
 type GPUBuffer[T any] struct {
-	device wasmgpu.GPUDevice
-	buffer wasmgpu.GPUBuffer
+	device gpu.Device
+	buffer gpu.Buffer
 	size   int
 
-	bindingType wasmgpu.GPUBufferBindingType
+	bindingType gpu.BufferBindingType
 	structDefs  []wgsltypes.Struct
-}
+	wgslType    wgsltypes.TypeName
 
-type DebugBuffer[T any] struct {
-	GPUBuffer[T]
+	// ring, if not nil, stages writes to the buffer rather than writing them with the device's queue.
+	ring *UploadRing
 }
 
-func (b GPUBuffer[T]) Buffer() wasmgpu.GPUBuffer {
+func (b GPUBuffer[T]) Buffer() gpu.Buffer {
 	return b.buffer
 }
 
@@ -30,122 +29,119 @@ func (b GPUBuffer[T]) StructDefs() []wgsltypes.Struct {
 	return b.structDefs
 }
 
-func (b GPUBuffer[T]) MakeBindGroupLayoutEntry(idx int) wasmgpu.GPUBindGroupLayoutEntry {
-	return wasmgpu.GPUBindGroupLayoutEntry{
-		Binding:    wasmgpu.GPUIndex32(idx),
-		Visibility: wasmgpu.GPUShaderStageFlagsCompute,
-		Buffer: opt.V(wasmgpu.GPUBufferBindingLayout{
-			Type: opt.V(b.bindingType),
-		}),
-	}
+// WGSLType returns the type of the variable the buffer should be bound to, e.g. "array<Body>".
+// It is empty if T has no WGSL equivalent.
+func (b GPUBuffer[T]) WGSLType() wgsltypes.TypeName {
+	return b.wgslType
+}
+
+func (b GPUBuffer[T]) BindingType() gpu.BufferBindingType {
+	return b.bindingType
 }
 
-func (b GPUBuffer[T]) MakeBindingGroupEntry(idx int) wasmgpu.GPUBindGroupEntry {
-	return wasmgpu.GPUBindGroupEntry{
-		Binding: wasmgpu.GPUIndex32(idx),
-		Resource: wasmgpu.GPUBufferBinding{
-			Buffer: b.Buffer(),
+func (b GPUBuffer[T]) MakeBindGroupLayoutEntry(idx int) gpu.BindGroupLayoutEntry {
+	return gpu.BindGroupLayoutEntry{
+		Binding:    idx,
+		Visibility: gpu.ShaderStageCompute,
+		Buffer: gpu.BufferBindingLayout{
+			Type: b.bindingType,
 		},
 	}
 }
 
-func (b GPUBuffer[T]) BufferSize() wasmgpu.GPUSize64 {
-	return wasmgpu.GPUSize64(b.size)
+func (b GPUBuffer[T]) MakeBindingGroupEntry(idx int) gpu.BindGroupEntry {
+	return gpu.BindGroupEntry{
+		Binding: idx,
+		Buffer:  b.Buffer(),
+	}
+}
+
+func (b GPUBuffer[T]) BufferSize() uint64 {
+	return uint64(b.size)
 }
 
 func (b GPUBuffer[T]) UpdateBufferStruct(value T) {
 	bytes := structAsByteSlice(value)
-	b.device.Queue().WriteBuffer(b.buffer, 0, bytes)
+	b.writer().WriteBuffer(b.buffer, 0, bytes)
 }
 
-func initBuffer(device wasmgpu.GPUDevice, usage wasmgpu.GPUBufferUsageFlags, data []byte, initContents bool, opts ...BufferOption) wasmgpu.GPUBuffer {
-	desc := wasmgpu.GPUBufferDescriptor{
-		Size:             wasmgpu.GPUSize64(len(data)),
-		Usage:            usage,
-		MappedAtCreation: opt.V(initContents),
+func initBuffer(device gpu.Device, usage gpu.BufferUsage, data []byte, initContents bool, opts ...BufferOption) gpu.Buffer {
+	desc := gpu.BufferDescriptor{
+		Size:  uint64(len(data)),
+		Usage: usage,
+	}
+	if initContents {
+		desc.Contents = data
 	}
 	for _, opt := range opts {
 		opt(&desc)
 	}
-	buffer := device.CreateBuffer(desc)
-	if initContents {
-		js.CopyBytesToJS(uint8ArrayCtor.New(buffer.GetMappedRange(0, 0)), data)
-		buffer.Unmap()
-	}
-	return buffer
+	return device.CreateBuffer(desc)
 }
 
-func registerStruct[T any]() []wgsltypes.Struct {
+// registerStruct registers T if it's a struct, and panics if it can't be used in the address space.
+func registerStruct[T any](space wgsltypes.AddressSpace) []wgsltypes.Struct {
 	var t T
 	structType := reflect.TypeOf(t)
 
 	if structType.Kind() != reflect.Struct {
 		return nil
 	}
-	return []wgsltypes.Struct{wgsltypes.MustRegisterStruct[T]()}
+	s := wgsltypes.MustRegisterStruct[T]()
+	if err := s.Validate(space); err != nil {
+		panic(fmt.Sprintf("using %T in the %s address space: %v", t, space, err))
+	}
+	return []wgsltypes.Struct{s}
 }
 
-func InitStorageBufferStruct[T any](device wasmgpu.GPUDevice, value T, opts ...BufferOption) GPUBuffer[T] {
+// wgslTypeName returns the name of the WGSL type for a buffer of T, or a runtime-sized array of T.
+// T must have been registered if it's a struct.
+func wgslTypeName[T any](runtimeArray bool) wgsltypes.TypeName {
+	t, err := wgsltypes.LookupType[T]()
+	if err != nil {
+		return ""
+	}
+	if runtimeArray {
+		return wgsltypes.TypeName(fmt.Sprintf("array<%s>", t.Name))
+	}
+	return t.Name
+}
+
+func InitStorageBufferStruct[T any](device gpu.Device, value T, opts ...BufferOption) GPUBuffer[T] {
 	data := structAsByteSlice(value)
-	buffer := initBuffer(device, wasmgpu.GPUBufferUsageFlagsStorage, data, true, opts...)
+	buffer := initBuffer(device, gpu.BufferUsageStorage, data, true, opts...)
 	return GPUBuffer[T]{
 		device:      device,
 		buffer:      buffer,
 		size:        len(data),
-		bindingType: wasmgpu.GPUBufferBindingTypeStorage,
-		structDefs:  registerStruct[T](),
+		bindingType: gpu.BufferBindingTypeStorage,
+		structDefs:  registerStruct[T](wgsltypes.AddressSpaceStorage),
+		wgslType:    wgslTypeName[T](false),
 	}
 }
 
-func InitStorageBufferSlice[T any](device wasmgpu.GPUDevice, values []T, opts ...BufferOption) GPUBuffer[T] {
+func InitStorageBufferSlice[T any](device gpu.Device, values []T, opts ...BufferOption) GPUBuffer[T] {
 	data := sliceAsBytesSlice(values)
-	buffer := initBuffer(device, wasmgpu.GPUBufferUsageFlagsStorage, data, true, opts...)
+	buffer := initBuffer(device, gpu.BufferUsageStorage, data, true, opts...)
 	return GPUBuffer[T]{
 		device:      device,
 		buffer:      buffer,
 		size:        len(data),
-		bindingType: wasmgpu.GPUBufferBindingTypeStorage,
-		structDefs:  registerStruct[T](),
+		bindingType: gpu.BufferBindingTypeStorage,
+		structDefs:  registerStruct[T](wgsltypes.AddressSpaceStorage),
+		wgslType:    wgslTypeName[T](true),
 	}
 }
 
-func InitUniformBuffer[T any](device wasmgpu.GPUDevice, value T, opts ...BufferOption) GPUBuffer[T] {
+func InitUniformBuffer[T any](device gpu.Device, value T, opts ...BufferOption) GPUBuffer[T] {
 	data := structAsByteSlice(value)
-	buffer := initBuffer(device, wasmgpu.GPUBufferUsageFlagsUniform, data, true, opts...)
+	buffer := initBuffer(device, gpu.BufferUsageUniform, data, true, opts...)
 	return GPUBuffer[T]{
 		device:      device,
 		buffer:      buffer,
 		size:        len(data),
-		bindingType: wasmgpu.GPUBufferBindingTypeUniform,
-		structDefs:  registerStruct[T](),
-	}
-}
-
-func InitDebugBuffer[T any](device wasmgpu.GPUDevice, values []T, opts ...BufferOption) DebugBuffer[T] {
-	data := sliceAsBytesSlice(values)
-	buffer := initBuffer(device, wasmgpu.GPUBufferUsageFlagsMapRead|wasmgpu.GPUBufferUsageFlagsCopyDst, data, false, opts...)
-	return DebugBuffer[T]{
-		GPUBuffer: GPUBuffer[T]{
-			device:      device,
-			buffer:      buffer,
-			size:        len(data),
-			bindingType: wasmgpu.GPUBufferBindingTypeStorage,
-			structDefs:  registerStruct[T](),
-		},
+		bindingType: gpu.BufferBindingTypeUniform,
+		structDefs:  registerStruct[T](wgsltypes.AddressSpaceUniform),
+		wgslType:    wgslTypeName[T](false),
 	}
 }
-
-func (b DebugBuffer[T]) ReadAsync(callback func(data []T)) {
-	promise := b.buffer.MapAsync(wasmgpu.GPUMapModeFlagsRead, 0, b.BufferSize())
-	promise.Call("then", js.FuncOf(func(this js.Value, args []js.Value) any {
-		ab := b.buffer.GetMappedRange(0, b.BufferSize())
-		abCopy := ab.Call("slice")
-		b.buffer.Unmap()
-
-		bytes := make([]byte, b.size)
-		numBytes := js.CopyBytesToGo(bytes, uint8ArrayCtor.New(abCopy))
-		typedData := byteSliceAsStructSlice[T](bytes[:numBytes])
-		callback(typedData)
-		return nil
-	}))
-}
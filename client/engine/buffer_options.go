@@ -1,35 +1,41 @@
 package engine
 
-import "github.com/mokiat/wasmgpu"
+import "github.com/hulkholden/gowebgpu/client/engine/gpu"
 
-type BufferOption func(d *wasmgpu.GPUBufferDescriptor)
+type BufferOption func(d *gpu.BufferDescriptor)
 
 func WithVertexUsage() BufferOption {
-	return func(d *wasmgpu.GPUBufferDescriptor) {
-		d.Usage |= wasmgpu.GPUBufferUsageFlagsVertex
+	return func(d *gpu.BufferDescriptor) {
+		d.Usage |= gpu.BufferUsageVertex
 	}
 }
 
 func WithCopySrcUsage() BufferOption {
-	return func(d *wasmgpu.GPUBufferDescriptor) {
-		d.Usage |= wasmgpu.GPUBufferUsageFlagsCopySrc
+	return func(d *gpu.BufferDescriptor) {
+		d.Usage |= gpu.BufferUsageCopySrc
 	}
 }
 
 func WithCopyDstUsage() BufferOption {
-	return func(d *wasmgpu.GPUBufferDescriptor) {
-		d.Usage |= wasmgpu.GPUBufferUsageFlagsCopyDst
+	return func(d *gpu.BufferDescriptor) {
+		d.Usage |= gpu.BufferUsageCopyDst
 	}
 }
 
 func WithMapReadUsage() BufferOption {
-	return func(d *wasmgpu.GPUBufferDescriptor) {
-		d.Usage |= wasmgpu.GPUBufferUsageFlagsMapRead
+	return func(d *gpu.BufferDescriptor) {
+		d.Usage |= gpu.BufferUsageMapRead
 	}
 }
 
 func WithMapWriteUsage() BufferOption {
-	return func(d *wasmgpu.GPUBufferDescriptor) {
-		d.Usage |= wasmgpu.GPUBufferUsageFlagsMapWrite
+	return func(d *gpu.BufferDescriptor) {
+		d.Usage |= gpu.BufferUsageMapWrite
+	}
+}
+
+func WithIndirectUsage() BufferOption {
+	return func(d *gpu.BufferDescriptor) {
+		d.Usage |= gpu.BufferUsageIndirect
 	}
 }
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+)
+
+type bufferTestParams struct {
+	scale  float32
+	offset float32
+}
+
+func TestBuffers(t *testing.T) {
+	device := gpufake.NewDevice()
+	params := InitUniformBuffer(device, bufferTestParams{scale: 2}, WithCopyDstUsage())
+	values := InitStorageBufferSlice(device, []vmath.V2{{X: 1, Y: 2}, {X: 3, Y: 4}}, WithVertexUsage(), WithCopySrcUsage())
+
+	tests := []struct {
+		name      string
+		buffer    gpu.Buffer
+		wantUsage gpu.BufferUsage
+		wantData  []byte
+	}{
+		{
+			name:      "uniform",
+			buffer:    params.Buffer(),
+			wantUsage: gpu.BufferUsageUniform | gpu.BufferUsageCopyDst,
+			wantData:  []byte{0, 0, 0, 0x40, 0, 0, 0, 0},
+		},
+		{
+			name:      "storage",
+			buffer:    values.Buffer(),
+			wantUsage: gpu.BufferUsageStorage | gpu.BufferUsageVertex | gpu.BufferUsageCopySrc,
+			wantData:  sliceAsBytesSlice([]float32{1, 2, 3, 4}),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := tc.buffer.(*gpufake.Buffer)
+			if b.Desc.Usage != tc.wantUsage {
+				t.Errorf("Usage = %#x, want %#x", b.Desc.Usage, tc.wantUsage)
+			}
+			if diff := cmp.Diff(tc.wantData, b.Data); diff != "" {
+				t.Errorf("Data diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	params.UpdateBufferStruct(bufferTestParams{scale: 1, offset: -1})
+	wantWrites := []gpufake.Write{
+		{Buffer: params.Buffer().(*gpufake.Buffer), Data: sliceAsBytesSlice([]float32{1, -1})},
+	}
+	if diff := cmp.Diff(wantWrites, device.Writes); diff != "" {
+		t.Errorf("Writes diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+)
+
+// A CompilationMessage is a message reported when compiling a shader module.
+type CompilationMessage struct {
+	// Type is "error", "warning" or "info".
+	Type string
+	// Message is the text of the message.
+	Message string
+	// Location is the line in the original source file the message refers to.
+	// It's the zero value if the message doesn't refer to a line.
+	Location wgsl.Location
+	// Column is the 1-based column of the message in the line, or 0 if unknown.
+	Column int
+}
+
+func (m CompilationMessage) String() string {
+	switch {
+	case m.Location.File == "":
+		return fmt.Sprintf("%s: %s", m.Type, m.Message)
+	case m.Column > 0:
+		return fmt.Sprintf("%s:%d: %s: %s", m.Location, m.Column, m.Type, m.Message)
+	default:
+		return fmt.Sprintf("%s: %s: %s", m.Location, m.Type, m.Message)
+	}
+}
+
+// A CompilationError is returned when a shader module fails to compile.
+type CompilationError struct {
+	// Name is the name of the shader's source file.
+	Name string
+	// Messages holds the errors reported by the compiler.
+	Messages []CompilationMessage
+}
+
+func (e *CompilationError) Error() string {
+	lines := make([]string, len(e.Messages))
+	for i, m := range e.Messages {
+		lines[i] = m.String()
+	}
+	return fmt.Sprintf("compiling %s:\n%s", e.Name, strings.Join(lines, "\n"))
+}
+
+// compilation tracks the asynchronous compilation of a shader module.
+type compilation struct {
+	done chan struct{}
+	err  error
+}
+
+// watchCompilation requests the compilation info for the module, whose source is src.
+// Line numbers in messages are mapped back to the original source files.
+// Warnings are logged, and errors are logged and passed to the page's showError hook.
+// If the compilation info can't be retrieved, that error is reported in the same way.
+func watchCompilation(module gpu.ShaderModule, name string, src wgsl.Source) *compilation {
+	c := &compilation{done: make(chan struct{})}
+	module.GetCompilationInfo(func(messages []gpu.CompilationMessage, err error) {
+		defer close(c.done)
+
+		if err != nil {
+			c.err = fmt.Errorf("getting compilation info for %s: %w", name, err)
+			log.Print(c.err)
+			showError(c.err.Error())
+			return
+		}
+		var errs []CompilationMessage
+		for _, gm := range messages {
+			m := newCompilationMessage(gm, src)
+			if m.Type != "error" {
+				log.Printf("compiling %s: %s", name, m)
+				continue
+			}
+			errs = append(errs, m)
+		}
+		if len(errs) > 0 {
+			c.err = &CompilationError{Name: name, Messages: errs}
+			log.Print(c.err)
+			showError(c.err.Error())
+		}
+	})
+	return c
+}
+
+// newCompilationMessage maps the line of a message reported by the GPU back to the original source.
+func newCompilationMessage(gm gpu.CompilationMessage, src wgsl.Source) CompilationMessage {
+	m := CompilationMessage{
+		Type:    gm.Type,
+		Message: gm.Message,
+	}
+	// LineNum is 0 if the message doesn't correspond to a line.
+	if loc, ok := src.Location(gm.LineNum); ok {
+		m.Location = loc
+		m.Column = gm.LinePos
+	}
+	return m
+}
+
+// wait blocks until compilation has finished and returns a *CompilationError if it failed, or the error
+// retrieving the compilation info.
+func (c *compilation) wait() error {
+	if c == nil {
+		return nil
+	}
+	<-c.done
+	return c.err
+}
@@ -1,77 +1,360 @@
 package engine
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
 	"github.com/hulkholden/gowebgpu/common/wgsltypes"
-	"github.com/mokiat/gog/opt"
-	"github.com/mokiat/wasmgpu"
 )
 
-type ComputePass func(commandEncoder wasmgpu.GPUCommandEncoder)
+type ComputePass func(commandEncoder gpu.CommandEncoder)
 
 type ComputePassBuffer interface {
 	StructDefs() []wgsltypes.Struct
-	MakeBindGroupLayoutEntry(idx int) wasmgpu.GPUBindGroupLayoutEntry
-	MakeBindingGroupEntry(idx int) wasmgpu.GPUBindGroupEntry
+	WGSLType() wgsltypes.TypeName
+	BindingType() gpu.BufferBindingType
+	MakeBindGroupLayoutEntry(idx int) gpu.BindGroupLayoutEntry
+	MakeBindingGroupEntry(idx int) gpu.BindGroupEntry
 }
 
 type ComputePassFactory struct {
-	device                wasmgpu.GPUDevice
-	computeShaderModule   wasmgpu.GPUShaderModule
-	computePassDescriptor wasmgpu.GPUComputePassDescriptor
+	device                gpu.Device
+	computeShaderModule   ShaderModule
+	computePassDescriptor gpu.ComputePassDescriptor
+
+	// groups[g] holds the buffers bound to @group(g), or is nil if there are none.
+	groups []*passGroup
 
-	layout           wasmgpu.GPUPipelineLayout
-	bindGroupEntries []wasmgpu.GPUBindGroupEntry
+	code        string
+	bindings    []wgsl.Binding
+	entryPoints []wgsl.EntryPoint
+	parseErr    error
 }
 
-func NewComputePassFactory(device wasmgpu.GPUDevice, computeShaderCode string, extraStructDefinitions []wgsltypes.Struct, buffers []ComputePassBuffer) ComputePassFactory {
-	structDefinitions := []wgsltypes.Struct{}
-	for _, b := range buffers {
-		structDefinitions = append(structDefinitions, b.StructDefs()...)
+// A passGroup holds the buffers bound to one @group, and the layout and bind groups shared by every pass which uses it.
+type passGroup struct {
+	buffers     []ComputePassBuffer
+	bindingIdxs []int
+	layout      gpu.BindGroupLayout
+	bindGroups  bindGroupSet
+}
+
+// bufferAt returns the buffer bound to @binding(binding), or nil if there is none.
+func (g *passGroup) bufferAt(binding int) ComputePassBuffer {
+	for i, idx := range g.bindingIdxs {
+		if idx == binding {
+			return g.buffers[i]
+		}
 	}
-	structDefinitions = append(structDefinitions, extraStructDefinitions...)
+	return nil
+}
 
-	computeShaderModule := InitShaderModule(device, computeShaderCode, structDefinitions)
+// NewComputePassFactory creates a factory where buffers[i] is bound to @group(0) @binding(i).
+// Panics if the shader can't be preprocessed.
+func NewComputePassFactory(device gpu.Device, computeShaderCode string, buffers []ComputePassBuffer, opts ...ShaderModuleOption) ComputePassFactory {
+	return NewGroupedComputePassFactory(device, computeShaderCode, [][]ComputePassBuffer{buffers}, opts...)
+}
 
-	bindGroupEntries := make([]wasmgpu.GPUBindGroupEntry, len(buffers))
-	bindGroupLayoutEntries := make([]wasmgpu.GPUBindGroupLayoutEntry, len(buffers))
-	for i, b := range buffers {
-		bindGroupEntries[i] = b.MakeBindingGroupEntry(i)
-		bindGroupLayoutEntries[i] = b.MakeBindGroupLayoutEntry(i)
+// NewGroupedComputePassFactory creates a factory where groups[g][i] is bound to @group(g) @binding(i).
+// Panics if the shader can't be preprocessed.
+func NewGroupedComputePassFactory(device gpu.Device, computeShaderCode string, groups [][]ComputePassBuffer, opts ...ShaderModuleOption) ComputePassFactory {
+	cfg := newShaderModuleConfig(opts)
+	src, err := cfg.preprocess(computeShaderCode)
+	if err != nil {
+		panic(fmt.Sprintf("preprocessing shader: %v", err))
 	}
+	bindingIdxs := make([][]int, len(groups))
+	for g, buffers := range groups {
+		bindingIdxs[g] = make([]int, len(buffers))
+		for i := range buffers {
+			bindingIdxs[g][i] = i
+		}
+	}
+	return newComputePassFactory(device, src, cfg, groups, bindingIdxs)
+}
 
-	layout := device.CreatePipelineLayout(wasmgpu.GPUPipelineLayoutDescriptor{
-		BindGroupLayouts: []wasmgpu.GPUBindGroupLayout{
-			device.CreateBindGroupLayout(wasmgpu.GPUBindGroupLayoutDescriptor{
-				Entries: bindGroupLayoutEntries,
-			}),
-		},
-	})
-	return ComputePassFactory{
+// NewNamedComputePassFactory creates a factory where each buffer is bound to the variable with the same name in the shader.
+// It returns an error if a variable has no buffer, a buffer has no variable, or a buffer doesn't match the variable's declaration.
+func NewNamedComputePassFactory(device gpu.Device, computeShaderCode string, buffers map[string]ComputePassBuffer, opts ...ShaderModuleOption) (ComputePassFactory, error) {
+	cfg := newShaderModuleConfig(opts)
+	src, err := cfg.preprocess(computeShaderCode)
+	if err != nil {
+		return ComputePassFactory{}, fmt.Errorf("preprocessing shader: %v", err)
+	}
+	bindings, err := wgsl.ParseBindings(src.Code)
+	if err != nil {
+		return ComputePassFactory{}, fmt.Errorf("parsing bindings: %v", err)
+	}
+
+	var groups [][]ComputePassBuffer
+	var bindingIdxs [][]int
+	used := make(map[string]bool)
+	for _, b := range bindings {
+		buf, ok := buffers[b.Name]
+		if !ok {
+			return ComputePassFactory{}, fmt.Errorf("no buffer provided for variable %q (%v)", b.Name, b)
+		}
+		if err := checkBinding(b, buf); err != nil {
+			return ComputePassFactory{}, err
+		}
+		used[b.Name] = true
+		for len(groups) <= b.Group {
+			groups = append(groups, nil)
+			bindingIdxs = append(bindingIdxs, nil)
+		}
+		groups[b.Group] = append(groups[b.Group], buf)
+		bindingIdxs[b.Group] = append(bindingIdxs[b.Group], b.Binding)
+	}
+	for name := range buffers {
+		if !used[name] {
+			return ComputePassFactory{}, fmt.Errorf("buffer %q doesn't match any variable in the shader", name)
+		}
+	}
+	return newComputePassFactory(device, src, cfg, groups, bindingIdxs), nil
+}
+
+type bindingDecl struct {
+	addressSpace string
+	access       wgsl.AccessMode
+}
+
+// bindingTypeDecls maps buffer binding types to the variable declarations they're compatible with.
+var bindingTypeDecls = map[gpu.BufferBindingType]bindingDecl{
+	gpu.BufferBindingTypeUniform:         {addressSpace: "uniform", access: wgsl.AccessRead},
+	gpu.BufferBindingTypeStorage:         {addressSpace: "storage", access: wgsl.AccessReadWrite},
+	gpu.BufferBindingTypeReadOnlyStorage: {addressSpace: "storage", access: wgsl.AccessRead},
+}
+
+// checkBinding returns an error if the buffer can't be bound to the variable declared by b.
+func checkBinding(b wgsl.Binding, buf ComputePassBuffer) error {
+	want, ok := bindingTypeDecls[buf.BindingType()]
+	if !ok {
+		return fmt.Errorf("variable %q: unhandled buffer binding type %q", b.Name, buf.BindingType())
+	}
+	if b.AddressSpace != want.addressSpace {
+		return fmt.Errorf("line %d: variable %q: declared in the %s address space but buffer is bound as %s", b.Line, b.Name, b.AddressSpace, want.addressSpace)
+	}
+	if b.Access != want.access {
+		return fmt.Errorf("line %d: variable %q: declared with %s access but buffer is bound with %s access", b.Line, b.Name, b.Access, want.access)
+	}
+	bufType := strings.ReplaceAll(string(buf.WGSLType()), " ", "")
+	if bufType != b.Type {
+		return fmt.Errorf("line %d: variable %q: declared with type %s but buffer has type %q", b.Line, b.Name, b.Type, buf.WGSLType())
+	}
+	return nil
+}
+
+// newComputePassFactory creates a factory where groups[g][i] is bound to @group(g) @binding(bindingIdxs[g][i]).
+func newComputePassFactory(device gpu.Device, src wgsl.Source, cfg shaderModuleConfig, groups [][]ComputePassBuffer, bindingIdxs [][]int) ComputePassFactory {
+	structDefinitions := []wgsltypes.Struct{}
+	for _, buffers := range groups {
+		for _, b := range buffers {
+			structDefinitions = append(structDefinitions, b.StructDefs()...)
+		}
+	}
+
+	computeShaderModule := createShaderModule(device, src, structDefinitions, cfg)
+
+	cpf := ComputePassFactory{
 		device:                device,
-		layout:                layout,
 		computeShaderModule:   computeShaderModule,
-		bindGroupEntries:      bindGroupEntries,
-		computePassDescriptor: wasmgpu.GPUComputePassDescriptor{},
+		computePassDescriptor: gpu.ComputePassDescriptor{},
+		groups:                make([]*passGroup, len(groups)),
+		code:                  src.Code,
+	}
+	for g, buffers := range groups {
+		if len(buffers) == 0 {
+			continue
+		}
+		bindGroupLayoutEntries := make([]gpu.BindGroupLayoutEntry, len(buffers))
+		for i, b := range buffers {
+			bindGroupLayoutEntries[i] = b.MakeBindGroupLayoutEntry(bindingIdxs[g][i])
+		}
+		layout := device.CreateBindGroupLayout(gpu.BindGroupLayoutDescriptor{
+			Entries: bindGroupLayoutEntries,
+		})
+		cpf.groups[g] = &passGroup{
+			buffers:     buffers,
+			bindingIdxs: bindingIdxs[g],
+			layout:      layout,
+			bindGroups:  newBindGroupSet(device, layout, buffers, bindingIdxs[g]),
+		}
+	}
+	cpf.entryPoints, cpf.parseErr = wgsl.ParseEntryPoints(src.Code)
+	if cpf.parseErr == nil {
+		cpf.bindings, cpf.parseErr = wgsl.ParseBindings(src.Code)
 	}
+	return cpf
 }
 
-func (cpf ComputePassFactory) InitPass(entryPoint string, numWorkgroups int) ComputePass {
-	pipeline := cpf.device.CreateComputePipeline(wasmgpu.GPUComputePipelineDescriptor{
-		Layout: opt.V(cpf.layout),
-		Compute: wasmgpu.GPUProgrammableStage{
-			Module:     cpf.computeShaderModule,
+// CompilationErr waits for the factory's shader module to be compiled and returns an error as ShaderModule.CompilationErr does.
+func (cpf ComputePassFactory) CompilationErr() error {
+	return cpf.computeShaderModule.CompilationErr()
+}
+
+// InitPass returns a pass which runs the entry point once for each of the invocations.
+// Invocations can have 1, 2 or 3 dimensions, and the number of workgroups to dispatch is
+// derived from the entry point's @workgroup_size.
+// Overrides sets the values of override declarations in the shader, and may be nil.
+// The pass only binds the groups containing variables the entry point uses, and if it uses the
+// Write role of a PingPongBuffer, the buffer is swapped after each time the pass is recorded.
+// Panics if the entry point or its workgroup size can't be found in the shader, the overrides are invalid,
+// or the entry point uses a variable with no buffer.
+func (cpf ComputePassFactory) InitPass(entryPoint string, overrides wgsl.Overrides, invocations ...int) ComputePass {
+	numWorkgroups, err := cpf.workgroupCounts(entryPoint, overrides, invocations)
+	if err != nil {
+		panic(fmt.Sprintf("InitPass(%q): %v", entryPoint, err))
+	}
+	return cpf.initPass("InitPass", entryPoint, overrides, func(passEncoder gpu.ComputePassEncoder) {
+		passEncoder.DispatchWorkgroups(uint32(numWorkgroups[0]), uint32(numWorkgroups[1]), uint32(numWorkgroups[2]))
+	})
+}
+
+// InitIndirectPass is like InitPass, but dispatches the number of workgroups held in args when the pass runs.
+func (cpf ComputePassFactory) InitIndirectPass(entryPoint string, overrides wgsl.Overrides, args IndirectDispatchBuffer) ComputePass {
+	if _, err := cpf.workgroupSize(entryPoint, overrides); err != nil {
+		panic(fmt.Sprintf("InitIndirectPass(%q): %v", entryPoint, err))
+	}
+	return cpf.initPass("InitIndirectPass", entryPoint, overrides, func(passEncoder gpu.ComputePassEncoder) {
+		passEncoder.DispatchWorkgroupsIndirect(args.Buffer(), 0)
+	})
+}
+
+// InitCountedPass is like InitPass, but runs the entry point once for each item counted by counter, so the work
+// done scales with the number of items. The pass first computes the number of workgroups to dispatch on the GPU
+// using InitDispatchArgsPass, so it must run after the passes which update the counter.
+// Items are counted in one dimension, so panics if the entry point's @workgroup_size has a y or z other than 1.
+func (cpf ComputePassFactory) InitCountedPass(entryPoint string, overrides wgsl.Overrides, counter Counter) ComputePass {
+	size, err := cpf.workgroupSize(entryPoint, overrides)
+	if err != nil {
+		panic(fmt.Sprintf("InitCountedPass(%q): %v", entryPoint, err))
+	}
+	if size[1] != 1 || size[2] != 1 {
+		panic(fmt.Sprintf("InitCountedPass(%q): @workgroup_size is %v, but counted passes must be one-dimensional", entryPoint, size))
+	}
+	args := InitIndirectDispatchBuffer(cpf.device, DispatchArgs{})
+	argsPass := InitDispatchArgsPass(cpf.device, counter, size[0], args)
+	pass := cpf.InitIndirectPass(entryPoint, overrides, args)
+	return func(commandEncoder gpu.CommandEncoder) {
+		argsPass(commandEncoder)
+		pass(commandEncoder)
+	}
+}
+
+// initPass returns a pass which binds the groups the entry point uses and calls dispatch. caller names the
+// exported method in panics.
+func (cpf ComputePassFactory) initPass(caller, entryPoint string, overrides wgsl.Overrides, dispatch func(passEncoder gpu.ComputePassEncoder)) ComputePass {
+	constants, err := cpf.computeShaderModule.PipelineConstants(overrides)
+	if err != nil {
+		panic(fmt.Sprintf("%s(%q): %v", caller, entryPoint, err))
+	}
+	usedGroups, swaps, err := cpf.passBindings(entryPoint)
+	if err != nil {
+		panic(fmt.Sprintf("%s(%q): %v", caller, entryPoint, err))
+	}
+
+	// Pipeline layouts can't have holes, so unused groups before the last used one are bound to empty groups.
+	bindGroupLayouts := make([]gpu.BindGroupLayout, len(usedGroups))
+	emptyBindGroups := make([]gpu.BindGroup, len(usedGroups))
+	for g, used := range usedGroups {
+		if used {
+			bindGroupLayouts[g] = cpf.groups[g].layout
+			continue
+		}
+		bindGroupLayouts[g] = cpf.device.CreateBindGroupLayout(gpu.BindGroupLayoutDescriptor{})
+		emptyBindGroups[g] = cpf.device.CreateBindGroup(gpu.BindGroupDescriptor{Layout: bindGroupLayouts[g]})
+	}
+	pipeline := cpf.device.CreateComputePipeline(gpu.ComputePipelineDescriptor{
+		Layout: cpf.device.CreatePipelineLayout(gpu.PipelineLayoutDescriptor{
+			BindGroupLayouts: bindGroupLayouts,
+		}),
+		Compute: gpu.ProgrammableStage{
+			Module:     cpf.computeShaderModule.Module,
 			EntryPoint: entryPoint,
+			Constants:  constants,
 		},
 	})
-	bindGroup := cpf.device.CreateBindGroup(wasmgpu.GPUBindGroupDescriptor{
-		Layout:  pipeline.GetBindGroupLayout(0),
-		Entries: cpf.bindGroupEntries,
-	})
-	return func(commandEncoder wasmgpu.GPUCommandEncoder) {
-		passEncoder := commandEncoder.BeginComputePass(opt.V(cpf.computePassDescriptor))
+	return func(commandEncoder gpu.CommandEncoder) {
+		passEncoder := commandEncoder.BeginComputePass(cpf.computePassDescriptor)
 		passEncoder.SetPipeline(pipeline)
-		passEncoder.SetBindGroup(0, bindGroup, nil)
-		passEncoder.DispatchWorkgroups(wasmgpu.GPUSize32(numWorkgroups), 0, 0)
+		for g, used := range usedGroups {
+			if used {
+				passEncoder.SetBindGroup(g, cpf.groups[g].bindGroups.current())
+			} else {
+				passEncoder.SetBindGroup(g, emptyBindGroups[g])
+			}
+		}
+		dispatch(passEncoder)
 		passEncoder.End()
+		for _, s := range swaps {
+			s.swap()
+		}
+	}
+}
+
+// passBindings returns whether the entry point uses each group up to the last one it uses,
+// and the PingPongBuffers it writes to.
+func (cpf ComputePassFactory) passBindings(entryPoint string) ([]bool, []*pingPongState, error) {
+	uses, err := wgsl.StaticUses(cpf.code, entryPoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	var usedGroups []bool
+	var usedBuffers []ComputePassBuffer
+	for _, b := range cpf.bindings {
+		if _, ok := slices.BinarySearch(uses, b.Name); !ok {
+			continue
+		}
+		var buf ComputePassBuffer
+		if b.Group < len(cpf.groups) && cpf.groups[b.Group] != nil {
+			buf = cpf.groups[b.Group].bufferAt(b.Binding)
+		}
+		if buf == nil {
+			return nil, nil, fmt.Errorf("line %d: variable %q: no buffer is bound to @group(%d) @binding(%d)", b.Line, b.Name, b.Group, b.Binding)
+		}
+		for len(usedGroups) <= b.Group {
+			usedGroups = append(usedGroups, false)
+		}
+		usedGroups[b.Group] = true
+		usedBuffers = append(usedBuffers, buf)
+	}
+	return usedGroups, pingPongWriters(usedBuffers), nil
+}
+
+// workgroupCounts returns the number of workgroups needed in each dimension to cover the invocations.
+func (cpf ComputePassFactory) workgroupCounts(entryPoint string, overrides wgsl.Overrides, invocations []int) ([3]int, error) {
+	var counts [3]int
+	if len(invocations) < 1 || len(invocations) > 3 {
+		return counts, fmt.Errorf("invocations must have 1 to 3 dimensions, got %d", len(invocations))
+	}
+	size, err := cpf.workgroupSize(entryPoint, overrides)
+	if err != nil {
+		return counts, err
+	}
+	for i := range counts {
+		n := 1
+		if i < len(invocations) {
+			n = invocations[i]
+		}
+		if n < 1 {
+			return counts, fmt.Errorf("invocations[%d] is %d, must be at least 1", i, n)
+		}
+		counts[i] = (n + size[i] - 1) / size[i]
+	}
+	return counts, nil
+}
+
+// workgroupSize returns the @workgroup_size of the entry point.
+func (cpf ComputePassFactory) workgroupSize(entryPoint string, overrides wgsl.Overrides) ([3]int, error) {
+	if cpf.parseErr != nil {
+		return [3]int{}, fmt.Errorf("parsing shader: %v", cpf.parseErr)
+	}
+	ep, ok := wgsl.FindEntryPoint(cpf.entryPoints, entryPoint)
+	if !ok || ep.Stage != wgsl.StageCompute {
+		return [3]int{}, fmt.Errorf("no compute entry point named %q", entryPoint)
 	}
+	return ep.ResolveWorkgroupSize(cpf.computeShaderModule.constants, overrides)
 }
@@ -0,0 +1,286 @@
+package engine
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpucpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+const testComputeShader = `@group(0) @binding(0) var<uniform> params : vec4<f32>;
+@group(0) @binding(1) var<storage, read_write> gValues : array<u32>;
+
+override workgroupSize : u32 = 64;
+
+@compute @workgroup_size(workgroupSize, 1)
+fn main(@builtin(global_invocation_id) id : vec3<u32>) {
+  gValues[id.x] = id.x * u32(params.y);
+}
+`
+
+// testComputeKernels implements testComputeShader for gpucpu.
+var testComputeKernels = gpucpu.Kernels{
+	"main": func(inv gpucpu.Invocation) {
+		params := gpucpu.Ptr[vmath.V4](inv.Bindings, 0, 0)
+		values := gpucpu.Slice[uint32](inv.Bindings, 0, 1)
+		// Out of bounds writes are discarded in WGSL.
+		if id := inv.GlobalID[0]; id < uint32(len(values)) {
+			values[id] = id * uint32(params.Y)
+		}
+	},
+}
+
+func TestInitPass(t *testing.T) {
+	device := gpufake.NewDevice()
+	params := InitUniformBuffer(device, vmath.NewV4(1, 2, 3, 4))
+	values := InitStorageBufferSlice(device, make([]uint32, 100))
+
+	cpf, err := NewNamedComputePassFactory(device, testComputeShader, map[string]ComputePassBuffer{
+		"gValues": values,
+		"params":  params,
+	})
+	if err != nil {
+		t.Fatalf("NewNamedComputePassFactory() = %v", err)
+	}
+	if err := cpf.CompilationErr(); err != nil {
+		t.Fatalf("CompilationErr() = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		overrides   wgsl.Overrides
+		invocations []int
+		wantCounts  [3]uint32
+		wantConsts  map[string]float64
+	}{
+		{
+			name:        "default workgroup size",
+			invocations: []int{100},
+			wantCounts:  [3]uint32{2, 1, 1},
+			wantConsts:  map[string]float64{},
+		},
+		{
+			name:        "overridden workgroup size",
+			overrides:   wgsl.Overrides{"workgroupSize": 16},
+			invocations: []int{100, 3},
+			wantCounts:  [3]uint32{7, 3, 1},
+			wantConsts:  map[string]float64{"workgroupSize": 16},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pass := cpf.InitPass("main", tc.overrides, tc.invocations...)
+			encoder := device.CreateCommandEncoder()
+			pass(encoder)
+			device.Queue().Submit(encoder.Finish())
+
+			dispatches := device.Dispatches()
+			got := dispatches[len(dispatches)-1]
+			if diff := cmp.Diff(tc.wantCounts, [3]uint32{got.X, got.Y, got.Z}); diff != "" {
+				t.Errorf("dispatched workgroups diff (-want +got):\n%s", diff)
+			}
+			stage := got.Pipeline.Desc.Compute
+			if stage.EntryPoint != "main" {
+				t.Errorf("EntryPoint = %q, want %q", stage.EntryPoint, "main")
+			}
+			if diff := cmp.Diff(tc.wantConsts, stage.Constants); diff != "" {
+				t.Errorf("Constants diff (-want +got):\n%s", diff)
+			}
+			bindGroup := got.BindGroups[0]
+			if bindGroup.BufferAt(0) != params.Buffer() || bindGroup.BufferAt(1) != values.Buffer() {
+				t.Errorf("bind group entries = %+v, want params at 0 and values at 1", bindGroup.Desc.Entries)
+			}
+		})
+	}
+}
+
+func TestInitPassOnCPU(t *testing.T) {
+	device := gpucpu.NewDevice(testComputeKernels)
+	params := InitUniformBuffer(device, vmath.NewV4(1, 2, 3, 4))
+	values := InitStorageBufferSlice(device, make([]uint32, 100), WithCopySrcUsage())
+
+	cpf, err := NewNamedComputePassFactory(device, testComputeShader, map[string]ComputePassBuffer{
+		"gValues": values,
+		"params":  params,
+	})
+	if err != nil {
+		t.Fatalf("NewNamedComputePassFactory() = %v", err)
+	}
+	pass := cpf.InitPass("main", wgsl.Overrides{"workgroupSize": 16}, 100)
+
+	encoder := device.CreateCommandEncoder()
+	pass(encoder)
+	device.Queue().Submit(encoder.Finish())
+
+	got := <-values.Read(NewStagingPool(device), 0, values.Len())
+	want := make([]uint32, 100)
+	for i := range want {
+		want[i] = uint32(i) * 2
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("values diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompilationErr(t *testing.T) {
+	device := gpufake.NewDevice()
+	code := "fn f() -> f32 {\n  return 1;\n}\n"
+	consts := []wgsltypes.Const{wgsltypes.MustNewConst("limit", uint32(4))}
+	// The module's code starts with the prologue, so the compiler reports lines after it.
+	src, err := wgsl.Preprocessor{}.Process("test.wgsl", code)
+	if err != nil {
+		t.Fatalf("Process() = %v", err)
+	}
+	moduleLines := strings.Split(wgsl.ModuleSource(src, nil, consts).Code, "\n")
+	returnLine := slices.Index(moduleLines, "  return 1;") + 1
+	device.CompilationMessages = map[string][]gpu.CompilationMessage{
+		"test.wgsl": {
+			{Type: "warning", Message: "unused function"},
+			{Type: "error", Message: "bad return type", LineNum: returnLine, LinePos: 10},
+		},
+	}
+
+	m := InitShaderModule(device, code, nil, WithSourceName("test.wgsl"), WithConsts(consts...))
+	err = m.CompilationErr()
+	var compilationErr *CompilationError
+	if !errors.As(err, &compilationErr) {
+		t.Fatalf("CompilationErr() = %v, want a *CompilationError", err)
+	}
+	want := []CompilationMessage{
+		{Type: "error", Message: "bad return type", Location: wgsl.Location{File: "test.wgsl", Line: 2}, Column: 10},
+	}
+	if diff := cmp.Diff(want, compilationErr.Messages); diff != "" {
+		t.Errorf("Messages diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompilationErrInfoFails(t *testing.T) {
+	device := gpufake.NewDevice()
+	infoErr := errors.New("device lost")
+	device.CompilationInfoErr = infoErr
+
+	m := InitShaderModule(device, "fn f() {}\n", nil, WithSourceName("test.wgsl"))
+	if err := m.CompilationErr(); !errors.Is(err, infoErr) {
+		t.Errorf("CompilationErr() = %v, want %v", err, infoErr)
+	}
+}
+
+const testGroupedComputeShader = `@group(0) @binding(0) var<uniform> params : vec4<f32>;
+@group(1) @binding(0) var<storage, read_write> gValues : array<u32>;
+@group(2) @binding(0) var<storage, read> gSrc : array<u32>;
+@group(2) @binding(1) var<storage, read_write> gDst : array<u32>;
+
+fn scale(x : u32) -> u32 {
+  return x * u32(params.y);
+}
+
+@compute @workgroup_size(64)
+fn scaleValues(@builtin(global_invocation_id) id : vec3<u32>) {
+  gValues[id.x] = scale(gValues[id.x]);
+}
+
+@compute @workgroup_size(64)
+fn copy(@builtin(global_invocation_id) id : vec3<u32>) {
+  gDst[id.x] = gSrc[id.x];
+}
+`
+
+func TestInitPassGroups(t *testing.T) {
+	device := gpufake.NewDevice()
+	params := InitUniformBuffer(device, vmath.NewV4(1, 2, 3, 4))
+	values := InitStorageBufferSlice(device, make([]uint32, 100))
+	pingPong := InitPingPongBufferSlice(device, 2, make([]uint32, 100))
+
+	cpf, err := NewNamedComputePassFactory(device, testGroupedComputeShader, map[string]ComputePassBuffer{
+		"params":  params,
+		"gValues": values,
+		"gSrc":    pingPong.Read(),
+		"gDst":    pingPong.Write(),
+	})
+	if err != nil {
+		t.Fatalf("NewNamedComputePassFactory() = %v", err)
+	}
+	scalePass := cpf.InitPass("scaleValues", nil, 100)
+	copyPass := cpf.InitPass("copy", nil, 100)
+	bindGroupCount := len(device.BindGroups)
+
+	const frames = 2
+	for i := 0; i < frames; i++ {
+		encoder := device.CreateCommandEncoder()
+		scalePass(encoder)
+		copyPass(encoder)
+		device.Queue().Submit(encoder.Finish())
+	}
+
+	type binding struct {
+		Group, Binding int
+		Buffer         gpu.Buffer
+	}
+	bindings := func(d gpufake.Dispatch) []binding {
+		var got []binding
+		for g := 0; g < len(d.BindGroups); g++ {
+			for _, e := range d.BindGroups[g].Desc.Entries {
+				got = append(got, binding{Group: g, Binding: e.Binding, Buffer: e.Buffer})
+			}
+		}
+		return got
+	}
+	copies := pingPong.Buffers()
+	want := [][]binding{
+		{{Group: 0, Binding: 0, Buffer: params.Buffer()}, {Group: 1, Binding: 0, Buffer: values.Buffer()}},
+		{{Group: 2, Binding: 0, Buffer: copies[0].Buffer()}, {Group: 2, Binding: 1, Buffer: copies[1].Buffer()}},
+		{{Group: 0, Binding: 0, Buffer: params.Buffer()}, {Group: 1, Binding: 0, Buffer: values.Buffer()}},
+		{{Group: 2, Binding: 0, Buffer: copies[1].Buffer()}, {Group: 2, Binding: 1, Buffer: copies[0].Buffer()}},
+	}
+	var got [][]binding
+	for _, d := range device.Dispatches() {
+		got = append(got, bindings(d))
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("bindings diff (-want +got):\n%s", diff)
+	}
+
+	// Passes share the layouts and bind groups of the groups they use.
+	scaleLayouts := device.Dispatches()[0].Pipeline.Desc.Layout.(*gpufake.PipelineLayout).Desc.BindGroupLayouts
+	copyLayouts := device.Dispatches()[1].Pipeline.Desc.Layout.(*gpufake.PipelineLayout).Desc.BindGroupLayouts
+	if len(scaleLayouts) != 2 || len(copyLayouts) != 3 {
+		t.Fatalf("got %d and %d bind group layouts, want 2 and 3", len(scaleLayouts), len(copyLayouts))
+	}
+	if scaleLayouts[0] != device.BindGroupLayouts[0] || scaleLayouts[1] != device.BindGroupLayouts[1] || copyLayouts[2] != device.BindGroupLayouts[2] {
+		t.Errorf("passes don't use the factory's bind group layouts")
+	}
+	for g := 0; g < 2; g++ {
+		if entries := copyLayouts[g].(*gpufake.BindGroupLayout).Desc.Entries; len(entries) != 0 {
+			t.Errorf("copy pass layout for unused group %d has entries %+v, want none", g, entries)
+		}
+	}
+	// One bind group for each of groups 0 and 1, one for each ping-pong copy, and two empty groups for the copy pass.
+	if want := 1 + 1 + 2 + 2; bindGroupCount != want {
+		t.Errorf("created %d bind groups, want %d", bindGroupCount, want)
+	}
+}
+
+func TestInitPassMissingBuffer(t *testing.T) {
+	device := gpufake.NewDevice()
+	params := InitUniformBuffer(device, vmath.NewV4(1, 2, 3, 4))
+	values := InitStorageBufferSlice(device, make([]uint32, 100))
+	cpf := NewGroupedComputePassFactory(device, testGroupedComputeShader, [][]ComputePassBuffer{{params}, {values}})
+
+	// The factory has buffers for every variable scaleValues uses.
+	cpf.InitPass("scaleValues", nil, 100)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("InitPass() didn't panic")
+		}
+	}()
+	cpf.InitPass("copy", nil, 100)
+}
@@ -4,41 +4,79 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"strings"
-	"syscall/js"
 
-	"github.com/hulkholden/gowebgpu/client/browser"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
 	"github.com/hulkholden/gowebgpu/common/wgsltypes"
-	"github.com/mokiat/wasmgpu"
 )
 
-func InitRenderCallback(update func()) {
-	frame := js.FuncOf(func(this js.Value, args []js.Value) any {
-		update()
-		InitRenderCallback(update)
-		return nil
-	})
-	browser.Window().RequestAnimationFrame(frame)
+// A ShaderModule is a compiled shader module along with the declarations parsed from its source.
+type ShaderModule struct {
+	Module gpu.ShaderModule
+
+	constants   []wgsl.Constant
+	parseErr    error
+	compilation *compilation
+}
+
+// CompilationErr waits for the module to be compiled and returns a *CompilationError if compilation failed,
+// or the error retrieving the compilation info if that failed.
+// It must not be called from a JavaScript callback, as the result is delivered by one.
+func (m ShaderModule) CompilationErr() error {
+	return m.compilation.wait()
+}
+
+// PipelineConstants validates overrides against the module's override declarations and
+// returns the constants to use in the programmable stage of a pipeline.
+func (m ShaderModule) PipelineConstants(overrides wgsl.Overrides) (map[string]float64, error) {
+	if m.parseErr != nil {
+		return nil, fmt.Errorf("parsing shader: %v", m.parseErr)
+	}
+	return overrides.PipelineConstants(m.constants)
 }
 
-func LoadShaderModule(device wasmgpu.GPUDevice, url string, structs []wgsltypes.Struct) (wasmgpu.GPUShaderModule, error) {
+// LoadShaderModule fetches and preprocesses the shader at url, then creates a module as InitShaderModule does.
+// Unless WithIncludes is provided, #includes are fetched relative to url.
+// It waits for the module to be compiled, and returns a *CompilationError if compilation fails.
+func LoadShaderModule(device gpu.Device, url string, structs []wgsltypes.Struct, opts ...ShaderModuleOption) (ShaderModule, error) {
 	bytes, err := loadFile(url)
 	if err != nil {
-		return wasmgpu.GPUShaderModule{}, fmt.Errorf("loading shader: %v", err)
+		return ShaderModule{}, fmt.Errorf("loading shader: %v", err)
 	}
-	return InitShaderModule(device, string(bytes), structs), nil
+	defaults := []ShaderModuleOption{WithSourceName(url), WithIncludes(loadFile)}
+	cfg := newShaderModuleConfig(append(defaults, opts...))
+	src, err := cfg.preprocess(string(bytes))
+	if err != nil {
+		return ShaderModule{}, fmt.Errorf("preprocessing shader: %v", err)
+	}
+	m := createShaderModule(device, src, structs, cfg)
+	return m, m.CompilationErr()
 }
 
-func InitShaderModule(device wasmgpu.GPUDevice, code string, structs []wgsltypes.Struct) wasmgpu.GPUShaderModule {
-	defs := make([]string, len(structs))
-	for i, s := range structs {
-		defs[i] = s.ToWGSL()
+// InitShaderModule preprocesses code and creates a shader module with definitions of structs, and all the structs they depend on, prepended.
+// Compilation errors are reported to the page, with line numbers referring to the original source files,
+// and can be retrieved with CompilationErr.
+// Panics if preprocessing fails.
+func InitShaderModule(device gpu.Device, code string, structs []wgsltypes.Struct, opts ...ShaderModuleOption) ShaderModule {
+	cfg := newShaderModuleConfig(opts)
+	src, err := cfg.preprocess(code)
+	if err != nil {
+		panic(fmt.Sprintf("preprocessing shader: %v", err))
 	}
-	prologue := strings.Join(defs, "\n")
+	return createShaderModule(device, src, structs, cfg)
+}
 
-	return device.CreateShaderModule(wasmgpu.GPUShaderModuleDescriptor{
-		Code: prologue + "\n" + code,
-	})
+func createShaderModule(device gpu.Device, src wgsl.Source, structs []wgsltypes.Struct, cfg shaderModuleConfig) ShaderModule {
+	src = wgsl.ModuleSource(src, structs, cfg.consts)
+	m := ShaderModule{
+		Module: device.CreateShaderModule(gpu.ShaderModuleDescriptor{
+			Label: cfg.name,
+			Code:  src.Code,
+		}),
+	}
+	m.compilation = watchCompilation(m.Module, cfg.name, src)
+	m.constants, m.parseErr = wgsl.ParseConstants(src.Code)
+	return m
 }
 
 func loadFile(url string) ([]byte, error) {
@@ -0,0 +1,128 @@
+// Package gpu defines the subset of WebGPU used by the engine as Go interfaces, so the engine
+// and examples don't depend on a particular backend.
+//
+// The WebGPU backend, built on wasmgpu, is only available when building for js/wasm.
+// Package gpufake provides an in-memory backend for tests.
+package gpu
+
+// A Device creates GPU resources.
+type Device interface {
+	Queue() Queue
+	CreateBuffer(desc BufferDescriptor) Buffer
+	CreateShaderModule(desc ShaderModuleDescriptor) ShaderModule
+	CreateBindGroupLayout(desc BindGroupLayoutDescriptor) BindGroupLayout
+	CreateBindGroup(desc BindGroupDescriptor) BindGroup
+	CreatePipelineLayout(desc PipelineLayoutDescriptor) PipelineLayout
+	CreateComputePipeline(desc ComputePipelineDescriptor) ComputePipeline
+	CreateRenderPipeline(desc RenderPipelineDescriptor) RenderPipeline
+	CreateCommandEncoder() CommandEncoder
+	// CreateQuerySet creates a set of queries. Timestamp queries need FeatureTimestampQuery.
+	CreateQuerySet(desc QuerySetDescriptor) QuerySet
+	// HasFeature reports whether the device was created with the feature.
+	HasFeature(feature FeatureName) bool
+}
+
+// A Queue executes commands and writes to buffers.
+type Queue interface {
+	// WriteBuffer writes data to the buffer at offset.
+	WriteBuffer(buffer Buffer, offset uint64, data []byte)
+	// Submit schedules the command buffers for execution.
+	Submit(commandBuffers ...CommandBuffer)
+}
+
+// A Buffer is a block of GPU memory.
+type Buffer interface {
+	// Size returns the size of the buffer, in bytes.
+	Size() uint64
+	// MapRead maps size bytes of the buffer starting at offset for reading, and calls callback with
+	// a copy of the contents once they're available. The buffer must have BufferUsageMapRead.
+	// If the buffer can't be mapped, for example because the device was lost, callback is called with
+	// the error instead.
+	MapRead(offset, size uint64, callback func(data []byte, err error))
+	// Destroy releases the buffer's memory.
+	Destroy()
+}
+
+// A QuerySet is a set of queries whose results are written by passes and resolved into buffers.
+type QuerySet interface {
+	// Destroy releases the query set.
+	Destroy()
+}
+
+// A ShaderModule is compiled WGSL.
+type ShaderModule interface {
+	// GetCompilationInfo calls callback with the messages reported when compiling the module, once they're available,
+	// or with an error if they can't be retrieved.
+	GetCompilationInfo(callback func(messages []CompilationMessage, err error))
+}
+
+// A BindGroupLayout describes the resources in a bind group.
+type BindGroupLayout interface{}
+
+// A BindGroup is a set of resources bound together.
+type BindGroup interface{}
+
+// A PipelineLayout describes the bind group layouts used by a pipeline.
+type PipelineLayout interface{}
+
+// A ComputePipeline is a compute shader stage and its layout.
+type ComputePipeline interface {
+	// GetBindGroupLayout returns the layout of the bind group at index.
+	GetBindGroupLayout(index int) BindGroupLayout
+}
+
+// A RenderPipeline is a vertex and fragment shader stage and their layout.
+type RenderPipeline interface {
+	// GetBindGroupLayout returns the layout of the bind group at index.
+	GetBindGroupLayout(index int) BindGroupLayout
+}
+
+// A CommandEncoder records commands to be submitted to a Queue.
+type CommandEncoder interface {
+	BeginComputePass(desc ComputePassDescriptor) ComputePassEncoder
+	BeginRenderPass(desc RenderPassDescriptor) RenderPassEncoder
+	CopyBufferToBuffer(source Buffer, sourceOffset uint64, destination Buffer, destinationOffset uint64, size uint64)
+	ClearBuffer(buffer Buffer, offset, size uint64)
+	// ResolveQuerySet writes the results of queryCount queries starting at firstQuery to destination as u64s.
+	// Timestamps are in nanoseconds. The destination must have BufferUsageQueryResolve, and
+	// destinationOffset must be a multiple of 256.
+	ResolveQuerySet(querySet QuerySet, firstQuery, queryCount int, destination Buffer, destinationOffset uint64)
+	Finish() CommandBuffer
+}
+
+// A CommandBuffer holds the commands recorded by a CommandEncoder.
+type CommandBuffer interface{}
+
+// A ComputePassEncoder records the commands of a compute pass.
+type ComputePassEncoder interface {
+	SetPipeline(pipeline ComputePipeline)
+	SetBindGroup(index int, bindGroup BindGroup)
+	DispatchWorkgroups(x, y, z uint32)
+	// DispatchWorkgroupsIndirect dispatches the number of workgroups held in three u32s at indirectOffset
+	// in indirectBuffer, read when the command runs. The buffer must have BufferUsageIndirect.
+	DispatchWorkgroupsIndirect(indirectBuffer Buffer, indirectOffset uint64)
+	End()
+}
+
+// A RenderPassEncoder records the commands of a render pass.
+type RenderPassEncoder interface {
+	SetPipeline(pipeline RenderPipeline)
+	SetBindGroup(index int, bindGroup BindGroup)
+	SetVertexBuffer(slot int, buffer Buffer)
+	Draw(vertexCount, instanceCount, firstVertex, firstInstance uint32)
+	// DrawIndirect draws using the vertexCount, instanceCount, firstVertex and firstInstance held in four u32s
+	// at indirectOffset in indirectBuffer, read when the command runs. The buffer must have BufferUsageIndirect.
+	DrawIndirect(indirectBuffer Buffer, indirectOffset uint64)
+	End()
+}
+
+// A CanvasContext provides the textures displayed on a canvas.
+type CanvasContext interface {
+	// CurrentTextureView returns a view of the texture to render the next frame to.
+	CurrentTextureView() TextureView
+	// Format returns the format of the canvas's textures, which render pipelines must target.
+	Format() TextureFormat
+}
+
+// A TextureView is a view of a texture which can be rendered to.
+type TextureView interface{}
@@ -0,0 +1,105 @@
+package gpucpu
+
+import (
+	"encoding/binary"
+	"maps"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+)
+
+// commandBuffer holds the commands to run when it's submitted.
+type commandBuffer struct {
+	commands []func()
+}
+
+type commandEncoder struct {
+	commands []func()
+}
+
+func (e *commandEncoder) BeginComputePass(desc gpu.ComputePassDescriptor) gpu.ComputePassEncoder {
+	return &computePassEncoder{encoder: e, bindGroups: map[int]*bindGroup{}}
+}
+
+func (e *commandEncoder) BeginRenderPass(desc gpu.RenderPassDescriptor) gpu.RenderPassEncoder {
+	return renderPassEncoder{}
+}
+
+func (e *commandEncoder) CopyBufferToBuffer(source gpu.Buffer, sourceOffset uint64, destination gpu.Buffer, destinationOffset uint64, size uint64) {
+	src, dst := source.(*buffer), destination.(*buffer)
+	src.checkRange("CopyBufferToBuffer", sourceOffset, size)
+	dst.checkRange("CopyBufferToBuffer", destinationOffset, size)
+	e.commands = append(e.commands, func() {
+		copy(dst.data[destinationOffset:destinationOffset+size], src.data[sourceOffset:])
+	})
+}
+
+func (e *commandEncoder) ClearBuffer(buf gpu.Buffer, offset, size uint64) {
+	b := buf.(*buffer)
+	b.checkRange("ClearBuffer", offset, size)
+	e.commands = append(e.commands, func() {
+		clear(b.data[offset : offset+size])
+	})
+}
+
+// ResolveQuerySet panics, as the device can't create query sets.
+func (e *commandEncoder) ResolveQuerySet(querySet gpu.QuerySet, firstQuery, queryCount int, destination gpu.Buffer, destinationOffset uint64) {
+	panic("ResolveQuerySet: queries aren't supported")
+}
+
+func (e *commandEncoder) Finish() gpu.CommandBuffer {
+	return &commandBuffer{commands: e.commands}
+}
+
+type computePassEncoder struct {
+	encoder    *commandEncoder
+	pipeline   *computePipeline
+	bindGroups map[int]*bindGroup
+}
+
+func (e *computePassEncoder) SetPipeline(pipeline gpu.ComputePipeline) {
+	e.pipeline = pipeline.(*computePipeline)
+}
+
+func (e *computePassEncoder) SetBindGroup(index int, bg gpu.BindGroup) {
+	e.bindGroups[index] = bg.(*bindGroup)
+}
+
+func (e *computePassEncoder) DispatchWorkgroups(x, y, z uint32) {
+	if e.pipeline == nil {
+		panic("DispatchWorkgroups without a pipeline")
+	}
+	pipeline := e.pipeline
+	bindings := Bindings{groups: maps.Clone(e.bindGroups)}
+	e.encoder.commands = append(e.encoder.commands, func() {
+		pipeline.dispatch(bindings, [3]uint32{x, y, z})
+	})
+}
+
+func (e *computePassEncoder) DispatchWorkgroupsIndirect(indirectBuffer gpu.Buffer, indirectOffset uint64) {
+	if e.pipeline == nil {
+		panic("DispatchWorkgroupsIndirect without a pipeline")
+	}
+	args := indirectBuffer.(*buffer)
+	args.checkRange("DispatchWorkgroupsIndirect", indirectOffset, 12)
+	pipeline := e.pipeline
+	bindings := Bindings{groups: maps.Clone(e.bindGroups)}
+	e.encoder.commands = append(e.encoder.commands, func() {
+		var counts [3]uint32
+		for i := range counts {
+			counts[i] = binary.LittleEndian.Uint32(args.data[indirectOffset+uint64(4*i):])
+		}
+		pipeline.dispatch(bindings, counts)
+	})
+}
+
+func (e *computePassEncoder) End() {}
+
+// renderPassEncoder ignores render commands.
+type renderPassEncoder struct{}
+
+func (renderPassEncoder) SetPipeline(pipeline gpu.RenderPipeline)                            {}
+func (renderPassEncoder) SetBindGroup(index int, bindGroup gpu.BindGroup)                    {}
+func (renderPassEncoder) SetVertexBuffer(slot int, buffer gpu.Buffer)                        {}
+func (renderPassEncoder) Draw(vertexCount, instanceCount, firstVertex, firstInstance uint32) {}
+func (renderPassEncoder) DrawIndirect(indirectBuffer gpu.Buffer, indirectOffset uint64)      {}
+func (renderPassEncoder) End()                                                               {}
@@ -0,0 +1,24 @@
+package gpucpu
+
+// CounterKernels implements the shaders of the engine's passes which convert counters into indirect arguments
+// (see engine.InitDispatchArgsPass and engine.InitDrawArgsPass), so counted passes and indirect draws can run on
+// a Device. Add them to the kernels for the counted entry points.
+var CounterKernels = Kernels{
+	"dispatchArgs": func(inv Invocation) {
+		// args is an engine.DispatchArgs.
+		args := Ptr[[3]uint32](inv.Bindings, 0, 1)
+		workgroupSize := uint32(inv.Overrides["workgroupSize"])
+		*args = [3]uint32{(counterCount(inv) + workgroupSize - 1) / workgroupSize, 1, 1}
+	},
+	"drawArgs": func(inv Invocation) {
+		// args is an engine.DrawArgs, whose InstanceCount is the second u32.
+		args := Ptr[[4]uint32](inv.Bindings, 0, 1)
+		args[1] = counterCount(inv)
+	},
+}
+
+// counterCount returns the number of items counted by the counter bound to a counter args shader, clamped to its capacity.
+func counterCount(inv Invocation) uint32 {
+	counter := Slice[uint32](inv.Bindings, 0, 0)
+	return min(counter[uint32(inv.Overrides["counterIndex"])], uint32(inv.Overrides["capacity"]))
+}
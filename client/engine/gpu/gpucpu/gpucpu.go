@@ -0,0 +1,223 @@
+// Package gpucpu provides a gpu.Device which runs compute passes as Go kernels on the CPU.
+//
+// Buffers are held in memory with the same layout GPUBuffer[T] uploads, so kernels access them as
+// slices of the Go types the engine was given. Render passes are accepted but draw nothing, so
+// examples can run headless.
+package gpucpu
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+)
+
+// Device is a gpu.Device which runs compute pipelines using the kernel registered for their entry point.
+type Device struct {
+	kernels Kernels
+	// Workers is the number of goroutines workgroups are run on.
+	Workers int
+}
+
+var _ gpu.Device = (*Device)(nil)
+
+// NewDevice returns a device which runs the kernels, using a worker for each CPU.
+func NewDevice(kernels Kernels) *Device {
+	return &Device{kernels: kernels, Workers: runtime.GOMAXPROCS(0)}
+}
+
+func (d *Device) Queue() gpu.Queue {
+	return queue{}
+}
+
+func (d *Device) CreateBuffer(desc gpu.BufferDescriptor) gpu.Buffer {
+	if uint64(len(desc.Contents)) > desc.Size {
+		panic(fmt.Sprintf("buffer %q: %d bytes of contents don't fit in %d bytes", desc.Label, len(desc.Contents), desc.Size))
+	}
+	b := &buffer{label: desc.Label, usage: desc.Usage, data: make([]byte, desc.Size)}
+	copy(b.data, desc.Contents)
+	return b
+}
+
+func (d *Device) CreateShaderModule(desc gpu.ShaderModuleDescriptor) gpu.ShaderModule {
+	return &shaderModule{label: desc.Label, code: desc.Code}
+}
+
+func (d *Device) CreateBindGroupLayout(desc gpu.BindGroupLayoutDescriptor) gpu.BindGroupLayout {
+	return &bindGroupLayout{}
+}
+
+func (d *Device) CreateBindGroup(desc gpu.BindGroupDescriptor) gpu.BindGroup {
+	bg := &bindGroup{entries: make(map[int][]byte, len(desc.Entries))}
+	for _, e := range desc.Entries {
+		b := e.Buffer.(*buffer)
+		size := e.Size
+		if size == 0 {
+			size = uint64(len(b.data)) - e.Offset
+		}
+		b.checkRange("binding", e.Offset, size)
+		bg.entries[e.Binding] = b.data[e.Offset : e.Offset+size]
+	}
+	return bg
+}
+
+func (d *Device) CreatePipelineLayout(desc gpu.PipelineLayoutDescriptor) gpu.PipelineLayout {
+	return &pipelineLayout{}
+}
+
+// CreateComputePipeline panics if there's no kernel for the entry point, or its workgroup size can't be resolved.
+func (d *Device) CreateComputePipeline(desc gpu.ComputePipelineDescriptor) gpu.ComputePipeline {
+	stage := desc.Compute
+	kernel, ok := d.kernels[stage.EntryPoint]
+	if !ok {
+		panic(fmt.Sprintf("no kernel for entry point %q", stage.EntryPoint))
+	}
+	code := stage.Module.(*shaderModule).code
+	entryPoints, err := wgsl.ParseEntryPoints(code)
+	if err != nil {
+		panic(fmt.Sprintf("parsing entry points: %v", err))
+	}
+	constants, err := wgsl.ParseConstants(code)
+	if err != nil {
+		panic(fmt.Sprintf("parsing constants: %v", err))
+	}
+	ep, ok := wgsl.FindEntryPoint(entryPoints, stage.EntryPoint)
+	if !ok || ep.Stage != wgsl.StageCompute {
+		panic(fmt.Sprintf("no compute entry point named %q", stage.EntryPoint))
+	}
+	overrides, err := wgsl.OverridesFromPipelineConstants(constants, stage.Constants).WithDefaults(constants)
+	if err != nil {
+		panic(fmt.Sprintf("entry point %q: %v", stage.EntryPoint, err))
+	}
+	size, err := ep.ResolveWorkgroupSize(constants, overrides)
+	if err != nil {
+		panic(err.Error())
+	}
+	return &computePipeline{
+		kernel:        kernel,
+		workgroupSize: [3]uint32{uint32(size[0]), uint32(size[1]), uint32(size[2])},
+		overrides:     overrides,
+		workers:       d.Workers,
+	}
+}
+
+func (d *Device) CreateRenderPipeline(desc gpu.RenderPipelineDescriptor) gpu.RenderPipeline {
+	return &renderPipeline{}
+}
+
+func (d *Device) CreateCommandEncoder() gpu.CommandEncoder {
+	return &commandEncoder{}
+}
+
+// CreateQuerySet panics, as queries aren't supported.
+func (d *Device) CreateQuerySet(desc gpu.QuerySetDescriptor) gpu.QuerySet {
+	panic(fmt.Sprintf("CreateQuerySet: %s queries aren't supported", desc.Type))
+}
+
+// HasFeature reports that the device has no optional features.
+func (d *Device) HasFeature(feature gpu.FeatureName) bool {
+	return false
+}
+
+type queue struct{}
+
+func (q queue) WriteBuffer(buf gpu.Buffer, offset uint64, data []byte) {
+	b := buf.(*buffer)
+	b.checkRange("WriteBuffer", offset, uint64(len(data)))
+	copy(b.data[offset:], data)
+}
+
+// Submit runs the commands in order, returning once they've completed.
+func (q queue) Submit(commandBuffers ...gpu.CommandBuffer) {
+	for _, cb := range commandBuffers {
+		for _, cmd := range cb.(*commandBuffer).commands {
+			cmd()
+		}
+	}
+}
+
+type buffer struct {
+	label     string
+	usage     gpu.BufferUsage
+	data      []byte
+	destroyed bool
+}
+
+func (b *buffer) Size() uint64 {
+	return uint64(len(b.data))
+}
+
+// MapRead calls callback immediately with a copy of the buffer's contents.
+func (b *buffer) MapRead(offset, size uint64, callback func(data []byte, err error)) {
+	if b.usage&gpu.BufferUsageMapRead == 0 {
+		panic(fmt.Sprintf("buffer %q: MapRead without BufferUsageMapRead", b.label))
+	}
+	b.checkRange("MapRead", offset, size)
+	callback(append([]byte(nil), b.data[offset:offset+size]...), nil)
+}
+
+func (b *buffer) Destroy() {
+	b.destroyed = true
+}
+
+// checkRange panics if the range isn't in the buffer, or the buffer has been destroyed.
+func (b *buffer) checkRange(op string, offset, size uint64) {
+	if b.destroyed {
+		panic(fmt.Sprintf("buffer %q: %s after Destroy", b.label, op))
+	}
+	if offset+size > b.Size() {
+		panic(fmt.Sprintf("buffer %q: %s of %d bytes at offset %d exceeds size %d", b.label, op, size, offset, b.Size()))
+	}
+}
+
+type shaderModule struct {
+	label string
+	code  string
+}
+
+// GetCompilationInfo calls callback immediately with no messages, as the code is never compiled.
+func (m *shaderModule) GetCompilationInfo(callback func(messages []gpu.CompilationMessage, err error)) {
+	callback(nil, nil)
+}
+
+type bindGroupLayout struct{}
+
+type pipelineLayout struct{}
+
+// bindGroup holds the bound range of each buffer, keyed by binding.
+type bindGroup struct {
+	entries map[int][]byte
+}
+
+type computePipeline struct {
+	kernel        Kernel
+	workgroupSize [3]uint32
+	overrides     wgsl.Overrides
+	workers       int
+}
+
+func (p *computePipeline) GetBindGroupLayout(index int) gpu.BindGroupLayout {
+	return &bindGroupLayout{}
+}
+
+type renderPipeline struct{}
+
+func (p *renderPipeline) GetBindGroupLayout(index int) gpu.BindGroupLayout {
+	return &bindGroupLayout{}
+}
+
+// CanvasContext is a gpu.CanvasContext for running headless.
+type CanvasContext struct{}
+
+var _ gpu.CanvasContext = CanvasContext{}
+
+type textureView struct{}
+
+func (CanvasContext) CurrentTextureView() gpu.TextureView {
+	return textureView{}
+}
+
+func (CanvasContext) Format() gpu.TextureFormat {
+	return gpu.TextureFormatBGRA8Unorm
+}
@@ -0,0 +1,185 @@
+package gpucpu
+
+import (
+	"sync/atomic"
+	"testing"
+	"unsafe"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+)
+
+const testShader = `
+override width : u32 = 4;
+
+@compute @workgroup_size(width, 2)
+fn record(@builtin(global_invocation_id) id : vec3<u32>) {}
+
+@compute @workgroup_size(8)
+fn count() {}
+`
+
+type invocationRecord struct {
+	localIndex  uint32
+	workgroupID [2]uint32
+}
+
+type counter struct {
+	total uint32
+}
+
+func TestDispatch(t *testing.T) {
+	kernels := Kernels{
+		"record": func(inv Invocation) {
+			records := Slice[invocationRecord](inv.Bindings, 0, 0)
+			width := inv.NumWorkgroups[0] * uint32(inv.Overrides["width"])
+			records[inv.GlobalID[1]*width+inv.GlobalID[0]] = invocationRecord{
+				localIndex:  inv.LocalIndex,
+				workgroupID: [2]uint32{inv.WorkgroupID[0], inv.WorkgroupID[1]},
+			}
+		},
+		"count": func(inv Invocation) {
+			c := Ptr[counter](inv.Bindings, 1, 3)
+			atomic.AddUint32(&c.total, 1)
+		},
+	}
+
+	tests := []struct {
+		name    string
+		workers int
+	}{
+		{name: "one worker", workers: 1},
+		{name: "many workers", workers: 16},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDevice(kernels)
+			d.Workers = tc.workers
+			const recordSize = uint64(unsafe.Sizeof(invocationRecord{}))
+			records := d.CreateBuffer(gpu.BufferDescriptor{Size: 6 * 4 * recordSize, Usage: gpu.BufferUsageStorage | gpu.BufferUsageMapRead})
+			counts := d.CreateBuffer(gpu.BufferDescriptor{Size: 4, Usage: gpu.BufferUsageStorage | gpu.BufferUsageMapRead})
+			module := d.CreateShaderModule(gpu.ShaderModuleDescriptor{Code: testShader})
+
+			recordPipeline := d.CreateComputePipeline(gpu.ComputePipelineDescriptor{
+				Compute: gpu.ProgrammableStage{Module: module, EntryPoint: "record", Constants: map[string]float64{"width": 2}},
+			})
+			countPipeline := d.CreateComputePipeline(gpu.ComputePipelineDescriptor{
+				Compute: gpu.ProgrammableStage{Module: module, EntryPoint: "count"},
+			})
+			recordGroup := d.CreateBindGroup(gpu.BindGroupDescriptor{Entries: []gpu.BindGroupEntry{{Binding: 0, Buffer: records}}})
+			countGroup := d.CreateBindGroup(gpu.BindGroupDescriptor{Entries: []gpu.BindGroupEntry{{Binding: 3, Buffer: counts}}})
+
+			enc := d.CreateCommandEncoder()
+			pass := enc.BeginComputePass(gpu.ComputePassDescriptor{})
+			pass.SetPipeline(recordPipeline)
+			pass.SetBindGroup(0, recordGroup)
+			pass.DispatchWorkgroups(3, 2, 1)
+			pass.SetPipeline(countPipeline)
+			pass.SetBindGroup(1, countGroup)
+			pass.DispatchWorkgroups(5, 1, 2)
+			pass.End()
+			d.Queue().Submit(enc.Finish())
+
+			var got []invocationRecord
+			records.MapRead(0, records.Size(), func(data []byte, err error) {
+				got = unsafe.Slice((*invocationRecord)(unsafe.Pointer(&data[0])), len(data)/int(recordSize))
+			})
+			// Each workgroup is 2x2 invocations, and there are 3x2 workgroups.
+			var want []invocationRecord
+			for y := uint32(0); y < 4; y++ {
+				for x := uint32(0); x < 6; x++ {
+					want = append(want, invocationRecord{
+						localIndex:  (y%2)*2 + x%2,
+						workgroupID: [2]uint32{x / 2, y / 2},
+					})
+				}
+			}
+			if diff := cmp.Diff(want, got, cmp.AllowUnexported(invocationRecord{})); diff != "" {
+				t.Errorf("records diff (-want +got):\n%s", diff)
+			}
+
+			var total uint32
+			counts.MapRead(0, 4, func(data []byte, err error) { total = *(*uint32)(unsafe.Pointer(&data[0])) })
+			if want := uint32(5 * 2 * 8); total != want {
+				t.Errorf("count kernel ran %d times, want %d", total, want)
+			}
+		})
+	}
+}
+
+func TestOverrideDefaults(t *testing.T) {
+	kernels := Kernels{
+		"record": func(inv Invocation) {
+			c := Ptr[counter](inv.Bindings, 0, 0)
+			atomic.StoreUint32(&c.total, uint32(inv.Overrides["width"]))
+		},
+	}
+	d := NewDevice(kernels)
+	widths := d.CreateBuffer(gpu.BufferDescriptor{Size: 4, Usage: gpu.BufferUsageStorage | gpu.BufferUsageMapRead})
+	module := d.CreateShaderModule(gpu.ShaderModuleDescriptor{Code: testShader})
+	pipeline := d.CreateComputePipeline(gpu.ComputePipelineDescriptor{
+		Compute: gpu.ProgrammableStage{Module: module, EntryPoint: "record"},
+	})
+	group := d.CreateBindGroup(gpu.BindGroupDescriptor{Entries: []gpu.BindGroupEntry{{Binding: 0, Buffer: widths}}})
+
+	enc := d.CreateCommandEncoder()
+	pass := enc.BeginComputePass(gpu.ComputePassDescriptor{})
+	pass.SetPipeline(pipeline)
+	pass.SetBindGroup(0, group)
+	pass.DispatchWorkgroups(1, 1, 1)
+	pass.End()
+	d.Queue().Submit(enc.Finish())
+
+	var got uint32
+	widths.MapRead(0, 4, func(data []byte, err error) { got = *(*uint32)(unsafe.Pointer(&data[0])) })
+	if want := uint32(4); got != want {
+		t.Errorf("width = %d, want its default of %d", got, want)
+	}
+}
+
+func TestCreateComputePipelinePanics(t *testing.T) {
+	d := NewDevice(Kernels{"record": func(Invocation) {}})
+	module := d.CreateShaderModule(gpu.ShaderModuleDescriptor{Code: testShader})
+	defer func() {
+		if recover() == nil {
+			t.Errorf("CreateComputePipeline() without a kernel didn't panic")
+		}
+	}()
+	d.CreateComputePipeline(gpu.ComputePipelineDescriptor{
+		Compute: gpu.ProgrammableStage{Module: module, EntryPoint: "count"},
+	})
+}
+
+func TestDispatchIndirect(t *testing.T) {
+	kernels := Kernels{
+		"count": func(inv Invocation) {
+			c := Ptr[counter](inv.Bindings, 0, 0)
+			atomic.AddUint32(&c.total, 1)
+		},
+	}
+	d := NewDevice(kernels)
+	counts := d.CreateBuffer(gpu.BufferDescriptor{Size: 4, Usage: gpu.BufferUsageStorage | gpu.BufferUsageMapRead})
+	args := d.CreateBuffer(gpu.BufferDescriptor{Size: 16, Usage: gpu.BufferUsageIndirect | gpu.BufferUsageCopyDst})
+	module := d.CreateShaderModule(gpu.ShaderModuleDescriptor{Code: testShader})
+	pipeline := d.CreateComputePipeline(gpu.ComputePipelineDescriptor{
+		Compute: gpu.ProgrammableStage{Module: module, EntryPoint: "count"},
+	})
+	group := d.CreateBindGroup(gpu.BindGroupDescriptor{Entries: []gpu.BindGroupEntry{{Binding: 0, Buffer: counts}}})
+
+	enc := d.CreateCommandEncoder()
+	pass := enc.BeginComputePass(gpu.ComputePassDescriptor{})
+	pass.SetPipeline(pipeline)
+	pass.SetBindGroup(0, group)
+	pass.DispatchWorkgroupsIndirect(args, 4)
+	pass.End()
+	// The arguments are read when the commands run, not when they're recorded.
+	workgroups := []uint32{3, 2, 1}
+	d.Queue().WriteBuffer(args, 4, unsafe.Slice((*byte)(unsafe.Pointer(&workgroups[0])), 12))
+	d.Queue().Submit(enc.Finish())
+
+	var total uint32
+	counts.MapRead(0, 4, func(data []byte, err error) { total = *(*uint32)(unsafe.Pointer(&data[0])) })
+	if want := uint32(3 * 2 * 8); total != want {
+		t.Errorf("count kernel ran %d times, want %d", total, want)
+	}
+}
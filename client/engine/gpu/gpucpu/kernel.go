@@ -0,0 +1,134 @@
+package gpucpu
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+)
+
+// A Kernel is the Go equivalent of a compute shader entry point. It's called once for each invocation.
+//
+// Workgroups run concurrently, but the invocations in a workgroup run one after another, in
+// local_invocation_index order, on the same goroutine, so kernels can't wait on each other as
+// with workgroupBarrier. Values which the shader updates with atomic builtins must be updated
+// with sync/atomic.
+type Kernel func(inv Invocation)
+
+// Kernels maps compute entry point names to the kernels which implement them.
+type Kernels map[string]Kernel
+
+// An Invocation holds the builtin values and resources for one invocation of a kernel.
+type Invocation struct {
+	// GlobalID is global_invocation_id.
+	GlobalID [3]uint32
+	// LocalID is local_invocation_id.
+	LocalID [3]uint32
+	// LocalIndex is local_invocation_index.
+	LocalIndex uint32
+	// WorkgroupID is workgroup_id.
+	WorkgroupID [3]uint32
+	// NumWorkgroups is num_workgroups.
+	NumWorkgroups [3]uint32
+
+	// Bindings are the buffers bound for the dispatch.
+	Bindings Bindings
+	// Overrides holds the value of each override declaration: the value set when the pipeline was created,
+	// or its default if it wasn't set.
+	Overrides wgsl.Overrides
+}
+
+// Bindings are the buffers bound for a dispatch.
+type Bindings struct {
+	groups map[int]*bindGroup
+}
+
+// Bytes returns the range of the buffer bound to @group(group) @binding(binding).
+// Panics if nothing is bound there.
+func (b Bindings) Bytes(group, binding int) []byte {
+	bg, ok := b.groups[group]
+	if !ok {
+		panic(fmt.Sprintf("no bind group set at @group(%d)", group))
+	}
+	data, ok := bg.entries[binding]
+	if !ok {
+		panic(fmt.Sprintf("no buffer bound at @group(%d) @binding(%d)", group, binding))
+	}
+	return data
+}
+
+// Slice returns the buffer bound to @group(group) @binding(binding) as a []T.
+// Writes to the slice update the buffer.
+func Slice[T any](b Bindings, group, binding int) []T {
+	data := b.Bytes(group, binding)
+	var zero T
+	n := uintptr(len(data)) / unsafe.Sizeof(zero)
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(&data[0])), n)
+}
+
+// Ptr returns a pointer to the value at the start of the buffer bound to @group(group) @binding(binding).
+// Panics if the buffer is too small to hold a T.
+func Ptr[T any](b Bindings, group, binding int) *T {
+	data := b.Bytes(group, binding)
+	var zero T
+	if uintptr(len(data)) < unsafe.Sizeof(zero) {
+		panic(fmt.Sprintf("@group(%d) @binding(%d): %d bytes can't hold a %T", group, binding, len(data), zero))
+	}
+	return (*T)(unsafe.Pointer(&data[0]))
+}
+
+// dispatch runs the kernel for every invocation in the workgroups, returning once they've all completed.
+func (p *computePipeline) dispatch(bindings Bindings, numWorkgroups [3]uint32) {
+	total := numWorkgroups[0] * numWorkgroups[1] * numWorkgroups[2]
+	workers := min(uint32(max(p.workers, 1)), total)
+
+	var next atomic.Uint32
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := next.Add(1) - 1
+				if i >= total {
+					return
+				}
+				workgroupID := [3]uint32{
+					i % numWorkgroups[0],
+					i / numWorkgroups[0] % numWorkgroups[1],
+					i / (numWorkgroups[0] * numWorkgroups[1]),
+				}
+				p.runWorkgroup(bindings, workgroupID, numWorkgroups)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// runWorkgroup runs the kernel for each invocation in the workgroup.
+func (p *computePipeline) runWorkgroup(bindings Bindings, workgroupID, numWorkgroups [3]uint32) {
+	size := p.workgroupSize
+	inv := Invocation{
+		WorkgroupID:   workgroupID,
+		NumWorkgroups: numWorkgroups,
+		Bindings:      bindings,
+		Overrides:     p.overrides,
+	}
+	for z := uint32(0); z < size[2]; z++ {
+		for y := uint32(0); y < size[1]; y++ {
+			for x := uint32(0); x < size[0]; x++ {
+				inv.LocalID = [3]uint32{x, y, z}
+				inv.LocalIndex = (z*size[1]+y)*size[0] + x
+				for i := range inv.GlobalID {
+					inv.GlobalID[i] = workgroupID[i]*size[i] + inv.LocalID[i]
+				}
+				p.kernel(inv)
+			}
+		}
+	}
+}
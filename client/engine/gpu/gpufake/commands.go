@@ -0,0 +1,256 @@
+package gpufake
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+)
+
+// A Command is a command recorded by a CommandEncoder: a Dispatch, Draw, Copy, Clear or Resolve.
+type Command interface {
+	isCommand()
+}
+
+// A Dispatch records a compute dispatch and the state it was made with.
+type Dispatch struct {
+	Pass       gpu.ComputePassDescriptor
+	Pipeline   *ComputePipeline
+	BindGroups map[int]*BindGroup
+	X, Y, Z    uint32
+
+	// IndirectBuffer is the buffer holding the workgroup counts of indirect dispatches, which have zero X, Y and Z.
+	IndirectBuffer *Buffer
+	IndirectOffset uint64
+}
+
+// A Draw records a draw and the state it was made with.
+type Draw struct {
+	Pass          gpu.RenderPassDescriptor
+	Pipeline      *RenderPipeline
+	BindGroups    map[int]*BindGroup
+	VertexBuffers map[int]*Buffer
+
+	VertexCount   uint32
+	InstanceCount uint32
+	FirstVertex   uint32
+	FirstInstance uint32
+
+	// IndirectBuffer is the buffer holding the arguments of indirect draws, which have zero counts.
+	IndirectBuffer *Buffer
+	IndirectOffset uint64
+}
+
+type Copy struct {
+	Source            *Buffer
+	SourceOffset      uint64
+	Destination       *Buffer
+	DestinationOffset uint64
+	Size              uint64
+}
+
+type Clear struct {
+	Buffer *Buffer
+	Offset uint64
+	Size   uint64
+}
+
+// A Resolve records the resolution of queries into a buffer.
+type Resolve struct {
+	QuerySet          *QuerySet
+	FirstQuery        int
+	QueryCount        int
+	Destination       *Buffer
+	DestinationOffset uint64
+}
+
+func (Dispatch) isCommand() {}
+func (Draw) isCommand()     {}
+func (Copy) isCommand()     {}
+func (Clear) isCommand()    {}
+func (Resolve) isCommand()  {}
+
+// A CommandBuffer holds the commands recorded by a CommandEncoder.
+type CommandBuffer struct {
+	Commands []Command
+}
+
+// A CommandEncoder records commands.
+type CommandEncoder struct {
+	commands []Command
+	finished bool
+}
+
+func (e *CommandEncoder) BeginComputePass(desc gpu.ComputePassDescriptor) gpu.ComputePassEncoder {
+	return &computePassEncoder{encoder: e, desc: desc, bindGroups: map[int]*BindGroup{}}
+}
+
+func (e *CommandEncoder) BeginRenderPass(desc gpu.RenderPassDescriptor) gpu.RenderPassEncoder {
+	// Callers often reuse the descriptor, changing the view each frame.
+	desc.ColorAttachments = slices.Clone(desc.ColorAttachments)
+	return &renderPassEncoder{encoder: e, desc: desc, bindGroups: map[int]*BindGroup{}, vertexBuffers: map[int]*Buffer{}}
+}
+
+func (e *CommandEncoder) CopyBufferToBuffer(source gpu.Buffer, sourceOffset uint64, destination gpu.Buffer, destinationOffset uint64, size uint64) {
+	src, dst := source.(*Buffer), destination.(*Buffer)
+	src.checkRange("CopyBufferToBuffer", sourceOffset, size)
+	dst.checkRange("CopyBufferToBuffer", destinationOffset, size)
+	e.record(Copy{Source: src, SourceOffset: sourceOffset, Destination: dst, DestinationOffset: destinationOffset, Size: size})
+}
+
+func (e *CommandEncoder) ClearBuffer(buffer gpu.Buffer, offset, size uint64) {
+	b := buffer.(*Buffer)
+	b.checkRange("ClearBuffer", offset, size)
+	e.record(Clear{Buffer: b, Offset: offset, Size: size})
+}
+
+func (e *CommandEncoder) ResolveQuerySet(querySet gpu.QuerySet, firstQuery, queryCount int, destination gpu.Buffer, destinationOffset uint64) {
+	qs, dst := querySet.(*QuerySet), destination.(*Buffer)
+	if firstQuery < 0 || queryCount < 0 || firstQuery+queryCount > qs.Desc.Count {
+		panic(fmt.Sprintf("ResolveQuerySet of %d queries from %d exceeds count %d", queryCount, firstQuery, qs.Desc.Count))
+	}
+	if dst.Desc.Usage&gpu.BufferUsageQueryResolve == 0 {
+		panic(fmt.Sprintf("buffer %q: ResolveQuerySet without BufferUsageQueryResolve", dst.Desc.Label))
+	}
+	if destinationOffset%256 != 0 {
+		panic(fmt.Sprintf("buffer %q: ResolveQuerySet to offset %d, which isn't a multiple of 256", dst.Desc.Label, destinationOffset))
+	}
+	dst.checkRange("ResolveQuerySet", destinationOffset, uint64(8*queryCount))
+	e.record(Resolve{QuerySet: qs, FirstQuery: firstQuery, QueryCount: queryCount, Destination: dst, DestinationOffset: destinationOffset})
+}
+
+func (e *CommandEncoder) Finish() gpu.CommandBuffer {
+	e.finished = true
+	return &CommandBuffer{Commands: e.commands}
+}
+
+func (e *CommandEncoder) record(cmd Command) {
+	if e.finished {
+		panic(fmt.Sprintf("recording %T after Finish", cmd))
+	}
+	e.commands = append(e.commands, cmd)
+}
+
+type computePassEncoder struct {
+	encoder    *CommandEncoder
+	desc       gpu.ComputePassDescriptor
+	pipeline   *ComputePipeline
+	bindGroups map[int]*BindGroup
+}
+
+func (e *computePassEncoder) SetPipeline(pipeline gpu.ComputePipeline) {
+	e.pipeline = pipeline.(*ComputePipeline)
+}
+
+func (e *computePassEncoder) SetBindGroup(index int, bindGroup gpu.BindGroup) {
+	e.bindGroups[index] = bindGroup.(*BindGroup)
+}
+
+func (e *computePassEncoder) DispatchWorkgroups(x, y, z uint32) {
+	if e.pipeline == nil {
+		panic("DispatchWorkgroups without a pipeline")
+	}
+	e.encoder.record(Dispatch{
+		Pass:       e.desc,
+		Pipeline:   e.pipeline,
+		BindGroups: maps.Clone(e.bindGroups),
+		X:          x,
+		Y:          y,
+		Z:          z,
+	})
+}
+
+func (e *computePassEncoder) DispatchWorkgroupsIndirect(indirectBuffer gpu.Buffer, indirectOffset uint64) {
+	if e.pipeline == nil {
+		panic("DispatchWorkgroupsIndirect without a pipeline")
+	}
+	e.encoder.record(Dispatch{
+		Pass:           e.desc,
+		Pipeline:       e.pipeline,
+		BindGroups:     maps.Clone(e.bindGroups),
+		IndirectBuffer: indirectBuffer.(*Buffer),
+		IndirectOffset: indirectOffset,
+	})
+}
+
+func (e *computePassEncoder) End() {}
+
+type renderPassEncoder struct {
+	encoder       *CommandEncoder
+	desc          gpu.RenderPassDescriptor
+	pipeline      *RenderPipeline
+	bindGroups    map[int]*BindGroup
+	vertexBuffers map[int]*Buffer
+}
+
+func (e *renderPassEncoder) SetPipeline(pipeline gpu.RenderPipeline) {
+	e.pipeline = pipeline.(*RenderPipeline)
+}
+
+func (e *renderPassEncoder) SetBindGroup(index int, bindGroup gpu.BindGroup) {
+	e.bindGroups[index] = bindGroup.(*BindGroup)
+}
+
+func (e *renderPassEncoder) SetVertexBuffer(slot int, buffer gpu.Buffer) {
+	e.vertexBuffers[slot] = buffer.(*Buffer)
+}
+
+func (e *renderPassEncoder) Draw(vertexCount, instanceCount, firstVertex, firstInstance uint32) {
+	if e.pipeline == nil {
+		panic("Draw without a pipeline")
+	}
+	e.encoder.record(Draw{
+		Pass:          e.desc,
+		Pipeline:      e.pipeline,
+		BindGroups:    maps.Clone(e.bindGroups),
+		VertexBuffers: maps.Clone(e.vertexBuffers),
+		VertexCount:   vertexCount,
+		InstanceCount: instanceCount,
+		FirstVertex:   firstVertex,
+		FirstInstance: firstInstance,
+	})
+}
+
+func (e *renderPassEncoder) DrawIndirect(indirectBuffer gpu.Buffer, indirectOffset uint64) {
+	if e.pipeline == nil {
+		panic("DrawIndirect without a pipeline")
+	}
+	e.encoder.record(Draw{
+		Pass:           e.desc,
+		Pipeline:       e.pipeline,
+		BindGroups:     maps.Clone(e.bindGroups),
+		VertexBuffers:  maps.Clone(e.vertexBuffers),
+		IndirectBuffer: indirectBuffer.(*Buffer),
+		IndirectOffset: indirectOffset,
+	})
+}
+
+func (e *renderPassEncoder) End() {}
+
+// CanvasContext is a gpu.CanvasContext which returns a new TextureView for each frame.
+type CanvasContext struct {
+	// Frames is the number of views returned by CurrentTextureView.
+	Frames int
+	// TextureFormat is returned by Format. It defaults to gpu.TextureFormatBGRA8Unorm.
+	TextureFormat gpu.TextureFormat
+}
+
+var _ gpu.CanvasContext = (*CanvasContext)(nil)
+
+// A TextureView identifies the frame it was returned for.
+type TextureView struct {
+	Frame int
+}
+
+func (c *CanvasContext) CurrentTextureView() gpu.TextureView {
+	c.Frames++
+	return &TextureView{Frame: c.Frames}
+}
+
+func (c *CanvasContext) Format() gpu.TextureFormat {
+	if c.TextureFormat == "" {
+		return gpu.TextureFormatBGRA8Unorm
+	}
+	return c.TextureFormat
+}
@@ -0,0 +1,352 @@
+// Package gpufake provides an in-memory gpu.Device for tests.
+//
+// The device records every resource it creates, every buffer write and every command submitted,
+// so tests can check what the engine asked the GPU to do. Buffer writes, copies and clears are
+// applied to the buffers' contents, but shaders are never run.
+package gpufake
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+)
+
+// Device is a gpu.Device which records the resources created and commands submitted.
+type Device struct {
+	Buffers          []*Buffer
+	ShaderModules    []*ShaderModule
+	BindGroupLayouts []*BindGroupLayout
+	BindGroups       []*BindGroup
+	PipelineLayouts  []*PipelineLayout
+	ComputePipelines []*ComputePipeline
+	RenderPipelines  []*RenderPipeline
+	QuerySets        []*QuerySet
+
+	// Features are the features HasFeature reports the device as having.
+	Features []gpu.FeatureName
+
+	// DeferMapReads makes MapRead wait for CompleteMapReads to call callbacks, as a browser does, rather than
+	// calling them immediately.
+	DeferMapReads bool
+	pendingMaps   []func()
+	// MapReadErr, if set, is passed to MapRead callbacks in place of the buffers' contents, as when the
+	// device is lost.
+	MapReadErr error
+
+	// Writes holds the buffer writes made through the queue, in order.
+	Writes []Write
+	// Submissions holds the command buffers submitted to the queue, in order.
+	Submissions []*CommandBuffer
+
+	// CompilationMessages holds the messages reported when compiling shader modules, keyed by label.
+	CompilationMessages map[string][]gpu.CompilationMessage
+	// CompilationInfoErr, if set, is passed to GetCompilationInfo callbacks in place of the messages.
+	CompilationInfoErr error
+}
+
+var _ gpu.Device = (*Device)(nil)
+
+func NewDevice() *Device {
+	return &Device{}
+}
+
+func (d *Device) Queue() gpu.Queue {
+	return queue{d}
+}
+
+func (d *Device) CreateBuffer(desc gpu.BufferDescriptor) gpu.Buffer {
+	if uint64(len(desc.Contents)) > desc.Size {
+		panic(fmt.Sprintf("buffer %q: %d bytes of contents don't fit in %d bytes", desc.Label, len(desc.Contents), desc.Size))
+	}
+	b := &Buffer{Desc: desc, Data: make([]byte, desc.Size), device: d}
+	copy(b.Data, desc.Contents)
+	b.Desc.Contents = nil
+	d.Buffers = append(d.Buffers, b)
+	return b
+}
+
+func (d *Device) CreateShaderModule(desc gpu.ShaderModuleDescriptor) gpu.ShaderModule {
+	m := &ShaderModule{Desc: desc, Messages: d.CompilationMessages[desc.Label], Err: d.CompilationInfoErr}
+	d.ShaderModules = append(d.ShaderModules, m)
+	return m
+}
+
+func (d *Device) CreateBindGroupLayout(desc gpu.BindGroupLayoutDescriptor) gpu.BindGroupLayout {
+	l := &BindGroupLayout{Desc: desc}
+	d.BindGroupLayouts = append(d.BindGroupLayouts, l)
+	return l
+}
+
+func (d *Device) CreateBindGroup(desc gpu.BindGroupDescriptor) gpu.BindGroup {
+	for _, e := range desc.Entries {
+		if _, ok := e.Buffer.(*Buffer); !ok {
+			panic(fmt.Sprintf("bind group %q: binding %d: got buffer %T, want *gpufake.Buffer", desc.Label, e.Binding, e.Buffer))
+		}
+	}
+	bg := &BindGroup{Desc: desc}
+	d.BindGroups = append(d.BindGroups, bg)
+	return bg
+}
+
+func (d *Device) CreatePipelineLayout(desc gpu.PipelineLayoutDescriptor) gpu.PipelineLayout {
+	l := &PipelineLayout{Desc: desc}
+	d.PipelineLayouts = append(d.PipelineLayouts, l)
+	return l
+}
+
+func (d *Device) CreateComputePipeline(desc gpu.ComputePipelineDescriptor) gpu.ComputePipeline {
+	p := &ComputePipeline{Desc: desc}
+	d.ComputePipelines = append(d.ComputePipelines, p)
+	return p
+}
+
+func (d *Device) CreateRenderPipeline(desc gpu.RenderPipelineDescriptor) gpu.RenderPipeline {
+	p := &RenderPipeline{Desc: desc}
+	d.RenderPipelines = append(d.RenderPipelines, p)
+	return p
+}
+
+func (d *Device) CreateCommandEncoder() gpu.CommandEncoder {
+	return &CommandEncoder{}
+}
+
+func (d *Device) CreateQuerySet(desc gpu.QuerySetDescriptor) gpu.QuerySet {
+	if desc.Type == gpu.QueryTypeTimestamp && !d.HasFeature(gpu.FeatureTimestampQuery) {
+		panic("CreateQuerySet: timestamp queries without FeatureTimestampQuery")
+	}
+	qs := &QuerySet{Desc: desc, Results: make([]uint64, desc.Count)}
+	d.QuerySets = append(d.QuerySets, qs)
+	return qs
+}
+
+func (d *Device) HasFeature(feature gpu.FeatureName) bool {
+	return slices.Contains(d.Features, feature)
+}
+
+// CompleteMapReads calls the callbacks of the MapReads deferred by DeferMapReads, in order.
+func (d *Device) CompleteMapReads() {
+	pending := d.pendingMaps
+	d.pendingMaps = nil
+	for _, fn := range pending {
+		fn()
+	}
+}
+
+// Commands returns the commands from every submission, in order.
+func (d *Device) Commands() []Command {
+	var cmds []Command
+	for _, cb := range d.Submissions {
+		cmds = append(cmds, cb.Commands...)
+	}
+	return cmds
+}
+
+// Dispatches returns the dispatches from every submission, in order.
+func (d *Device) Dispatches() []Dispatch {
+	var dispatches []Dispatch
+	for _, cmd := range d.Commands() {
+		if dispatch, ok := cmd.(Dispatch); ok {
+			dispatches = append(dispatches, dispatch)
+		}
+	}
+	return dispatches
+}
+
+// Draws returns the draws from every submission, in order.
+func (d *Device) Draws() []Draw {
+	var draws []Draw
+	for _, cmd := range d.Commands() {
+		if draw, ok := cmd.(Draw); ok {
+			draws = append(draws, draw)
+		}
+	}
+	return draws
+}
+
+// WritesTo returns the writes made to b, in order.
+func (d *Device) WritesTo(b gpu.Buffer) []Write {
+	var writes []Write
+	for _, w := range d.Writes {
+		if w.Buffer == b {
+			writes = append(writes, w)
+		}
+	}
+	return writes
+}
+
+// A Write is a buffer write made through the queue.
+type Write struct {
+	Buffer *Buffer
+	Offset uint64
+	Data   []byte
+}
+
+type queue struct {
+	device *Device
+}
+
+func (q queue) WriteBuffer(buffer gpu.Buffer, offset uint64, data []byte) {
+	b := buffer.(*Buffer)
+	b.checkRange("WriteBuffer", offset, uint64(len(data)))
+	copy(b.Data[offset:], data)
+	q.device.Writes = append(q.device.Writes, Write{Buffer: b, Offset: offset, Data: append([]byte(nil), data...)})
+}
+
+// Submit applies the copies, clears and resolves in the command buffers to the buffers' contents.
+func (q queue) Submit(commandBuffers ...gpu.CommandBuffer) {
+	for _, cb := range commandBuffers {
+		cb := cb.(*CommandBuffer)
+		for _, cmd := range cb.Commands {
+			switch cmd := cmd.(type) {
+			case Copy:
+				if cmd.Destination.mapping {
+					panic(fmt.Sprintf("buffer %q: copied to while mapping", cmd.Destination.Desc.Label))
+				}
+				copy(cmd.Destination.Data[cmd.DestinationOffset:cmd.DestinationOffset+cmd.Size], cmd.Source.Data[cmd.SourceOffset:])
+			case Clear:
+				clear(cmd.Buffer.Data[cmd.Offset : cmd.Offset+cmd.Size])
+			case Resolve:
+				for i, r := range cmd.QuerySet.Results[cmd.FirstQuery : cmd.FirstQuery+cmd.QueryCount] {
+					binary.LittleEndian.PutUint64(cmd.Destination.Data[cmd.DestinationOffset+uint64(8*i):], r)
+				}
+			}
+		}
+		q.device.Submissions = append(q.device.Submissions, cb)
+	}
+}
+
+// A Buffer holds its contents in memory.
+type Buffer struct {
+	// Desc is the descriptor the buffer was created with. Its Contents are cleared.
+	Desc      gpu.BufferDescriptor
+	Data      []byte
+	Destroyed bool
+
+	device *Device
+	// mapping is set while a deferred MapRead is pending.
+	mapping bool
+}
+
+// Equal reports whether b and o are the same buffer, so cmp compares buffers by identity.
+func (b *Buffer) Equal(o *Buffer) bool {
+	return b == o
+}
+
+func (b *Buffer) Size() uint64 {
+	return b.Desc.Size
+}
+
+// MapRead calls callback with a copy of the buffer's contents, or the device's MapReadErr, immediately unless
+// the device has DeferMapReads. It panics if the buffer is already being mapped.
+func (b *Buffer) MapRead(offset, size uint64, callback func(data []byte, err error)) {
+	if b.Desc.Usage&gpu.BufferUsageMapRead == 0 {
+		panic(fmt.Sprintf("buffer %q: MapRead without BufferUsageMapRead", b.Desc.Label))
+	}
+	if b.mapping {
+		panic(fmt.Sprintf("buffer %q: MapRead while already mapping", b.Desc.Label))
+	}
+	b.checkRange("MapRead", offset, size)
+	if !b.device.DeferMapReads {
+		b.completeMapRead(offset, size, callback)
+		return
+	}
+	b.mapping = true
+	b.device.pendingMaps = append(b.device.pendingMaps, func() {
+		b.mapping = false
+		b.completeMapRead(offset, size, callback)
+	})
+}
+
+func (b *Buffer) completeMapRead(offset, size uint64, callback func(data []byte, err error)) {
+	if err := b.device.MapReadErr; err != nil {
+		callback(nil, err)
+		return
+	}
+	callback(append([]byte(nil), b.Data[offset:offset+size]...), nil)
+}
+
+func (b *Buffer) Destroy() {
+	b.Destroyed = true
+}
+
+// checkRange panics if the range isn't in the buffer, or the buffer has been destroyed.
+func (b *Buffer) checkRange(op string, offset, size uint64) {
+	if b.Destroyed {
+		panic(fmt.Sprintf("buffer %q: %s after Destroy", b.Desc.Label, op))
+	}
+	if offset+size > b.Size() {
+		panic(fmt.Sprintf("buffer %q: %s of %d bytes at offset %d exceeds size %d", b.Desc.Label, op, size, offset, b.Size()))
+	}
+}
+
+// A QuerySet holds the results its queries resolve to.
+type QuerySet struct {
+	Desc gpu.QuerySetDescriptor
+	// Results are written to buffers when resolves are submitted. Passes don't write them, so tests set them.
+	Results   []uint64
+	Destroyed bool
+}
+
+func (qs *QuerySet) Destroy() {
+	qs.Destroyed = true
+}
+
+type ShaderModule struct {
+	Desc gpu.ShaderModuleDescriptor
+	// Messages are the device's CompilationMessages for the module's label.
+	Messages []gpu.CompilationMessage
+	// Err is the device's CompilationInfoErr when the module was created.
+	Err error
+}
+
+// GetCompilationInfo calls callback immediately with the module's Messages, or its Err if set.
+func (m *ShaderModule) GetCompilationInfo(callback func(messages []gpu.CompilationMessage, err error)) {
+	if m.Err != nil {
+		callback(nil, m.Err)
+		return
+	}
+	callback(m.Messages, nil)
+}
+
+type BindGroupLayout struct {
+	// Desc is the descriptor for layouts created by the device.
+	Desc gpu.BindGroupLayoutDescriptor
+	// Index is the index of layouts retrieved from a pipeline.
+	Index int
+}
+
+type BindGroup struct {
+	Desc gpu.BindGroupDescriptor
+}
+
+// BufferAt returns the buffer bound to binding, or nil if there isn't one.
+func (bg *BindGroup) BufferAt(binding int) *Buffer {
+	for _, e := range bg.Desc.Entries {
+		if e.Binding == binding {
+			return e.Buffer.(*Buffer)
+		}
+	}
+	return nil
+}
+
+type PipelineLayout struct {
+	Desc gpu.PipelineLayoutDescriptor
+}
+
+type ComputePipeline struct {
+	Desc gpu.ComputePipelineDescriptor
+}
+
+func (p *ComputePipeline) GetBindGroupLayout(index int) gpu.BindGroupLayout {
+	return &BindGroupLayout{Index: index}
+}
+
+type RenderPipeline struct {
+	Desc gpu.RenderPipelineDescriptor
+}
+
+func (p *RenderPipeline) GetBindGroupLayout(index int) gpu.BindGroupLayout {
+	return &BindGroupLayout{Index: index}
+}
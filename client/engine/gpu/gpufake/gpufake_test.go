@@ -0,0 +1,190 @@
+package gpufake
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+)
+
+func TestBufferContents(t *testing.T) {
+	d := NewDevice()
+	src := d.CreateBuffer(gpu.BufferDescriptor{Size: 8, Usage: gpu.BufferUsageCopySrc, Contents: []byte{1, 2, 3, 4}})
+	dst := d.CreateBuffer(gpu.BufferDescriptor{Size: 8, Usage: gpu.BufferUsageCopyDst | gpu.BufferUsageMapRead})
+
+	d.Queue().WriteBuffer(src, 4, []byte{5, 6, 7, 8})
+	d.Queue().WriteBuffer(dst, 0, []byte{9, 9, 9, 9, 9, 9, 9, 9})
+
+	enc := d.CreateCommandEncoder()
+	enc.CopyBufferToBuffer(src, 2, dst, 0, 4)
+	enc.ClearBuffer(dst, 6, 2)
+	cb := enc.Finish()
+
+	var got []byte
+	dst.MapRead(0, 8, func(data []byte, err error) { got = data })
+	if diff := cmp.Diff([]byte{9, 9, 9, 9, 9, 9, 9, 9}, got); diff != "" {
+		t.Errorf("contents before Submit diff (-want +got):\n%s", diff)
+	}
+
+	d.Queue().Submit(cb)
+	dst.MapRead(0, 8, func(data []byte, err error) { got = data })
+	if diff := cmp.Diff([]byte{3, 4, 5, 6, 9, 9, 0, 0}, got); diff != "" {
+		t.Errorf("contents after Submit diff (-want +got):\n%s", diff)
+	}
+
+	wantWrites := []Write{
+		{Buffer: src.(*Buffer), Offset: 4, Data: []byte{5, 6, 7, 8}},
+	}
+	if diff := cmp.Diff(wantWrites, d.WritesTo(src)); diff != "" {
+		t.Errorf("WritesTo() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	d := NewDevice()
+	buf := d.CreateBuffer(gpu.BufferDescriptor{Size: 16, Usage: gpu.BufferUsageStorage | gpu.BufferUsageVertex})
+	args := d.CreateBuffer(gpu.BufferDescriptor{Size: 32, Usage: gpu.BufferUsageIndirect})
+	module := d.CreateShaderModule(gpu.ShaderModuleDescriptor{Code: "fn main() {}"})
+	computePipeline := d.CreateComputePipeline(gpu.ComputePipelineDescriptor{
+		Compute: gpu.ProgrammableStage{Module: module, EntryPoint: "main"},
+	})
+	renderPipeline := d.CreateRenderPipeline(gpu.RenderPipelineDescriptor{
+		Vertex: gpu.VertexState{ProgrammableStage: gpu.ProgrammableStage{Module: module, EntryPoint: "main"}},
+	})
+	bg := d.CreateBindGroup(gpu.BindGroupDescriptor{
+		Layout:  computePipeline.GetBindGroupLayout(0),
+		Entries: []gpu.BindGroupEntry{{Binding: 3, Buffer: buf}},
+	})
+	view := (&CanvasContext{}).CurrentTextureView()
+	renderPass := gpu.RenderPassDescriptor{ColorAttachments: []gpu.RenderPassColorAttachment{{View: view}}}
+
+	enc := d.CreateCommandEncoder()
+	cpe := enc.BeginComputePass(gpu.ComputePassDescriptor{Label: "update"})
+	cpe.SetPipeline(computePipeline)
+	cpe.SetBindGroup(0, bg)
+	cpe.DispatchWorkgroups(4, 2, 1)
+	cpe.DispatchWorkgroupsIndirect(args, 16)
+	cpe.End()
+	rpe := enc.BeginRenderPass(renderPass)
+	rpe.SetPipeline(renderPipeline)
+	rpe.SetVertexBuffer(1, buf)
+	rpe.Draw(3, 10, 0, 0)
+	rpe.DrawIndirect(args, 0)
+	rpe.End()
+	d.Queue().Submit(enc.Finish())
+
+	wantDispatches := []Dispatch{{
+		Pass:       gpu.ComputePassDescriptor{Label: "update"},
+		Pipeline:   computePipeline.(*ComputePipeline),
+		BindGroups: map[int]*BindGroup{0: bg.(*BindGroup)},
+		X:          4, Y: 2, Z: 1,
+	}, {
+		Pass:           gpu.ComputePassDescriptor{Label: "update"},
+		Pipeline:       computePipeline.(*ComputePipeline),
+		BindGroups:     map[int]*BindGroup{0: bg.(*BindGroup)},
+		IndirectBuffer: args.(*Buffer),
+		IndirectOffset: 16,
+	}}
+	if diff := cmp.Diff(wantDispatches, d.Dispatches()); diff != "" {
+		t.Errorf("Dispatches() diff (-want +got):\n%s", diff)
+	}
+	wantDraws := []Draw{{
+		Pass:          renderPass,
+		Pipeline:      renderPipeline.(*RenderPipeline),
+		BindGroups:    map[int]*BindGroup{},
+		VertexBuffers: map[int]*Buffer{1: buf.(*Buffer)},
+		VertexCount:   3,
+		InstanceCount: 10,
+	}, {
+		Pass:           renderPass,
+		Pipeline:       renderPipeline.(*RenderPipeline),
+		BindGroups:     map[int]*BindGroup{},
+		VertexBuffers:  map[int]*Buffer{1: buf.(*Buffer)},
+		IndirectBuffer: args.(*Buffer),
+	}}
+	if diff := cmp.Diff(wantDraws, d.Draws()); diff != "" {
+		t.Errorf("Draws() diff (-want +got):\n%s", diff)
+	}
+	if got := bg.(*BindGroup).BufferAt(3); got != buf {
+		t.Errorf("BufferAt(3) = %v, want %v", got, buf)
+	}
+}
+
+func TestResolveQuerySet(t *testing.T) {
+	d := NewDevice()
+	d.Features = []gpu.FeatureName{gpu.FeatureTimestampQuery}
+	qs := d.CreateQuerySet(gpu.QuerySetDescriptor{Type: gpu.QueryTypeTimestamp, Count: 4})
+	dst := d.CreateBuffer(gpu.BufferDescriptor{Size: 16, Usage: gpu.BufferUsageQueryResolve | gpu.BufferUsageMapRead})
+
+	enc := d.CreateCommandEncoder()
+	enc.ResolveQuerySet(qs, 1, 2, dst, 0)
+	qs.(*QuerySet).Results = []uint64{1, 0x0102, 3, 4}
+	d.Queue().Submit(enc.Finish())
+
+	var got []byte
+	dst.MapRead(0, 16, func(data []byte, err error) { got = data })
+	want := []byte{2, 1, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("contents after Submit diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestDeferMapReads(t *testing.T) {
+	d := NewDevice()
+	d.DeferMapReads = true
+	b := d.CreateBuffer(gpu.BufferDescriptor{Size: 4, Usage: gpu.BufferUsageMapRead, Contents: []byte{1, 2, 3, 4}})
+
+	var got []byte
+	b.MapRead(0, 4, func(data []byte, err error) { got = data })
+	if got != nil {
+		t.Fatalf("MapRead called callback before CompleteMapReads")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("MapRead while mapping didn't panic")
+			}
+		}()
+		b.MapRead(0, 4, func([]byte, error) {})
+	}()
+
+	d.CompleteMapReads()
+	if diff := cmp.Diff([]byte{1, 2, 3, 4}, got); diff != "" {
+		t.Errorf("contents diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestBufferPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(d *Device, b gpu.Buffer)
+	}{
+		{
+			name: "write out of range",
+			fn:   func(d *Device, b gpu.Buffer) { d.Queue().WriteBuffer(b, 6, []byte{1, 2, 3}) },
+		},
+		{
+			name: "map without usage",
+			fn:   func(d *Device, b gpu.Buffer) { b.MapRead(0, 8, func([]byte, error) {}) },
+		},
+		{
+			name: "write after destroy",
+			fn: func(d *Device, b gpu.Buffer) {
+				b.Destroy()
+				d.Queue().WriteBuffer(b, 0, []byte{1})
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDevice()
+			b := d.CreateBuffer(gpu.BufferDescriptor{Size: 8, Usage: gpu.BufferUsageStorage})
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s didn't panic", tc.name)
+				}
+			}()
+			tc.fn(d, b)
+		})
+	}
+}
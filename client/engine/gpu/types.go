@@ -0,0 +1,271 @@
+package gpu
+
+// BufferUsage is a set of flags describing how a buffer can be used.
+type BufferUsage uint32
+
+const (
+	BufferUsageMapRead      BufferUsage = 0x0001
+	BufferUsageMapWrite     BufferUsage = 0x0002
+	BufferUsageCopySrc      BufferUsage = 0x0004
+	BufferUsageCopyDst      BufferUsage = 0x0008
+	BufferUsageIndex        BufferUsage = 0x0010
+	BufferUsageVertex       BufferUsage = 0x0020
+	BufferUsageUniform      BufferUsage = 0x0040
+	BufferUsageStorage      BufferUsage = 0x0080
+	BufferUsageIndirect     BufferUsage = 0x0100
+	BufferUsageQueryResolve BufferUsage = 0x0200
+)
+
+// FeatureName is an optional capability a device may have been created with.
+type FeatureName string
+
+const (
+	FeatureTimestampQuery FeatureName = "timestamp-query"
+)
+
+// QueryType is the type of the queries in a query set.
+type QueryType string
+
+const (
+	QueryTypeTimestamp QueryType = "timestamp"
+)
+
+// ShaderStage is a set of flags describing the shader stages a binding is visible to.
+type ShaderStage uint32
+
+const (
+	ShaderStageVertex   ShaderStage = 0x1
+	ShaderStageFragment ShaderStage = 0x2
+	ShaderStageCompute  ShaderStage = 0x4
+)
+
+// BufferBindingType describes how a buffer is bound.
+type BufferBindingType string
+
+const (
+	BufferBindingTypeUniform         BufferBindingType = "uniform"
+	BufferBindingTypeStorage         BufferBindingType = "storage"
+	BufferBindingTypeReadOnlyStorage BufferBindingType = "read-only-storage"
+)
+
+// VertexFormat is the format of a vertex attribute.
+type VertexFormat string
+
+const (
+	VertexFormatFloat32   VertexFormat = "float32"
+	VertexFormatFloat32x2 VertexFormat = "float32x2"
+	VertexFormatFloat32x3 VertexFormat = "float32x3"
+	VertexFormatFloat32x4 VertexFormat = "float32x4"
+	VertexFormatFloat16x2 VertexFormat = "float16x2"
+	VertexFormatFloat16x4 VertexFormat = "float16x4"
+	VertexFormatSint32    VertexFormat = "sint32"
+	VertexFormatSint32x2  VertexFormat = "sint32x2"
+	VertexFormatSint32x3  VertexFormat = "sint32x3"
+	VertexFormatSint32x4  VertexFormat = "sint32x4"
+	VertexFormatUint32    VertexFormat = "uint32"
+	VertexFormatUint32x2  VertexFormat = "uint32x2"
+	VertexFormatUint32x3  VertexFormat = "uint32x3"
+	VertexFormatUint32x4  VertexFormat = "uint32x4"
+	VertexFormatUnorm8x4  VertexFormat = "unorm8x4"
+	VertexFormatSnorm8x4  VertexFormat = "snorm8x4"
+	VertexFormatUnorm16x2 VertexFormat = "unorm16x2"
+	VertexFormatSnorm16x2 VertexFormat = "snorm16x2"
+)
+
+// VertexStepMode describes whether a vertex buffer is indexed by vertex or instance.
+type VertexStepMode string
+
+const (
+	VertexStepModeVertex   VertexStepMode = "vertex"
+	VertexStepModeInstance VertexStepMode = "instance"
+)
+
+// TextureFormat is the format of a texture.
+type TextureFormat string
+
+const (
+	TextureFormatBGRA8Unorm TextureFormat = "bgra8unorm"
+	TextureFormatRGBA8Unorm TextureFormat = "rgba8unorm"
+)
+
+// PrimitiveTopology describes how vertices are assembled into primitives.
+type PrimitiveTopology string
+
+const (
+	PrimitiveTopologyPointList     PrimitiveTopology = "point-list"
+	PrimitiveTopologyLineList      PrimitiveTopology = "line-list"
+	PrimitiveTopologyLineStrip     PrimitiveTopology = "line-strip"
+	PrimitiveTopologyTriangleList  PrimitiveTopology = "triangle-list"
+	PrimitiveTopologyTriangleStrip PrimitiveTopology = "triangle-strip"
+)
+
+// LoadOp is the operation performed on an attachment at the start of a render pass.
+type LoadOp string
+
+const (
+	LoadOpLoad  LoadOp = "load"
+	LoadOpClear LoadOp = "clear"
+)
+
+// StoreOp is the operation performed on an attachment at the end of a render pass.
+type StoreOp string
+
+const (
+	StoreOpStore   StoreOp = "store"
+	StoreOpDiscard StoreOp = "discard"
+)
+
+// Color is an RGBA color.
+type Color struct {
+	R, G, B, A float64
+}
+
+type BufferDescriptor struct {
+	Label string
+	Size  uint64
+	Usage BufferUsage
+	// Contents, if not nil, is copied to the start of the buffer when it's created.
+	Contents []byte
+}
+
+type ShaderModuleDescriptor struct {
+	Label string
+	Code  string
+}
+
+// CompilationMessage is a message reported when compiling a shader module.
+type CompilationMessage struct {
+	// Type is "error", "warning" or "info".
+	Type    string
+	Message string
+	// LineNum is the 1-based line in the shader's code the message refers to, or 0 if it doesn't refer to a line.
+	LineNum int
+	// LinePos is the 1-based position in the line, or 0 if it doesn't refer to a line.
+	LinePos int
+}
+
+type BufferBindingLayout struct {
+	Type BufferBindingType
+}
+
+type BindGroupLayoutEntry struct {
+	Binding    int
+	Visibility ShaderStage
+	Buffer     BufferBindingLayout
+}
+
+type BindGroupLayoutDescriptor struct {
+	Label   string
+	Entries []BindGroupLayoutEntry
+}
+
+// BindGroupEntry binds a range of a buffer.
+type BindGroupEntry struct {
+	Binding int
+	Buffer  Buffer
+	Offset  uint64
+	// Size is the size of the range, or 0 for the rest of the buffer.
+	Size uint64
+}
+
+type BindGroupDescriptor struct {
+	Label   string
+	Layout  BindGroupLayout
+	Entries []BindGroupEntry
+}
+
+type PipelineLayoutDescriptor struct {
+	Label            string
+	BindGroupLayouts []BindGroupLayout
+}
+
+// ProgrammableStage is a shader entry point.
+type ProgrammableStage struct {
+	Module     ShaderModule
+	EntryPoint string
+	// Constants holds values for override declarations, keyed by pipeline constant identifier.
+	Constants map[string]float64
+}
+
+type ComputePipelineDescriptor struct {
+	Label string
+	// Layout is the layout of the pipeline, or nil for a layout derived from the shader.
+	Layout  PipelineLayout
+	Compute ProgrammableStage
+}
+
+type VertexAttribute struct {
+	Format         VertexFormat
+	Offset         uint64
+	ShaderLocation int
+}
+
+type VertexBufferLayout struct {
+	ArrayStride uint64
+	// StepMode defaults to VertexStepModeVertex.
+	StepMode   VertexStepMode
+	Attributes []VertexAttribute
+}
+
+type VertexState struct {
+	ProgrammableStage
+	Buffers []VertexBufferLayout
+}
+
+type ColorTargetState struct {
+	Format TextureFormat
+}
+
+type FragmentState struct {
+	ProgrammableStage
+	Targets []ColorTargetState
+}
+
+type PrimitiveState struct {
+	// Topology defaults to PrimitiveTopologyTriangleList.
+	Topology PrimitiveTopology
+}
+
+type RenderPipelineDescriptor struct {
+	Label string
+	// Layout is the layout of the pipeline, or nil for a layout derived from the shaders.
+	Layout    PipelineLayout
+	Vertex    VertexState
+	Primitive PrimitiveState
+	// Fragment is the fragment stage, or nil if the pipeline doesn't have one.
+	Fragment *FragmentState
+}
+
+type QuerySetDescriptor struct {
+	Type  QueryType
+	Count int
+}
+
+// PassTimestampWrites are the queries a pass writes the time to when it begins and ends.
+type PassTimestampWrites struct {
+	QuerySet                  QuerySet
+	BeginningOfPassWriteIndex int
+	EndOfPassWriteIndex       int
+}
+
+type ComputePassDescriptor struct {
+	Label string
+	// TimestampWrites, if not nil, are the queries the pass writes timestamps to.
+	// The device must have FeatureTimestampQuery.
+	TimestampWrites *PassTimestampWrites
+}
+
+type RenderPassColorAttachment struct {
+	View       TextureView
+	ClearValue Color
+	LoadOp     LoadOp
+	StoreOp    StoreOp
+}
+
+type RenderPassDescriptor struct {
+	Label            string
+	ColorAttachments []RenderPassColorAttachment
+	// TimestampWrites, if not nil, are the queries the pass writes timestamps to.
+	// The device must have FeatureTimestampQuery.
+	TimestampWrites *PassTimestampWrites
+}
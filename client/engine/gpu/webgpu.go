@@ -0,0 +1,464 @@
+//go:build js && wasm
+
+package gpu
+
+import (
+	"syscall/js"
+
+	"github.com/mokiat/gog/opt"
+	"github.com/mokiat/wasmgpu"
+)
+
+// Descriptor fields and methods which upstream wasmgpu doesn't have are used through the JavaScript objects behind
+// its values (see ToJS), so objects created with them are held as js.Values.
+
+var uint8ArrayCtor = js.Global().Get("Uint8Array")
+
+// gpuMapModeRead is GPUMapMode.READ.
+const gpuMapModeRead = 0x0001
+
+// NewDevice returns a Device which uses the browser's WebGPU implementation.
+func NewDevice(device wasmgpu.GPUDevice) Device {
+	return webDevice{device}
+}
+
+// NewCanvasContext returns a CanvasContext which renders to a canvas in the page.
+// The canvas must have been configured with the browser's preferred format.
+func NewCanvasContext(context wasmgpu.GPUCanvasContext) CanvasContext {
+	format := js.Global().Get("navigator").Get("gpu").Call("getPreferredCanvasFormat").String()
+	return webCanvasContext{context: context, format: TextureFormat(format)}
+}
+
+type webDevice struct {
+	device wasmgpu.GPUDevice
+}
+
+func (d webDevice) Queue() Queue {
+	return webQueue{d.device.Queue()}
+}
+
+func (d webDevice) CreateBuffer(desc BufferDescriptor) Buffer {
+	jsDesc := js.ValueOf(map[string]any{
+		"size":             desc.Size,
+		"usage":            int(desc.Usage),
+		"mappedAtCreation": desc.Contents != nil,
+	})
+	setLabel(jsDesc, desc.Label)
+	buffer := d.device.ToJS().(js.Value).Call("createBuffer", jsDesc)
+	if desc.Contents != nil {
+		js.CopyBytesToJS(uint8ArrayCtor.New(buffer.Call("getMappedRange")), desc.Contents)
+		buffer.Call("unmap")
+	}
+	return webBuffer{buffer: buffer, size: desc.Size}
+}
+
+func (d webDevice) CreateShaderModule(desc ShaderModuleDescriptor) ShaderModule {
+	module := d.device.CreateShaderModule(wasmgpu.GPUShaderModuleDescriptor{
+		Code: desc.Code,
+	})
+	setLabel(module.ToJS().(js.Value), desc.Label)
+	return webShaderModule{module}
+}
+
+func (d webDevice) CreateBindGroupLayout(desc BindGroupLayoutDescriptor) BindGroupLayout {
+	entries := make([]wasmgpu.GPUBindGroupLayoutEntry, len(desc.Entries))
+	for i, e := range desc.Entries {
+		entries[i] = wasmgpu.GPUBindGroupLayoutEntry{
+			Binding:    wasmgpu.GPUIndex32(e.Binding),
+			Visibility: wasmgpu.GPUShaderStageFlags(e.Visibility),
+			Buffer: opt.V(wasmgpu.GPUBufferBindingLayout{
+				Type: opt.V(wasmgpu.GPUBufferBindingType(e.Buffer.Type)),
+			}),
+		}
+	}
+	layout := d.device.CreateBindGroupLayout(wasmgpu.GPUBindGroupLayoutDescriptor{
+		Entries: entries,
+	})
+	setLabel(layout.ToJS().(js.Value), desc.Label)
+	return layout
+}
+
+func (d webDevice) CreateBindGroup(desc BindGroupDescriptor) BindGroup {
+	entries := make([]any, len(desc.Entries))
+	for i, e := range desc.Entries {
+		binding := map[string]any{"buffer": e.Buffer.(webBuffer).buffer}
+		if e.Offset != 0 {
+			binding["offset"] = e.Offset
+		}
+		if e.Size != 0 {
+			binding["size"] = e.Size
+		}
+		entries[i] = map[string]any{
+			"binding":  e.Binding,
+			"resource": binding,
+		}
+	}
+	jsDesc := js.ValueOf(map[string]any{
+		"layout":  jsObject(desc.Layout),
+		"entries": entries,
+	})
+	setLabel(jsDesc, desc.Label)
+	return d.device.ToJS().(js.Value).Call("createBindGroup", jsDesc)
+}
+
+func (d webDevice) CreatePipelineLayout(desc PipelineLayoutDescriptor) PipelineLayout {
+	layouts := make([]any, len(desc.BindGroupLayouts))
+	for i, l := range desc.BindGroupLayouts {
+		layouts[i] = jsObject(l)
+	}
+	jsDesc := js.ValueOf(map[string]any{"bindGroupLayouts": layouts})
+	setLabel(jsDesc, desc.Label)
+	return d.device.ToJS().(js.Value).Call("createPipelineLayout", jsDesc)
+}
+
+func (d webDevice) CreateComputePipeline(desc ComputePipelineDescriptor) ComputePipeline {
+	jsDesc := js.ValueOf(wasmgpu.GPUComputePipelineDescriptor{
+		Compute: wasmgpu.GPUProgrammableStage{
+			Module:     desc.Compute.Module.(webShaderModule).module,
+			EntryPoint: desc.Compute.EntryPoint,
+		},
+	}.ToJS())
+	setPipelineLayout(jsDesc, desc.Layout)
+	setConstants(jsDesc.Get("compute"), desc.Compute.Constants)
+	setLabel(jsDesc, desc.Label)
+	return webComputePipeline{d.device.ToJS().(js.Value).Call("createComputePipeline", jsDesc)}
+}
+
+func (d webDevice) CreateRenderPipeline(desc RenderPipelineDescriptor) RenderPipeline {
+	buffers := make([]wasmgpu.GPUVertexBufferLayout, len(desc.Vertex.Buffers))
+	for i, b := range desc.Vertex.Buffers {
+		attributes := make([]wasmgpu.GPUVertexAttribute, len(b.Attributes))
+		for j, a := range b.Attributes {
+			attributes[j] = wasmgpu.GPUVertexAttribute{
+				Format:         wasmgpu.GPUVertexFormat(a.Format),
+				Offset:         wasmgpu.GPUSize64(a.Offset),
+				ShaderLocation: wasmgpu.GPUIndex32(a.ShaderLocation),
+			}
+		}
+		buffers[i] = wasmgpu.GPUVertexBufferLayout{
+			ArrayStride: wasmgpu.GPUSize64(b.ArrayStride),
+			Attributes:  attributes,
+		}
+		if b.StepMode != "" {
+			buffers[i].StepMode = opt.V(wasmgpu.GPUVertexStepMode(b.StepMode))
+		}
+	}
+	rpd := wasmgpu.GPURenderPipelineDescriptor{
+		Vertex: wasmgpu.GPUVertexState{
+			Module:     desc.Vertex.Module.(webShaderModule).module,
+			EntryPoint: desc.Vertex.EntryPoint,
+			Buffers:    buffers,
+		},
+	}
+	if desc.Primitive.Topology != "" {
+		rpd.Primitive = opt.V(wasmgpu.GPUPrimitiveState{
+			Topology: opt.V(wasmgpu.GPUPrimitiveTopology(desc.Primitive.Topology)),
+		})
+	}
+	if f := desc.Fragment; f != nil {
+		targets := make([]wasmgpu.GPUColorTargetState, len(f.Targets))
+		for i, t := range f.Targets {
+			targets[i] = wasmgpu.GPUColorTargetState{Format: wasmgpu.GPUTextureFormat(t.Format)}
+		}
+		rpd.Fragment = opt.V(wasmgpu.GPUFragmentState{
+			Module:     f.Module.(webShaderModule).module,
+			EntryPoint: f.EntryPoint,
+			Targets:    targets,
+		})
+	}
+	jsDesc := js.ValueOf(rpd.ToJS())
+	setPipelineLayout(jsDesc, desc.Layout)
+	setConstants(jsDesc.Get("vertex"), desc.Vertex.Constants)
+	if f := desc.Fragment; f != nil {
+		setConstants(jsDesc.Get("fragment"), f.Constants)
+	}
+	setLabel(jsDesc, desc.Label)
+	return webRenderPipeline{d.device.ToJS().(js.Value).Call("createRenderPipeline", jsDesc)}
+}
+
+func (d webDevice) CreateCommandEncoder() CommandEncoder {
+	return webCommandEncoder{d.device.CreateCommandEncoder()}
+}
+
+func (d webDevice) CreateQuerySet(desc QuerySetDescriptor) QuerySet {
+	return webQuerySet{d.device.ToJS().(js.Value).Call("createQuerySet", map[string]any{
+		"type":  string(desc.Type),
+		"count": desc.Count,
+	})}
+}
+
+func (d webDevice) HasFeature(feature FeatureName) bool {
+	return d.device.ToJS().(js.Value).Get("features").Call("has", string(feature)).Bool()
+}
+
+// jsObject returns the JavaScript object behind v, which is either a wasmgpu value or a js.Value.
+func jsObject(v any) js.Value {
+	if w, ok := v.(interface{ ToJS() any }); ok {
+		return w.ToJS().(js.Value)
+	}
+	return v.(js.Value)
+}
+
+// setLabel sets the label of an object or descriptor, unless label is empty.
+func setLabel(obj js.Value, label string) {
+	if label != "" {
+		obj.Set("label", label)
+	}
+}
+
+// setPipelineLayout sets the layout in a pipeline descriptor, leaving it as "auto" if layout is nil.
+func setPipelineLayout(desc js.Value, layout PipelineLayout) {
+	if layout != nil {
+		desc.Set("layout", jsObject(layout))
+	}
+}
+
+// setConstants sets the pipeline-overridable constants of a programmable stage descriptor.
+func setConstants(stage js.Value, constants map[string]float64) {
+	if len(constants) == 0 {
+		return
+	}
+	jsConstants := make(map[string]any, len(constants))
+	for k, v := range constants {
+		jsConstants[k] = v
+	}
+	stage.Set("constants", jsConstants)
+}
+
+type webQueue struct {
+	queue wasmgpu.GPUQueue
+}
+
+func (q webQueue) WriteBuffer(buffer Buffer, offset uint64, data []byte) {
+	jsData := uint8ArrayCtor.New(len(data))
+	js.CopyBytesToJS(jsData, data)
+	q.queue.ToJS().(js.Value).Call("writeBuffer", buffer.(webBuffer).buffer, offset, jsData)
+}
+
+func (q webQueue) Submit(commandBuffers ...CommandBuffer) {
+	cbs := make([]wasmgpu.GPUCommandBuffer, len(commandBuffers))
+	for i, cb := range commandBuffers {
+		cbs[i] = cb.(wasmgpu.GPUCommandBuffer)
+	}
+	q.queue.Submit(cbs)
+}
+
+type webBuffer struct {
+	buffer js.Value
+	size   uint64
+}
+
+func (b webBuffer) Size() uint64 {
+	return b.size
+}
+
+func (b webBuffer) MapRead(offset, size uint64, callback func(data []byte, err error)) {
+	promise := b.buffer.Call("mapAsync", gpuMapModeRead, offset, size)
+	await(promise, func(js.Value) {
+		ab := b.buffer.Call("getMappedRange", offset, size)
+		abCopy := ab.Call("slice")
+		b.buffer.Call("unmap")
+
+		data := make([]byte, size)
+		n := js.CopyBytesToGo(data, uint8ArrayCtor.New(abCopy))
+		callback(data[:n], nil)
+	}, func(err error) {
+		callback(nil, err)
+	})
+}
+
+func (b webBuffer) Destroy() {
+	b.buffer.Call("destroy")
+}
+
+type webQuerySet struct {
+	querySet js.Value
+}
+
+func (q webQuerySet) Destroy() {
+	q.querySet.Call("destroy")
+}
+
+type webShaderModule struct {
+	module wasmgpu.GPUShaderModule
+}
+
+func (m webShaderModule) GetCompilationInfo(callback func(messages []CompilationMessage, err error)) {
+	promise := m.module.ToJS().(js.Value).Call("getCompilationInfo")
+	await(promise, func(info js.Value) {
+		jsMessages := info.Get("messages")
+		messages := make([]CompilationMessage, jsMessages.Length())
+		for i := range messages {
+			jsMessage := jsMessages.Index(i)
+			messages[i] = CompilationMessage{
+				Type:    jsMessage.Get("type").String(),
+				Message: jsMessage.Get("message").String(),
+				LineNum: jsMessage.Get("lineNum").Int(),
+				LinePos: jsMessage.Get("linePos").Int(),
+			}
+		}
+		callback(messages, nil)
+	}, func(err error) {
+		callback(nil, err)
+	})
+}
+
+// await calls onFulfilled with the value promise resolves to, or onRejected with the reason it's rejected.
+// The functions passed to JavaScript are released once either has been called.
+func await(promise js.Value, onFulfilled func(value js.Value), onRejected func(err error)) {
+	var then, catch js.Func
+	release := func() {
+		then.Release()
+		catch.Release()
+	}
+	then = js.FuncOf(func(this js.Value, args []js.Value) any {
+		defer release()
+		onFulfilled(args[0])
+		return nil
+	})
+	catch = js.FuncOf(func(this js.Value, args []js.Value) any {
+		defer release()
+		onRejected(js.Error{Value: args[0]})
+		return nil
+	})
+	promise.Call("then", then, catch)
+}
+
+type webComputePipeline struct {
+	pipeline js.Value
+}
+
+func (p webComputePipeline) GetBindGroupLayout(index int) BindGroupLayout {
+	return p.pipeline.Call("getBindGroupLayout", index)
+}
+
+type webRenderPipeline struct {
+	pipeline js.Value
+}
+
+func (p webRenderPipeline) GetBindGroupLayout(index int) BindGroupLayout {
+	return p.pipeline.Call("getBindGroupLayout", index)
+}
+
+type webCommandEncoder struct {
+	encoder wasmgpu.GPUCommandEncoder
+}
+
+func (e webCommandEncoder) BeginComputePass(desc ComputePassDescriptor) ComputePassEncoder {
+	jsDesc := js.ValueOf(wasmgpu.GPUComputePassDescriptor{}.ToJS())
+	setTimestampWrites(jsDesc, desc.TimestampWrites)
+	setLabel(jsDesc, desc.Label)
+	return webComputePassEncoder{e.encoder.ToJS().(js.Value).Call("beginComputePass", jsDesc)}
+}
+
+func (e webCommandEncoder) BeginRenderPass(desc RenderPassDescriptor) RenderPassEncoder {
+	attachments := make([]wasmgpu.GPURenderPassColorAttachment, len(desc.ColorAttachments))
+	for i, a := range desc.ColorAttachments {
+		attachments[i] = wasmgpu.GPURenderPassColorAttachment{
+			View:       a.View.(wasmgpu.GPUTextureView),
+			ClearValue: opt.V(wasmgpu.GPUColor{R: a.ClearValue.R, G: a.ClearValue.G, B: a.ClearValue.B, A: a.ClearValue.A}),
+			LoadOp:     wasmgpu.GPULoadOp(a.LoadOp),
+			StoreOp:    wasmgpu.GPUStoreOp(a.StoreOp),
+		}
+	}
+	rpd := wasmgpu.GPURenderPassDescriptor{
+		ColorAttachments: attachments,
+	}
+	jsDesc := js.ValueOf(rpd.ToJS())
+	setTimestampWrites(jsDesc, desc.TimestampWrites)
+	setLabel(jsDesc, desc.Label)
+	return webRenderPassEncoder{e.encoder.ToJS().(js.Value).Call("beginRenderPass", jsDesc)}
+}
+
+// setTimestampWrites sets the timestamp writes of a pass descriptor, unless tw is nil.
+func setTimestampWrites(desc js.Value, tw *PassTimestampWrites) {
+	if tw == nil {
+		return
+	}
+	desc.Set("timestampWrites", map[string]any{
+		"querySet":                  tw.QuerySet.(webQuerySet).querySet,
+		"beginningOfPassWriteIndex": tw.BeginningOfPassWriteIndex,
+		"endOfPassWriteIndex":       tw.EndOfPassWriteIndex,
+	})
+}
+
+func (e webCommandEncoder) CopyBufferToBuffer(source Buffer, sourceOffset uint64, destination Buffer, destinationOffset uint64, size uint64) {
+	e.encoder.ToJS().(js.Value).Call("copyBufferToBuffer", source.(webBuffer).buffer, sourceOffset, destination.(webBuffer).buffer, destinationOffset, size)
+}
+
+func (e webCommandEncoder) ClearBuffer(buffer Buffer, offset, size uint64) {
+	e.encoder.ToJS().(js.Value).Call("clearBuffer", buffer.(webBuffer).buffer, offset, size)
+}
+
+func (e webCommandEncoder) ResolveQuerySet(querySet QuerySet, firstQuery, queryCount int, destination Buffer, destinationOffset uint64) {
+	e.encoder.ToJS().(js.Value).Call("resolveQuerySet", querySet.(webQuerySet).querySet, firstQuery, queryCount, destination.(webBuffer).buffer, destinationOffset)
+}
+
+func (e webCommandEncoder) Finish() CommandBuffer {
+	return e.encoder.Finish()
+}
+
+type webComputePassEncoder struct {
+	encoder js.Value
+}
+
+func (e webComputePassEncoder) SetPipeline(pipeline ComputePipeline) {
+	e.encoder.Call("setPipeline", pipeline.(webComputePipeline).pipeline)
+}
+
+func (e webComputePassEncoder) SetBindGroup(index int, bindGroup BindGroup) {
+	e.encoder.Call("setBindGroup", index, bindGroup.(js.Value))
+}
+
+func (e webComputePassEncoder) DispatchWorkgroups(x, y, z uint32) {
+	e.encoder.Call("dispatchWorkgroups", x, y, z)
+}
+
+func (e webComputePassEncoder) DispatchWorkgroupsIndirect(indirectBuffer Buffer, indirectOffset uint64) {
+	e.encoder.Call("dispatchWorkgroupsIndirect", indirectBuffer.(webBuffer).buffer, indirectOffset)
+}
+
+func (e webComputePassEncoder) End() {
+	e.encoder.Call("end")
+}
+
+type webRenderPassEncoder struct {
+	encoder js.Value
+}
+
+func (e webRenderPassEncoder) SetPipeline(pipeline RenderPipeline) {
+	e.encoder.Call("setPipeline", pipeline.(webRenderPipeline).pipeline)
+}
+
+func (e webRenderPassEncoder) SetBindGroup(index int, bindGroup BindGroup) {
+	e.encoder.Call("setBindGroup", index, bindGroup.(js.Value))
+}
+
+func (e webRenderPassEncoder) SetVertexBuffer(slot int, buffer Buffer) {
+	e.encoder.Call("setVertexBuffer", slot, buffer.(webBuffer).buffer)
+}
+
+func (e webRenderPassEncoder) Draw(vertexCount, instanceCount, firstVertex, firstInstance uint32) {
+	e.encoder.Call("draw", vertexCount, instanceCount, firstVertex, firstInstance)
+}
+
+func (e webRenderPassEncoder) DrawIndirect(indirectBuffer Buffer, indirectOffset uint64) {
+	e.encoder.Call("drawIndirect", indirectBuffer.(webBuffer).buffer, indirectOffset)
+}
+
+func (e webRenderPassEncoder) End() {
+	e.encoder.Call("end")
+}
+
+type webCanvasContext struct {
+	context wasmgpu.GPUCanvasContext
+	format  TextureFormat
+}
+
+func (c webCanvasContext) CurrentTextureView() TextureView {
+	return c.context.GetCurrentTexture().CreateView()
+}
+
+func (c webCanvasContext) Format() TextureFormat {
+	return c.format
+}
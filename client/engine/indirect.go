@@ -0,0 +1,160 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+// DispatchArgs are the workgroup counts of an indirect dispatch.
+type DispatchArgs struct {
+	X, Y, Z uint32
+}
+
+// DrawArgs are the arguments of an indirect draw.
+type DrawArgs struct {
+	VertexCount   uint32
+	InstanceCount uint32
+	FirstVertex   uint32
+	FirstInstance uint32
+}
+
+// An IndirectDispatchBuffer holds DispatchArgs for ComputePassFactory.InitIndirectPass.
+// It can be bound to shaders so they can set the arguments.
+type IndirectDispatchBuffer struct {
+	GPUBuffer[DispatchArgs]
+}
+
+// An IndirectDrawBuffer holds DrawArgs for a Draw.
+// It can be bound to shaders so they can set the arguments.
+type IndirectDrawBuffer struct {
+	GPUBuffer[DrawArgs]
+}
+
+func InitIndirectDispatchBuffer(device gpu.Device, args DispatchArgs, opts ...BufferOption) IndirectDispatchBuffer {
+	opts = append([]BufferOption{WithIndirectUsage()}, opts...)
+	return IndirectDispatchBuffer{GPUBuffer: InitStorageBufferStruct(device, args, opts...)}
+}
+
+func InitIndirectDrawBuffer(device gpu.Device, args DrawArgs, opts ...BufferOption) IndirectDrawBuffer {
+	opts = append([]BufferOption{WithIndirectUsage()}, opts...)
+	return IndirectDrawBuffer{GPUBuffer: InitStorageBufferStruct(device, args, opts...)}
+}
+
+// A Counter is a u32 field of a buffer which counts items written by the GPU, such as the atomic count of
+// a container struct. The counted items are clamped to the capacity of the container.
+type Counter struct {
+	buffer   gpu.Buffer
+	offset   int
+	capacity int
+}
+
+// NewCounter returns the counter held in the field of buf's struct, which has room for capacity items.
+// Panics if T isn't a struct, or the field isn't a u32 or atomic<u32>.
+func NewCounter[T any](buf GPUBuffer[T], field string, capacity int) Counter {
+	if len(buf.structDefs) == 0 {
+		var t T
+		panic(fmt.Sprintf("NewCounter: %T isn't a struct", t))
+	}
+	s := buf.structDefs[0]
+	f, ok := s.FieldMap[field]
+	if !ok {
+		panic(fmt.Sprintf("NewCounter: %s has no field %q", s.Name, field))
+	}
+	t := f.WGSLType
+	if t.Kind == wgsltypes.KindAtomic {
+		t = *t.Elem
+	}
+	if t.Name != "u32" {
+		panic(fmt.Sprintf("NewCounter: %s.%s has type %s, want u32 or atomic<u32>", s.Name, field, f.WGSLType.Name))
+	}
+	return Counter{buffer: buf.Buffer(), offset: int(f.Offset), capacity: capacity}
+}
+
+func (c Counter) StructDefs() []wgsltypes.Struct {
+	return nil
+}
+
+// WGSLType returns array<u32>: counters are bound as an array so the count can be read from any offset.
+func (c Counter) WGSLType() wgsltypes.TypeName {
+	return "array<u32>"
+}
+
+func (c Counter) BindingType() gpu.BufferBindingType {
+	return gpu.BufferBindingTypeReadOnlyStorage
+}
+
+func (c Counter) MakeBindGroupLayoutEntry(idx int) gpu.BindGroupLayoutEntry {
+	return gpu.BindGroupLayoutEntry{
+		Binding:    idx,
+		Visibility: gpu.ShaderStageCompute,
+		Buffer: gpu.BufferBindingLayout{
+			Type: c.BindingType(),
+		},
+	}
+}
+
+func (c Counter) MakeBindingGroupEntry(idx int) gpu.BindGroupEntry {
+	return gpu.BindGroupEntry{
+		Binding: idx,
+		Buffer:  c.buffer,
+	}
+}
+
+// overrides returns the overrides which tell the counter shaders where to find the count.
+func (c Counter) overrides() wgsl.Overrides {
+	return wgsl.Overrides{
+		"counterIndex": float64(c.offset / 4),
+		"capacity":     float64(c.capacity),
+	}
+}
+
+const counterArgsShaderPrologue = `@group(0) @binding(0) var<storage, read> counter : array<u32>;
+
+override counterIndex : u32;
+override capacity : u32;
+
+fn count() -> u32 {
+  return min(counter[counterIndex], capacity);
+}
+`
+
+const dispatchArgsShader = counterArgsShaderPrologue + `
+@group(0) @binding(1) var<storage, read_write> args : DispatchArgs;
+
+override workgroupSize : u32;
+
+@compute @workgroup_size(1)
+fn dispatchArgs() {
+  args = DispatchArgs((count() + workgroupSize - 1) / workgroupSize, 1, 1);
+}
+`
+
+const drawArgsShader = counterArgsShaderPrologue + `
+@group(0) @binding(1) var<storage, read_write> args : DrawArgs;
+
+@compute @workgroup_size(1)
+fn drawArgs() {
+  args.InstanceCount = count();
+}
+`
+
+// InitDispatchArgsPass returns a pass which sets args to dispatch enough workgroups to run an entry point with
+// an x @workgroup_size of workgroupSize once for each item counted by counter.
+// It must run after the passes which update the counter, and before the indirect dispatch.
+func InitDispatchArgsPass(device gpu.Device, counter Counter, workgroupSize int, args IndirectDispatchBuffer) ComputePass {
+	cpf := NewComputePassFactory(device, dispatchArgsShader, []ComputePassBuffer{counter, args}, WithSourceName("dispatch_args.wgsl"))
+	overrides := counter.overrides()
+	overrides["workgroupSize"] = float64(workgroupSize)
+	return cpf.InitPass("dispatchArgs", overrides, 1)
+}
+
+// InitDrawArgsPass returns a pass which sets the InstanceCount of args to the number of items counted by counter,
+// leaving the other arguments unchanged.
+// It must run after the passes which update the counter, and before the indirect draw.
+func InitDrawArgsPass(device gpu.Device, counter Counter, args IndirectDrawBuffer) ComputePass {
+	cpf := NewComputePassFactory(device, drawArgsShader, []ComputePassBuffer{counter, args}, WithSourceName("draw_args.wgsl"))
+	return cpf.InitPass("drawArgs", counter.overrides(), 1)
+}
@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpucpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+)
+
+type testContainer struct {
+	flags    uint32
+	count    uint32    `atomic:"true"`
+	elements [8]uint32 `runtimeArray:"true"`
+}
+
+const testCountedShader = `@group(0) @binding(0) var<storage, read_write> gContainer : testContainer;
+
+override workgroupSize : u32 = 64;
+
+@compute @workgroup_size(workgroupSize)
+fn main(@builtin(global_invocation_id) id : vec3<u32>) {
+  if (id.x < atomicLoad(&gContainer.count)) {
+    gContainer.elements[id.x] += 1;
+  }
+}
+`
+
+func TestInitCountedPass(t *testing.T) {
+	device := gpufake.NewDevice()
+	container := InitStorageBufferStruct(device, testContainer{})
+	cpf, err := NewNamedComputePassFactory(device, testCountedShader, map[string]ComputePassBuffer{
+		"gContainer": container,
+	})
+	if err != nil {
+		t.Fatalf("NewNamedComputePassFactory() = %v", err)
+	}
+	pass := cpf.InitCountedPass("main", wgsl.Overrides{"workgroupSize": 16}, NewCounter(container, "count", 8))
+
+	encoder := device.CreateCommandEncoder()
+	pass(encoder)
+	device.Queue().Submit(encoder.Finish())
+
+	dispatches := device.Dispatches()
+	if len(dispatches) != 2 {
+		t.Fatalf("got %d dispatches, want 2", len(dispatches))
+	}
+	argsDispatch, dispatch := dispatches[0], dispatches[1]
+
+	// The first dispatch converts the count into the arguments of the second.
+	stage := argsDispatch.Pipeline.Desc.Compute
+	if stage.EntryPoint != "dispatchArgs" {
+		t.Errorf("first dispatch EntryPoint = %q, want %q", stage.EntryPoint, "dispatchArgs")
+	}
+	wantConsts := map[string]float64{"counterIndex": 1, "capacity": 8, "workgroupSize": 16}
+	if diff := cmp.Diff(wantConsts, stage.Constants); diff != "" {
+		t.Errorf("first dispatch Constants diff (-want +got):\n%s", diff)
+	}
+	if got := argsDispatch.BindGroups[0].BufferAt(0); got != container.Buffer() {
+		t.Errorf("first dispatch doesn't read the counter's buffer")
+	}
+	args := argsDispatch.BindGroups[0].BufferAt(1)
+	if args.Desc.Usage&gpu.BufferUsageIndirect == 0 {
+		t.Errorf("arguments buffer usage = %#x, want BufferUsageIndirect", args.Desc.Usage)
+	}
+
+	if got := dispatch.Pipeline.Desc.Compute.EntryPoint; got != "main" {
+		t.Errorf("second dispatch EntryPoint = %q, want %q", got, "main")
+	}
+	if dispatch.IndirectBuffer != args || dispatch.IndirectOffset != 0 {
+		t.Errorf("second dispatch isn't made indirectly with the arguments written by the first")
+	}
+}
+
+func TestInitCountedPassOnCPU(t *testing.T) {
+	kernels := gpucpu.Kernels{
+		"main": func(inv gpucpu.Invocation) {
+			container := gpucpu.Ptr[testContainer](inv.Bindings, 0, 0)
+			if id := inv.GlobalID[0]; id < container.count {
+				container.elements[id] += 1
+			}
+		},
+	}
+	maps.Copy(kernels, gpucpu.CounterKernels)
+	device := gpucpu.NewDevice(kernels)
+	container := InitStorageBufferStruct(device, testContainer{count: 5}, WithCopySrcUsage())
+	cpf, err := NewNamedComputePassFactory(device, testCountedShader, map[string]ComputePassBuffer{
+		"gContainer": container,
+	})
+	if err != nil {
+		t.Fatalf("NewNamedComputePassFactory() = %v", err)
+	}
+	pass := cpf.InitCountedPass("main", wgsl.Overrides{"workgroupSize": 2}, NewCounter(container, "count", 8))
+
+	encoder := device.CreateCommandEncoder()
+	pass(encoder)
+	device.Queue().Submit(encoder.Finish())
+
+	// The count is rounded up to whole workgroups, and the shader skips the invocations past it.
+	got := <-container.Read(NewStagingPool(device), 0, 1)
+	want := testContainer{count: 5, elements: [8]uint32{1, 1, 1, 1, 1}}
+	if diff := cmp.Diff(want, got[0], cmp.AllowUnexported(testContainer{})); diff != "" {
+		t.Errorf("container diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestIndirectDraw(t *testing.T) {
+	device := gpufake.NewDevice()
+	container := InitStorageBufferStruct(device, testContainer{})
+	args := InitIndirectDrawBuffer(device, DrawArgs{VertexCount: 3})
+	argsPass := InitDrawArgsPass(device, NewCounter(container, "count", 8), args)
+
+	rpf := NewRenderPassFactory(device, &gpufake.CanvasContext{}, testRenderShader, nil, nil)
+	renderPass := rpf.InitPass(Draw{VertexEntryPoint: "vertex_alt", FragmentEntryPoint: "fragment_main", Indirect: &args})
+
+	encoder := device.CreateCommandEncoder()
+	argsPass(encoder)
+	renderPass(encoder)
+	device.Queue().Submit(encoder.Finish())
+
+	dispatch := device.Dispatches()[0]
+	if got := dispatch.Pipeline.Desc.Compute.EntryPoint; got != "drawArgs" {
+		t.Errorf("EntryPoint = %q, want %q", got, "drawArgs")
+	}
+	if dispatch.BindGroups[0].BufferAt(0) != container.Buffer() || dispatch.BindGroups[0].BufferAt(1) != args.Buffer() {
+		t.Errorf("bind group entries = %+v, want the counter at 0 and the arguments at 1", dispatch.BindGroups[0].Desc.Entries)
+	}
+	draw := device.Draws()[0]
+	if draw.IndirectBuffer != args.Buffer() || draw.IndirectOffset != 0 {
+		t.Errorf("draw isn't made indirectly with the arguments")
+	}
+}
+
+func TestIndirectDrawOnCPU(t *testing.T) {
+	device := gpucpu.NewDevice(gpucpu.CounterKernels)
+	container := InitStorageBufferStruct(device, testContainer{count: 10})
+	args := InitIndirectDrawBuffer(device, DrawArgs{VertexCount: 3, FirstVertex: 1}, WithCopySrcUsage())
+	argsPass := InitDrawArgsPass(device, NewCounter(container, "count", 8), args)
+
+	encoder := device.CreateCommandEncoder()
+	argsPass(encoder)
+	device.Queue().Submit(encoder.Finish())
+
+	// The count is clamped to the capacity, and the other arguments are left unchanged.
+	got := <-args.Read(NewStagingPool(device), 0, 1)
+	if diff := cmp.Diff(DrawArgs{VertexCount: 3, InstanceCount: 8, FirstVertex: 1}, got[0]); diff != "" {
+		t.Errorf("DrawArgs diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestInitCountedPassPanics(t *testing.T) {
+	device := gpufake.NewDevice()
+	container := InitStorageBufferStruct(device, testContainer{})
+	shader := strings.Replace(testCountedShader, "@workgroup_size(workgroupSize)", "@workgroup_size(workgroupSize, 2)", 1)
+	cpf, err := NewNamedComputePassFactory(device, shader, map[string]ComputePassBuffer{
+		"gContainer": container,
+	})
+	if err != nil {
+		t.Fatalf("NewNamedComputePassFactory() = %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("InitCountedPass() with a two-dimensional @workgroup_size didn't panic")
+		}
+	}()
+	cpf.InitCountedPass("main", wgsl.Overrides{"workgroupSize": 16}, NewCounter(container, "count", 8))
+}
+
+func TestNewCounterPanics(t *testing.T) {
+	device := gpufake.NewDevice()
+	container := InitStorageBufferStruct(device, testContainer{})
+	values := InitStorageBufferSlice(device, make([]uint32, 4))
+	params := InitUniformBuffer(device, testParams{})
+
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{
+			name: "unknown field",
+			fn:   func() { NewCounter(container, "missing", 8) },
+		},
+		{
+			name: "field isn't a u32",
+			fn:   func() { NewCounter(params, "scale", 8) },
+		},
+		{
+			name: "buffer of non-structs",
+			fn:   func() { NewCounter(values, "count", 4) },
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("didn't panic")
+				}
+			}()
+			tc.fn()
+		})
+	}
+}
+
+type testParams struct {
+	scale float32
+}
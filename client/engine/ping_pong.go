@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+// A PingPongBuffer owns copies of a storage buffer which passes take turns to read from and write to.
+// Compute passes bind its Read and Write roles in place of a buffer, and each pass which binds the
+// Write role swaps the copies once it has been recorded, so the next pass (or frame) reads what it
+// wrote and Front returns it for rendering.
+type PingPongBuffer[T any] struct {
+	buffers []GPUBuffer[T]
+	state   *pingPongState
+}
+
+// pingPongState is shared by copies of a PingPongBuffer and its roles, so they all see swaps.
+type pingPongState struct {
+	front  int
+	copies int
+}
+
+func (s *pingPongState) swap() {
+	s.front = (s.front + 1) % s.copies
+}
+
+// InitPingPongBufferSlice creates a PingPongBuffer with the given number of copies, each initialized with values.
+// Panics if there are fewer than 2 copies.
+func InitPingPongBufferSlice[T any](device gpu.Device, copies int, values []T, opts ...BufferOption) PingPongBuffer[T] {
+	if copies < 2 {
+		panic(fmt.Sprintf("InitPingPongBufferSlice: need at least 2 copies, got %d", copies))
+	}
+	buffers := make([]GPUBuffer[T], copies)
+	for i := range buffers {
+		buffers[i] = InitStorageBufferSlice(device, values, opts...)
+	}
+	return PingPongBuffer[T]{
+		buffers: buffers,
+		state:   &pingPongState{copies: copies},
+	}
+}
+
+// Front returns the copy which was most recently written, or the first copy if none have been written yet.
+func (b PingPongBuffer[T]) Front() GPUBuffer[T] {
+	return b.buffers[b.state.front]
+}
+
+// Back returns the copy which the next pass binding the Write role will write to.
+func (b PingPongBuffer[T]) Back() GPUBuffer[T] {
+	return b.buffers[(b.state.front+1)%b.state.copies]
+}
+
+// Buffers returns all the copies.
+func (b PingPongBuffer[T]) Buffers() []GPUBuffer[T] {
+	return b.buffers
+}
+
+// Swap makes the back copy the front. It is only needed for passes not created by a ComputePassFactory.
+func (b PingPongBuffer[T]) Swap() {
+	b.state.swap()
+}
+
+// Read returns a binding for the front copy, bound as read-only storage.
+func (b PingPongBuffer[T]) Read() ComputePassBuffer {
+	return pingPongRole[T]{buffer: b, offset: 0, bindingType: gpu.BufferBindingTypeReadOnlyStorage}
+}
+
+// Write returns a binding for the back copy, bound as read-write storage.
+func (b PingPongBuffer[T]) Write() ComputePassBuffer {
+	return pingPongRole[T]{buffer: b, offset: 1, bindingType: gpu.BufferBindingTypeStorage}
+}
+
+// A pingPongBinding is a ComputePassBuffer whose buffer depends on which copy of a PingPongBuffer is the front.
+type pingPongBinding interface {
+	pingPong() *pingPongState
+	// bindingGroupEntryAt returns the entry for the binding when copy front is the front.
+	bindingGroupEntryAt(idx, front int) gpu.BindGroupEntry
+	// writes reports whether passes binding it should swap the copies.
+	writes() bool
+}
+
+// pingPongRole binds the copy offset copies after the front.
+type pingPongRole[T any] struct {
+	buffer      PingPongBuffer[T]
+	offset      int
+	bindingType gpu.BufferBindingType
+}
+
+func (r pingPongRole[T]) StructDefs() []wgsltypes.Struct {
+	return r.buffer.buffers[0].StructDefs()
+}
+
+func (r pingPongRole[T]) WGSLType() wgsltypes.TypeName {
+	return r.buffer.buffers[0].WGSLType()
+}
+
+func (r pingPongRole[T]) BindingType() gpu.BufferBindingType {
+	return r.bindingType
+}
+
+func (r pingPongRole[T]) MakeBindGroupLayoutEntry(idx int) gpu.BindGroupLayoutEntry {
+	entry := r.buffer.buffers[0].MakeBindGroupLayoutEntry(idx)
+	entry.Buffer.Type = r.bindingType
+	return entry
+}
+
+func (r pingPongRole[T]) MakeBindingGroupEntry(idx int) gpu.BindGroupEntry {
+	return r.bindingGroupEntryAt(idx, r.buffer.state.front)
+}
+
+func (r pingPongRole[T]) pingPong() *pingPongState {
+	return r.buffer.state
+}
+
+func (r pingPongRole[T]) bindingGroupEntryAt(idx, front int) gpu.BindGroupEntry {
+	return r.buffer.buffers[(front+r.offset)%r.buffer.state.copies].MakeBindingGroupEntry(idx)
+}
+
+func (r pingPongRole[T]) writes() bool {
+	return r.bindingType == gpu.BufferBindingTypeStorage
+}
+
+// A bindGroupSet holds a bind group for each combination of fronts of the PingPongBuffers bound in it,
+// so passes can pick the right one when they're recorded.
+type bindGroupSet struct {
+	groups []gpu.BindGroup
+	states []*pingPongState
+}
+
+// newBindGroupSet creates the bind groups where buffers[i] is bound to @binding(bindingIdxs[i]).
+func newBindGroupSet(device gpu.Device, layout gpu.BindGroupLayout, buffers []ComputePassBuffer, bindingIdxs []int) bindGroupSet {
+	var s bindGroupSet
+	combinations := 1
+	for _, b := range buffers {
+		if pp, ok := b.(pingPongBinding); ok && !slices.Contains(s.states, pp.pingPong()) {
+			s.states = append(s.states, pp.pingPong())
+			combinations *= pp.pingPong().copies
+		}
+	}
+
+	s.groups = make([]gpu.BindGroup, combinations)
+	for c := range s.groups {
+		entries := make([]gpu.BindGroupEntry, len(buffers))
+		for i, b := range buffers {
+			if pp, ok := b.(pingPongBinding); ok {
+				entries[i] = pp.bindingGroupEntryAt(bindingIdxs[i], s.front(c, slices.Index(s.states, pp.pingPong())))
+			} else {
+				entries[i] = b.MakeBindingGroupEntry(bindingIdxs[i])
+			}
+		}
+		s.groups[c] = device.CreateBindGroup(gpu.BindGroupDescriptor{
+			Layout:  layout,
+			Entries: entries,
+		})
+	}
+	return s
+}
+
+// front returns the front of s.states[i] in combination c.
+func (s bindGroupSet) front(c, i int) int {
+	for _, st := range s.states[:i] {
+		c /= st.copies
+	}
+	return c % s.states[i].copies
+}
+
+// current returns the bind group for the current fronts.
+func (s bindGroupSet) current() gpu.BindGroup {
+	c := 0
+	for i := len(s.states) - 1; i >= 0; i-- {
+		c = c*s.states[i].copies + s.states[i].front
+	}
+	return s.groups[c]
+}
+
+// pingPongWriters returns the distinct PingPongBuffers written by the buffers.
+func pingPongWriters(buffers []ComputePassBuffer) []*pingPongState {
+	var states []*pingPongState
+	for _, b := range buffers {
+		if pp, ok := b.(pingPongBinding); ok && pp.writes() && !slices.Contains(states, pp.pingPong()) {
+			states = append(states, pp.pingPong())
+		}
+	}
+	return states
+}
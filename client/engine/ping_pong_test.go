@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+)
+
+const testPingPongShader = `@group(0) @binding(0) var<storage, read> gSrc : array<u32>;
+@group(0) @binding(1) var<storage, read_write> gDst : array<u32>;
+
+@compute @workgroup_size(64)
+fn step(@builtin(global_invocation_id) id : vec3<u32>) {
+  gDst[id.x] = gSrc[id.x] + 1;
+}
+`
+
+const testPingPongReadShader = `@group(0) @binding(0) var<storage, read> gSrc : array<u32>;
+@group(0) @binding(1) var<storage, read_write> gSum : u32;
+
+@compute @workgroup_size(1)
+fn sum() {
+  for (var i = 0u; i < arrayLength(&gSrc); i++) {
+    gSum += gSrc[i];
+  }
+}
+`
+
+func TestPingPongBuffer(t *testing.T) {
+	for _, copies := range []int{2, 3} {
+		t.Run(fmt.Sprintf("%d copies", copies), func(t *testing.T) {
+			device := gpufake.NewDevice()
+			values := InitPingPongBufferSlice(device, copies, make([]uint32, 100))
+			sum := InitStorageBufferStruct(device, uint32(0))
+
+			stepFactory, err := NewNamedComputePassFactory(device, testPingPongShader, map[string]ComputePassBuffer{
+				"gSrc": values.Read(),
+				"gDst": values.Write(),
+			})
+			if err != nil {
+				t.Fatalf("NewNamedComputePassFactory() = %v", err)
+			}
+			sumFactory, err := NewNamedComputePassFactory(device, testPingPongReadShader, map[string]ComputePassBuffer{
+				"gSrc": values.Read(),
+				"gSum": sum,
+			})
+			if err != nil {
+				t.Fatalf("NewNamedComputePassFactory() = %v", err)
+			}
+			step := stepFactory.InitPass("step", nil, 100)
+			sumPass := sumFactory.InitPass("sum", nil, 1)
+
+			const frames = 4
+			var fronts []gpu.Buffer
+			for i := 0; i < frames; i++ {
+				encoder := device.CreateCommandEncoder()
+				step(encoder)
+				sumPass(encoder)
+				device.Queue().Submit(encoder.Finish())
+				fronts = append(fronts, values.Front().Buffer())
+			}
+
+			type binding struct {
+				Src, Dst gpu.Buffer
+			}
+			var got []binding
+			for _, d := range device.Dispatches() {
+				got = append(got, binding{Src: d.BindGroups[0].BufferAt(0), Dst: d.BindGroups[0].BufferAt(1)})
+			}
+			buffers := values.Buffers()
+			var want []binding
+			for i := 0; i < frames; i++ {
+				src, dst := buffers[i%copies].Buffer(), buffers[(i+1)%copies].Buffer()
+				// The step pass swaps the buffers so the sum pass reads what it wrote.
+				want = append(want, binding{Src: src, Dst: dst}, binding{Src: dst, Dst: sum.Buffer()})
+				if fronts[i] != dst {
+					t.Errorf("frame %d: Front() isn't the buffer written by the step pass", i)
+				}
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("bindings diff (-want +got):\n%s", diff)
+			}
+			if got := len(device.BindGroups); got != copies*2 {
+				t.Errorf("created %d bind groups, want one per copy for each pass (%d)", got, copies*2)
+			}
+		})
+	}
+}
+
+func TestPingPongBufferLayout(t *testing.T) {
+	device := gpufake.NewDevice()
+	values := InitPingPongBufferSlice(device, 2, make([]uint32, 100))
+	if _, err := NewNamedComputePassFactory(device, testPingPongShader, map[string]ComputePassBuffer{
+		"gSrc": values.Write(),
+		"gDst": values.Read(),
+	}); err == nil {
+		t.Errorf("NewNamedComputePassFactory() with swapped roles succeeded, want an error")
+	}
+
+	NewComputePassFactory(device, testPingPongShader, []ComputePassBuffer{values.Read(), values.Write()})
+	layout := device.BindGroupLayouts[len(device.BindGroupLayouts)-1]
+	wantEntries := []gpu.BindGroupLayoutEntry{
+		{Binding: 0, Visibility: gpu.ShaderStageCompute, Buffer: gpu.BufferBindingLayout{Type: gpu.BufferBindingTypeReadOnlyStorage}},
+		{Binding: 1, Visibility: gpu.ShaderStageCompute, Buffer: gpu.BufferBindingLayout{Type: gpu.BufferBindingTypeStorage}},
+	}
+	if diff := cmp.Diff(wantEntries, layout.Desc.Entries); diff != "" {
+		t.Errorf("bind group layout entries diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+)
+
+const (
+	// profilerWindow is the number of frames timings are averaged over.
+	profilerWindow = 60
+	// maxProfilerReadbacks is the number of frames of timestamps which can be waiting to be read back.
+	// Frames resolved while that many are waiting aren't timed.
+	maxProfilerReadbacks = 4
+)
+
+// A Profiler measures how long passes take on the GPU using timestamp queries, keeping an average of
+// each pass's duration over recent frames.
+//
+// Each frame, run the passes wrapped by TimeComputePass and TimeRenderPass, call Resolve before finishing
+// the command encoder, and Collect after submitting it.
+// If the device doesn't have gpu.FeatureTimestampQuery, the passes run untimed and there are no timings.
+type Profiler struct {
+	device        gpu.Device
+	maxPasses     int
+	querySet      gpu.QuerySet
+	resolveBuffer gpu.Buffer
+
+	// frame holds the name of each pass timed since the last Resolve. Pass i writes to queries 2i and 2i+1.
+	frame []string
+	// resolved holds the frames resolved since the last Collect.
+	resolved []profilerReadback
+	// readBuffers holds the buffers which aren't waiting to be read.
+	readBuffers []gpu.Buffer
+	numBuffers  int
+
+	timings map[string]*passTiming
+	names   []string
+	// updated is set when the timings have changed since they were last shown.
+	updated bool
+}
+
+// A profilerReadback is a buffer the timestamps of a frame's passes are copied to.
+type profilerReadback struct {
+	buffer gpu.Buffer
+	names  []string
+}
+
+// NewProfiler returns a profiler which times up to maxPasses passes each frame.
+// Passes run after the first maxPasses in a frame aren't timed.
+func NewProfiler(device gpu.Device, maxPasses int) *Profiler {
+	p := &Profiler{
+		device:    device,
+		maxPasses: maxPasses,
+		timings:   map[string]*passTiming{},
+		updated:   true,
+	}
+	if !device.HasFeature(gpu.FeatureTimestampQuery) {
+		return p
+	}
+	p.querySet = device.CreateQuerySet(gpu.QuerySetDescriptor{Type: gpu.QueryTypeTimestamp, Count: 2 * maxPasses})
+	p.resolveBuffer = device.CreateBuffer(gpu.BufferDescriptor{
+		Label: "profiler resolve",
+		Size:  uint64(16 * maxPasses),
+		Usage: gpu.BufferUsageQueryResolve | gpu.BufferUsageCopySrc,
+	})
+	return p
+}
+
+// Enabled reports whether passes are timed.
+func (p *Profiler) Enabled() bool {
+	return p.querySet != nil
+}
+
+// TimeComputePass returns a pass which runs pass, timing each compute pass it records under name.
+func (p *Profiler) TimeComputePass(name string, pass ComputePass) ComputePass {
+	if !p.Enabled() {
+		return pass
+	}
+	return func(commandEncoder gpu.CommandEncoder) {
+		pass(timedEncoder{CommandEncoder: commandEncoder, profiler: p, name: name})
+	}
+}
+
+// TimeRenderPass returns a pass which runs pass, timing each render pass it records under name.
+func (p *Profiler) TimeRenderPass(name string, pass RenderPass) RenderPass {
+	if !p.Enabled() {
+		return pass
+	}
+	return func(commandEncoder gpu.CommandEncoder) {
+		pass(timedEncoder{CommandEncoder: commandEncoder, profiler: p, name: name})
+	}
+}
+
+// A timedEncoder adds timestamp writes to the passes begun with it.
+type timedEncoder struct {
+	gpu.CommandEncoder
+	profiler *Profiler
+	name     string
+}
+
+func (e timedEncoder) BeginComputePass(desc gpu.ComputePassDescriptor) gpu.ComputePassEncoder {
+	desc.TimestampWrites = e.profiler.timestampWrites(e.name)
+	return e.CommandEncoder.BeginComputePass(desc)
+}
+
+func (e timedEncoder) BeginRenderPass(desc gpu.RenderPassDescriptor) gpu.RenderPassEncoder {
+	desc.TimestampWrites = e.profiler.timestampWrites(e.name)
+	return e.CommandEncoder.BeginRenderPass(desc)
+}
+
+// timestampWrites returns the queries for the next pass of the frame, or nil if there are none left.
+func (p *Profiler) timestampWrites(name string) *gpu.PassTimestampWrites {
+	i := len(p.frame)
+	if i == p.maxPasses {
+		return nil
+	}
+	p.frame = append(p.frame, name)
+	return &gpu.PassTimestampWrites{
+		QuerySet:                  p.querySet,
+		BeginningOfPassWriteIndex: 2 * i,
+		EndOfPassWriteIndex:       2*i + 1,
+	}
+}
+
+// Resolve records commands which copy the timestamps written by the passes timed since the last call to
+// a buffer for Collect to read. It must be called after the passes are recorded.
+func (p *Profiler) Resolve(commandEncoder gpu.CommandEncoder) {
+	names := p.frame
+	p.frame = nil
+	if len(names) == 0 {
+		return
+	}
+	buffer := p.readBuffer()
+	if buffer == nil {
+		return
+	}
+	size := uint64(16 * len(names))
+	commandEncoder.ResolveQuerySet(p.querySet, 0, 2*len(names), p.resolveBuffer, 0)
+	commandEncoder.CopyBufferToBuffer(p.resolveBuffer, 0, buffer, 0, size)
+	p.resolved = append(p.resolved, profilerReadback{buffer: buffer, names: names})
+}
+
+// readBuffer returns a buffer to copy a frame's timestamps to, or nil if too many frames are waiting to be read.
+func (p *Profiler) readBuffer() gpu.Buffer {
+	if n := len(p.readBuffers); n > 0 {
+		buffer := p.readBuffers[n-1]
+		p.readBuffers = p.readBuffers[:n-1]
+		return buffer
+	}
+	if p.numBuffers == maxProfilerReadbacks {
+		return nil
+	}
+	p.numBuffers++
+	return p.device.CreateBuffer(gpu.BufferDescriptor{
+		Label: fmt.Sprintf("profiler readback %d", p.numBuffers),
+		Size:  uint64(16 * p.maxPasses),
+		Usage: gpu.BufferUsageMapRead | gpu.BufferUsageCopyDst,
+	})
+}
+
+// Collect reads the timestamps resolved since the last call, updating the timings once they're available.
+// It must be called after submitting the command buffers Resolve recorded to.
+// Frames whose timestamps can't be read are logged and left out of the timings.
+func (p *Profiler) Collect() {
+	for _, r := range p.resolved {
+		r.buffer.MapRead(0, uint64(16*len(r.names)), func(data []byte, err error) {
+			p.readBuffers = append(p.readBuffers, r.buffer)
+			if err != nil {
+				log.Printf("reading timestamps: %v", err)
+				return
+			}
+			p.record(r.names, data)
+		})
+	}
+	p.resolved = nil
+}
+
+// record adds the durations of a frame's passes to the timings. Passes with the same name are summed.
+func (p *Profiler) record(names []string, data []byte) {
+	frame := map[string]time.Duration{}
+	var order []string
+	for i, name := range names {
+		begin := binary.LittleEndian.Uint64(data[16*i:])
+		end := binary.LittleEndian.Uint64(data[16*i+8:])
+		// Timestamps may be quantized, or even go backwards, so short passes can end before they begin.
+		var d time.Duration
+		if end > begin {
+			d = time.Duration(end - begin)
+		}
+		if _, ok := frame[name]; !ok {
+			order = append(order, name)
+		}
+		frame[name] += d
+	}
+	for _, name := range order {
+		t, ok := p.timings[name]
+		if !ok {
+			t = &passTiming{}
+			p.timings[name] = t
+			p.names = append(p.names, name)
+		}
+		t.add(frame[name])
+	}
+	p.updated = true
+}
+
+// A PassTiming is how long a pass took on the GPU, averaged over recent frames.
+type PassTiming struct {
+	Name    string
+	Average time.Duration
+	// Frames is the number of frames averaged.
+	Frames int
+}
+
+// Timings returns the timing of each pass, in the order they were first timed.
+func (p *Profiler) Timings() []PassTiming {
+	var timings []PassTiming
+	for _, name := range p.names {
+		t := p.timings[name]
+		timings = append(timings, PassTiming{Name: name, Average: t.average(), Frames: len(t.durations)})
+	}
+	return timings
+}
+
+// String returns a table of the timings, with a line for each pass followed by the total.
+func (p *Profiler) String() string {
+	if !p.Enabled() {
+		return fmt.Sprintf("GPU timings unavailable: the device doesn't support %s", gpu.FeatureTimestampQuery)
+	}
+	timings := p.Timings()
+	width := len("total")
+	for _, t := range timings {
+		width = max(width, len(t.Name))
+	}
+	var sb strings.Builder
+	var total time.Duration
+	for _, t := range timings {
+		fmt.Fprintf(&sb, "%-*s %8.3fms\n", width, t.Name, milliseconds(t.Average))
+		total += t.Average
+	}
+	fmt.Fprintf(&sb, "%-*s %8.3fms", width, "total", milliseconds(total))
+	return sb.String()
+}
+
+// ShowOverlay displays the timings over the page, if they've changed since it was last called.
+func (p *Profiler) ShowOverlay() {
+	if !p.updated {
+		return
+	}
+	p.updated = false
+	showOverlay(p.String())
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// passTiming holds the durations of a pass in the most recent frames.
+type passTiming struct {
+	durations []time.Duration
+	// next is the index of the duration to replace once the window is full.
+	next int
+}
+
+func (t *passTiming) add(d time.Duration) {
+	if len(t.durations) < profilerWindow {
+		t.durations = append(t.durations, d)
+		return
+	}
+	t.durations[t.next] = d
+	t.next = (t.next + 1) % profilerWindow
+}
+
+func (t *passTiming) average() time.Duration {
+	var total time.Duration
+	for _, d := range t.durations {
+		total += d
+	}
+	return total / time.Duration(len(t.durations))
+}
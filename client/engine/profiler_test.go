@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+)
+
+// initProfiledPasses returns passes timed as "update", "counted" and "render". The counted pass records
+// two compute passes.
+func initProfiledPasses(t *testing.T, device *gpufake.Device, profiler *Profiler) []func(gpu.CommandEncoder) {
+	t.Helper()
+	container := InitStorageBufferStruct(device, testContainer{})
+	cpf, err := NewNamedComputePassFactory(device, testCountedShader, map[string]ComputePassBuffer{
+		"gContainer": container,
+	})
+	if err != nil {
+		t.Fatalf("NewNamedComputePassFactory() = %v", err)
+	}
+	overrides := wgsl.Overrides{"workgroupSize": 16}
+	rpf := NewRenderPassFactory(device, &gpufake.CanvasContext{}, testRenderShader, nil, nil)
+	return []func(gpu.CommandEncoder){
+		profiler.TimeComputePass("update", cpf.InitPass("main", overrides, 8)),
+		profiler.TimeComputePass("counted", cpf.InitCountedPass("main", overrides, NewCounter(container, "count", 8))),
+		profiler.TimeRenderPass("render", rpf.InitPass(Draw{VertexEntryPoint: "vertex_alt", FragmentEntryPoint: "fragment_main", VertexCount: 3})),
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	device := gpufake.NewDevice()
+	device.Features = []gpu.FeatureName{gpu.FeatureTimestampQuery}
+	profiler := NewProfiler(device, 8)
+	passes := initProfiledPasses(t, device, profiler)
+
+	const frames = 2
+	for frame := 1; frame <= frames; frame++ {
+		encoder := device.CreateCommandEncoder()
+		for _, pass := range passes {
+			pass(encoder)
+		}
+		profiler.Resolve(encoder)
+		// Each frame's passes take twice as long as the last.
+		scale := uint64(frame)
+		device.QuerySets[0].Results = []uint64{
+			0, 1000 * scale, // update
+			2000, 2000 + 500*scale, // counted
+			3000, 3000 + 250*scale, // counted
+			4000, 4000 + 2000*scale, // render
+		}
+		device.Queue().Submit(encoder.Finish())
+		profiler.Collect()
+	}
+
+	var gotWrites []gpu.PassTimestampWrites
+	for _, d := range device.Dispatches()[:3] {
+		gotWrites = append(gotWrites, *d.Pass.TimestampWrites)
+	}
+	gotWrites = append(gotWrites, *device.Draws()[0].Pass.TimestampWrites)
+	querySet := device.QuerySets[0]
+	wantWrites := []gpu.PassTimestampWrites{
+		{QuerySet: querySet, BeginningOfPassWriteIndex: 0, EndOfPassWriteIndex: 1},
+		{QuerySet: querySet, BeginningOfPassWriteIndex: 2, EndOfPassWriteIndex: 3},
+		{QuerySet: querySet, BeginningOfPassWriteIndex: 4, EndOfPassWriteIndex: 5},
+		{QuerySet: querySet, BeginningOfPassWriteIndex: 6, EndOfPassWriteIndex: 7},
+	}
+	if diff := cmp.Diff(wantWrites, gotWrites); diff != "" {
+		t.Errorf("timestamp writes diff (-want +got):\n%s", diff)
+	}
+
+	want := []PassTiming{
+		{Name: "update", Average: 1500 * time.Nanosecond, Frames: 2},
+		{Name: "counted", Average: 1125 * time.Nanosecond, Frames: 2},
+		{Name: "render", Average: 3000 * time.Nanosecond, Frames: 2},
+	}
+	if diff := cmp.Diff(want, profiler.Timings()); diff != "" {
+		t.Errorf("Timings() diff (-want +got):\n%s", diff)
+	}
+	if got, want := profiler.String(), "update     0.002ms\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("String() = %q, want prefix %q", got, want)
+	}
+
+	// The buffer read in the first frame is reused in the second.
+	var readBuffers []*gpufake.Buffer
+	for _, cmd := range device.Commands() {
+		if c, ok := cmd.(gpufake.Copy); ok {
+			readBuffers = append(readBuffers, c.Destination)
+		}
+	}
+	if len(readBuffers) != frames || readBuffers[0] != readBuffers[1] {
+		t.Errorf("timestamps copied to %d buffers in %d frames, want the same buffer each frame", len(readBuffers), frames)
+	}
+}
+
+func TestProfilerMaxPasses(t *testing.T) {
+	device := gpufake.NewDevice()
+	device.Features = []gpu.FeatureName{gpu.FeatureTimestampQuery}
+	profiler := NewProfiler(device, 2)
+	passes := initProfiledPasses(t, device, profiler)
+
+	encoder := device.CreateCommandEncoder()
+	for _, pass := range passes {
+		pass(encoder)
+	}
+	profiler.Resolve(encoder)
+	device.Queue().Submit(encoder.Finish())
+	profiler.Collect()
+
+	// The second compute pass recorded by the counted pass is the last to be timed.
+	if got := device.Dispatches()[2].Pass.TimestampWrites; got != nil {
+		t.Errorf("third pass TimestampWrites = %+v, want nil", got)
+	}
+	if got := device.Draws()[0].Pass.TimestampWrites; got != nil {
+		t.Errorf("render pass TimestampWrites = %+v, want nil", got)
+	}
+	var got []string
+	for _, timing := range profiler.Timings() {
+		got = append(got, timing.Name)
+	}
+	if diff := cmp.Diff([]string{"update", "counted"}, got); diff != "" {
+		t.Errorf("timed passes diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestProfilerMapFails(t *testing.T) {
+	device := gpufake.NewDevice()
+	device.Features = []gpu.FeatureName{gpu.FeatureTimestampQuery}
+	device.MapReadErr = errors.New("device lost")
+	profiler := NewProfiler(device, 8)
+	passes := initProfiledPasses(t, device, profiler)
+
+	for range maxProfilerReadbacks + 1 {
+		encoder := device.CreateCommandEncoder()
+		for _, pass := range passes {
+			pass(encoder)
+		}
+		profiler.Resolve(encoder)
+		device.Queue().Submit(encoder.Finish())
+		profiler.Collect()
+	}
+
+	if got := profiler.Timings(); got != nil {
+		t.Errorf("Timings() = %v, want nil", got)
+	}
+	// The buffer whose read failed is reused, so every frame is resolved.
+	var resolves int
+	for _, cmd := range device.Commands() {
+		if _, ok := cmd.(gpufake.Resolve); ok {
+			resolves++
+		}
+	}
+	if want := maxProfilerReadbacks + 1; resolves != want {
+		t.Errorf("recorded %d resolves, want %d", resolves, want)
+	}
+}
+
+func TestProfilerUnsupported(t *testing.T) {
+	device := gpufake.NewDevice()
+	profiler := NewProfiler(device, 8)
+	passes := initProfiledPasses(t, device, profiler)
+
+	encoder := device.CreateCommandEncoder()
+	for _, pass := range passes {
+		pass(encoder)
+	}
+	profiler.Resolve(encoder)
+	device.Queue().Submit(encoder.Finish())
+	profiler.Collect()
+
+	if profiler.Enabled() {
+		t.Errorf("Enabled() = true, want false")
+	}
+	if len(device.QuerySets) != 0 {
+		t.Errorf("created %d query sets, want 0", len(device.QuerySets))
+	}
+	for _, cmd := range device.Commands() {
+		switch cmd := cmd.(type) {
+		case gpufake.Dispatch:
+			if cmd.Pass.TimestampWrites != nil {
+				t.Errorf("dispatch has TimestampWrites")
+			}
+		case gpufake.Resolve:
+			t.Errorf("recorded a Resolve")
+		}
+	}
+	if got := profiler.Timings(); got != nil {
+		t.Errorf("Timings() = %v, want nil", got)
+	}
+	if got := profiler.String(); !strings.Contains(got, "unavailable") {
+		t.Errorf("String() = %q, want it to say timings are unavailable", got)
+	}
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"math/bits"
+	"sync"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+)
+
+// minStagingSize is the size of the smallest staging buffer. Larger buffers are rounded up to a power of two,
+// so they can be reused for reads of similar sizes.
+const minStagingSize = 256
+
+// A StagingPool holds the buffers GPUBuffer.Read copies to, reusing each once its read has completed.
+// It's safe for concurrent use.
+type StagingPool struct {
+	device gpu.Device
+
+	mu   sync.Mutex
+	free []gpu.Buffer
+	// created is the number of buffers created, used to label them.
+	created int
+}
+
+func NewStagingPool(device gpu.Device) *StagingPool {
+	return &StagingPool{device: device}
+}
+
+// get returns a buffer of at least size bytes which isn't being read.
+func (p *StagingPool) get(size uint64) gpu.Buffer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	best := -1
+	for i, b := range p.free {
+		if b.Size() >= size && (best < 0 || b.Size() < p.free[best].Size()) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		b := p.free[best]
+		p.free = append(p.free[:best], p.free[best+1:]...)
+		return b
+	}
+	p.created++
+	return p.device.CreateBuffer(gpu.BufferDescriptor{
+		Label: fmt.Sprintf("staging %d", p.created),
+		Size:  stagingSize(size),
+		Usage: gpu.BufferUsageMapRead | gpu.BufferUsageCopyDst,
+	})
+}
+
+// put returns a buffer to the pool once its read has completed.
+func (p *StagingPool) put(b gpu.Buffer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.free = append(p.free, b)
+}
+
+// stagingSize returns the size of the staging buffer to create for a read of size bytes.
+func stagingSize(size uint64) uint64 {
+	if size <= minStagingSize {
+		return minStagingSize
+	}
+	return 1 << bits.Len64(size-1)
+}
+
+// Len returns the number of elements in the buffer: the length of the slice it was created from, or 1 for a struct.
+func (b GPUBuffer[T]) Len() int {
+	return b.size / int(elementSize[T]())
+}
+
+// Read copies the elements of the buffer from start up to end to a staging buffer from pool, and returns a
+// channel which receives them once the copy has completed. The copy runs after the work already submitted
+// to the device's queue. The buffer must have been created WithCopySrcUsage.
+//
+// Reads don't share staging buffers, so any number can be in flight at once. In the browser the result is
+// delivered by the event loop, so it must not be waited for from a frame callback: receive it from another
+// goroutine, or poll the channel each frame.
+// Each read allocates a new slice for the result: use ReadInto to reuse one.
+// If the staging buffer can't be mapped, for example because the device was lost, the error is logged and
+// the channel is closed without receiving anything.
+// Panics if the range isn't in the buffer.
+func (b GPUBuffer[T]) Read(pool *StagingPool, start, end int) <-chan []T {
+	if start < 0 || start > end || end > b.Len() {
+		panic(fmt.Sprintf("Read(%d, %d): range isn't in a buffer of %d elements", start, end, b.Len()))
+	}
+	return b.ReadInto(pool, start, make([]T, end-start))
+}
+
+// ReadInto is like Read, but copies the len(dst) elements from start into dst, and the channel receives dst.
+// dst must not be used until the channel has received it.
+// Panics if the range isn't in the buffer.
+func (b GPUBuffer[T]) ReadInto(pool *StagingPool, start int, dst []T) <-chan []T {
+	end := start + len(dst)
+	if start < 0 || end > b.Len() {
+		panic(fmt.Sprintf("ReadInto(%d, %d elements): range isn't in a buffer of %d elements", start, len(dst), b.Len()))
+	}
+	ch := make(chan []T, 1)
+	if len(dst) == 0 {
+		ch <- dst
+		return ch
+	}
+
+	elemSize := elementSize[T]()
+	begin, finish := uint64(start)*elemSize, uint64(end)*elemSize
+	// Copies must be a multiple of 4 bytes, so copy whole words and trim the result.
+	copyBegin := begin &^ 3
+	copySize := (finish+3)&^3 - copyBegin
+
+	staging := pool.get(copySize)
+	encoder := b.device.CreateCommandEncoder()
+	encoder.CopyBufferToBuffer(b.buffer, copyBegin, staging, 0, copySize)
+	b.device.Queue().Submit(encoder.Finish())
+	staging.MapRead(0, copySize, func(data []byte, err error) {
+		pool.put(staging)
+		if err != nil {
+			log.Printf("reading buffer: %v", err)
+			close(ch)
+			return
+		}
+		copy(sliceAsBytesSlice(dst), data[begin-copyBegin:])
+		ch <- dst
+	})
+	return ch
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+)
+
+func TestRead(t *testing.T) {
+	device := gpufake.NewDevice()
+	pool := NewStagingPool(device)
+	vectors := InitStorageBufferSlice(device, []vmath.V2{{X: 1, Y: 2}, {X: 3, Y: 4}, {X: 5, Y: 6}, {X: 7, Y: 8}}, WithCopySrcUsage())
+	halves := InitStorageBufferSlice(device, []uint16{1, 2, 3, 4, 5, 6}, WithCopySrcUsage())
+	params := InitStorageBufferStruct(device, bufferTestParams{scale: 2, offset: 1}, WithCopySrcUsage())
+
+	tests := []struct {
+		name string
+		read func() any
+		want any
+	}{
+		{
+			name: "all",
+			read: func() any { return <-vectors.Read(pool, 0, vectors.Len()) },
+			want: []vmath.V2{{X: 1, Y: 2}, {X: 3, Y: 4}, {X: 5, Y: 6}, {X: 7, Y: 8}},
+		},
+		{
+			name: "sub-range",
+			read: func() any { return <-vectors.Read(pool, 1, 3) },
+			want: []vmath.V2{{X: 3, Y: 4}, {X: 5, Y: 6}},
+		},
+		{
+			name: "empty",
+			read: func() any { return <-vectors.Read(pool, 2, 2) },
+			want: []vmath.V2{},
+		},
+		{
+			name: "unaligned",
+			read: func() any { return <-halves.Read(pool, 1, 4) },
+			want: []uint16{2, 3, 4},
+		},
+		{
+			name: "struct",
+			read: func() any { return <-params.Read(pool, 0, params.Len()) },
+			want: []bufferTestParams{{scale: 2, offset: 1}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, tc.read(), cmp.AllowUnexported(bufferTestParams{})); diff != "" {
+				t.Errorf("Read() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// Every read completed before the next began, so they all reused the same staging buffer.
+	if got := len(device.Buffers); got != 4 {
+		t.Errorf("got %d buffers, want the 3 read from and 1 staging buffer", got)
+	}
+}
+
+func TestReadInto(t *testing.T) {
+	device := gpufake.NewDevice()
+	pool := NewStagingPool(device)
+	values := InitStorageBufferSlice(device, []uint16{1, 2, 3, 4, 5, 6}, WithCopySrcUsage())
+
+	dst := make([]uint16, 3)
+	got := <-values.ReadInto(pool, 1, dst)
+	if diff := cmp.Diff([]uint16{2, 3, 4}, got); diff != "" {
+		t.Errorf("ReadInto() diff (-want +got):\n%s", diff)
+	}
+	if &got[0] != &dst[0] {
+		t.Errorf("ReadInto() returned a new slice, want dst")
+	}
+
+	// A second read overwrites the same slice.
+	<-values.ReadInto(pool, 3, dst)
+	if diff := cmp.Diff([]uint16{4, 5, 6}, dst); diff != "" {
+		t.Errorf("second ReadInto() diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadOverlapping(t *testing.T) {
+	device := gpufake.NewDevice()
+	device.DeferMapReads = true
+	pool := NewStagingPool(device)
+	values := InitStorageBufferSlice(device, []uint32{1, 2, 3, 4}, WithCopySrcUsage())
+
+	first := values.Read(pool, 0, 2)
+	device.Queue().WriteBuffer(values.Buffer(), 0, sliceAsBytesSlice([]uint32{5, 6, 7, 8}))
+	second := values.Read(pool, 0, 4)
+
+	select {
+	case got := <-first:
+		t.Fatalf("first read = %v before the map completed", got)
+	default:
+	}
+	device.CompleteMapReads()
+
+	if diff := cmp.Diff([]uint32{1, 2}, <-first); diff != "" {
+		t.Errorf("first read diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]uint32{5, 6, 7, 8}, <-second); diff != "" {
+		t.Errorf("second read diff (-want +got):\n%s", diff)
+	}
+
+	// Both staging buffers are free, so a third read reuses one.
+	third := values.Read(pool, 0, 1)
+	device.CompleteMapReads()
+	<-third
+	if got := len(device.Buffers); got != 3 {
+		t.Errorf("got %d buffers, want the 1 read from and 2 staging buffers", got)
+	}
+}
+
+func TestReadMapFails(t *testing.T) {
+	device := gpufake.NewDevice()
+	device.MapReadErr = errors.New("device lost")
+	pool := NewStagingPool(device)
+	values := InitStorageBufferSlice(device, []uint32{1, 2, 3, 4}, WithCopySrcUsage())
+
+	if got, ok := <-values.Read(pool, 0, 4); ok {
+		t.Errorf("Read() = %v, want the channel closed", got)
+	}
+
+	// The staging buffer is returned to the pool.
+	device.MapReadErr = nil
+	if diff := cmp.Diff([]uint32{1, 2}, <-values.Read(pool, 0, 2)); diff != "" {
+		t.Errorf("second Read() diff (-want +got):\n%s", diff)
+	}
+	if got := len(device.Buffers); got != 2 {
+		t.Errorf("got %d buffers, want the 1 read from and 1 staging buffer", got)
+	}
+}
+
+func TestReadPanics(t *testing.T) {
+	device := gpufake.NewDevice()
+	pool := NewStagingPool(device)
+	values := InitStorageBufferSlice(device, make([]uint32, 4), WithCopySrcUsage())
+
+	tests := []struct {
+		name       string
+		start, end int
+	}{
+		{name: "negative start", start: -1, end: 2},
+		{name: "end past the last element", start: 0, end: 5},
+		{name: "start after end", start: 3, end: 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Read(%d, %d) didn't panic", tc.start, tc.end)
+				}
+			}()
+			values.Read(pool, tc.start, tc.end)
+		})
+	}
+
+	for _, start := range []int{-1, 2} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("ReadInto(%d) of 3 elements didn't panic", start)
+				}
+			}()
+			values.ReadInto(pool, start, make([]uint32, 3))
+		}()
+	}
+}
@@ -0,0 +1,195 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+type RenderPass func(commandEncoder gpu.CommandEncoder)
+
+// A Draw describes an instanced draw made by a render pass.
+type Draw struct {
+	VertexEntryPoint   string
+	FragmentEntryPoint string
+	// Overrides sets the values of override declarations in the shader, and may be nil.
+	Overrides wgsl.Overrides
+
+	VertexCount   int
+	InstanceCount int
+	// Indirect, if set, holds the draw's arguments, which are read when the pass runs,
+	// and VertexCount and InstanceCount are ignored.
+	Indirect *IndirectDrawBuffer
+}
+
+type RenderPassFactory struct {
+	device               gpu.Device
+	context              gpu.CanvasContext
+	renderShaderModule   ShaderModule
+	renderPassDescriptor gpu.RenderPassDescriptor
+	vertexBuffers        *VertexBuffers
+
+	layout     gpu.PipelineLayout
+	bindGroups *bindGroupSet
+
+	entryPoints []wgsl.EntryPoint
+	parseErr    error
+}
+
+// renderStages are the stages render pass buffers are visible to.
+const renderStages = gpu.ShaderStageVertex | gpu.ShaderStageFragment
+
+// NewRenderPassFactory creates a factory for passes which render to context, clearing it first.
+// buffers[i] is bound to @group(0) @binding(i), and vertexBuffers are bound for each draw, so
+// buffers can be swapped in vertexBuffers.Buffers between frames, and slots bound to a PingPongBuffer
+// follow its swaps. vertexBuffers may be nil.
+// Storage buffers are bound read-only, and the roles of a PingPongBuffer follow its swaps without swapping it.
+// Panics if the shader can't be preprocessed.
+func NewRenderPassFactory(device gpu.Device, context gpu.CanvasContext, renderShaderCode string, vertexBuffers *VertexBuffers, buffers []ComputePassBuffer, opts ...ShaderModuleOption) RenderPassFactory {
+	cfg := newShaderModuleConfig(opts)
+	src, err := cfg.preprocess(renderShaderCode)
+	if err != nil {
+		panic(fmt.Sprintf("preprocessing shader: %v", err))
+	}
+	if vertexBuffers == nil {
+		vertexBuffers = &VertexBuffers{}
+	}
+
+	structDefinitions := []wgsltypes.Struct{}
+	for _, b := range buffers {
+		structDefinitions = append(structDefinitions, b.StructDefs()...)
+	}
+	renderShaderModule := createShaderModule(device, src, structDefinitions, cfg)
+
+	bindingIdxs := make([]int, len(buffers))
+	bindGroupLayoutEntries := make([]gpu.BindGroupLayoutEntry, len(buffers))
+	for i, b := range buffers {
+		bindingIdxs[i] = i
+		entry := b.MakeBindGroupLayoutEntry(i)
+		entry.Visibility = renderStages
+		// Vertex shaders can't write to storage buffers.
+		if entry.Buffer.Type == gpu.BufferBindingTypeStorage {
+			entry.Buffer.Type = gpu.BufferBindingTypeReadOnlyStorage
+		}
+		bindGroupLayoutEntries[i] = entry
+	}
+
+	rpf := RenderPassFactory{
+		device:             device,
+		context:            context,
+		renderShaderModule: renderShaderModule,
+		renderPassDescriptor: gpu.RenderPassDescriptor{
+			ColorAttachments: []gpu.RenderPassColorAttachment{
+				{
+					ClearValue: gpu.Color{R: 0.0, G: 0.0, B: 0.0, A: 1.0},
+					LoadOp:     gpu.LoadOpClear,
+					StoreOp:    gpu.StoreOpStore,
+				},
+			},
+		},
+		vertexBuffers: vertexBuffers,
+	}
+	var bindGroupLayouts []gpu.BindGroupLayout
+	if len(buffers) > 0 {
+		bindGroupLayout := device.CreateBindGroupLayout(gpu.BindGroupLayoutDescriptor{
+			Entries: bindGroupLayoutEntries,
+		})
+		bindGroupLayouts = append(bindGroupLayouts, bindGroupLayout)
+		bindGroups := newBindGroupSet(device, bindGroupLayout, buffers, bindingIdxs)
+		rpf.bindGroups = &bindGroups
+	}
+	rpf.layout = device.CreatePipelineLayout(gpu.PipelineLayoutDescriptor{
+		BindGroupLayouts: bindGroupLayouts,
+	})
+	rpf.entryPoints, rpf.parseErr = wgsl.ParseEntryPoints(src.Code)
+	return rpf
+}
+
+// CompilationErr waits for the factory's shader module to be compiled and returns an error as ShaderModule.CompilationErr does.
+func (rpf RenderPassFactory) CompilationErr() error {
+	return rpf.renderShaderModule.CompilationErr()
+}
+
+// InitPass returns a pass which makes the draws, in order, to the canvas's current texture.
+// Panics if an entry point can't be found in the shader, or the overrides are invalid.
+func (rpf RenderPassFactory) InitPass(draws ...Draw) RenderPass {
+	pipelines := make([]gpu.RenderPipeline, len(draws))
+	for i, d := range draws {
+		pipeline, err := rpf.createPipeline(d)
+		if err != nil {
+			panic(fmt.Sprintf("InitPass(%q, %q): %v", d.VertexEntryPoint, d.FragmentEntryPoint, err))
+		}
+		pipelines[i] = pipeline
+	}
+	desc := rpf.renderPassDescriptor
+	desc.ColorAttachments = slices.Clone(desc.ColorAttachments)
+	return func(commandEncoder gpu.CommandEncoder) {
+		desc.ColorAttachments[0].View = rpf.context.CurrentTextureView()
+		passEncoder := commandEncoder.BeginRenderPass(desc)
+		for i, d := range draws {
+			passEncoder.SetPipeline(pipelines[i])
+			if rpf.bindGroups != nil {
+				passEncoder.SetBindGroup(0, rpf.bindGroups.current())
+			}
+			rpf.vertexBuffers.Bind(passEncoder)
+			if d.Indirect != nil {
+				passEncoder.DrawIndirect(d.Indirect.Buffer(), 0)
+			} else {
+				passEncoder.Draw(uint32(d.VertexCount), uint32(d.InstanceCount), 0, 0)
+			}
+		}
+		passEncoder.End()
+	}
+}
+
+func (rpf RenderPassFactory) createPipeline(d Draw) (gpu.RenderPipeline, error) {
+	if err := rpf.checkEntryPoint(d.VertexEntryPoint, wgsl.StageVertex); err != nil {
+		return nil, err
+	}
+	if err := rpf.checkEntryPoint(d.FragmentEntryPoint, wgsl.StageFragment); err != nil {
+		return nil, err
+	}
+	constants, err := rpf.renderShaderModule.PipelineConstants(d.Overrides)
+	if err != nil {
+		return nil, err
+	}
+	return rpf.device.CreateRenderPipeline(gpu.RenderPipelineDescriptor{
+		Layout: rpf.layout,
+		Vertex: gpu.VertexState{
+			ProgrammableStage: gpu.ProgrammableStage{
+				Module:     rpf.renderShaderModule.Module,
+				EntryPoint: d.VertexEntryPoint,
+				Constants:  constants,
+			},
+			Buffers: rpf.vertexBuffers.Layout,
+		},
+		Fragment: &gpu.FragmentState{
+			ProgrammableStage: gpu.ProgrammableStage{
+				Module:     rpf.renderShaderModule.Module,
+				EntryPoint: d.FragmentEntryPoint,
+				Constants:  constants,
+			},
+			Targets: []gpu.ColorTargetState{
+				{Format: rpf.context.Format()},
+			},
+		},
+		Primitive: gpu.PrimitiveState{
+			Topology: gpu.PrimitiveTopologyTriangleList,
+		},
+	}), nil
+}
+
+// checkEntryPoint returns an error if the shader has no entry point with the name for the stage.
+func (rpf RenderPassFactory) checkEntryPoint(name string, stage wgsl.Stage) error {
+	if rpf.parseErr != nil {
+		return fmt.Errorf("parsing shader: %v", rpf.parseErr)
+	}
+	ep, ok := wgsl.FindEntryPoint(rpf.entryPoints, name)
+	if !ok || ep.Stage != stage {
+		return fmt.Errorf("no %s entry point named %q", stage, name)
+	}
+	return nil
+}
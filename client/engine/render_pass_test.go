@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+)
+
+const testRenderShader = `@group(0) @binding(0) var<uniform> params : vec4<f32>;
+@group(0) @binding(1) var<storage, read> gValues : array<u32>;
+
+override scale : f32 = 1.0;
+
+@vertex
+fn vertex_main(@location(0) pos : vec2<f32>) -> @builtin(position) vec4<f32> {
+  return vec4(pos * scale, 0.0, 1.0);
+}
+
+@vertex
+fn vertex_alt(@location(0) pos : vec2<f32>) -> @builtin(position) vec4<f32> {
+  return vec4(pos, 0.0, 1.0);
+}
+
+@fragment
+fn fragment_main() -> @location(0) vec4<f32> {
+  return params;
+}
+`
+
+type testVertex struct {
+	pos vmath.V2
+}
+
+func TestRenderPass(t *testing.T) {
+	device := gpufake.NewDevice()
+	context := &gpufake.CanvasContext{TextureFormat: gpu.TextureFormatRGBA8Unorm}
+	params := InitUniformBuffer(device, vmath.NewV4(1, 2, 3, 4))
+	values := InitStorageBufferSlice(device, make([]uint32, 4))
+	vertices := []GPUBuffer[testVertex]{
+		InitStorageBufferSlice(device, make([]testVertex, 3), WithVertexUsage()),
+		InitStorageBufferSlice(device, make([]testVertex, 3), WithVertexUsage()),
+	}
+
+	vertexBuffer := NewVertexBuffer(vertices[0], "pos")
+	vertexBuffers := NewVertexBuffersFrom(vertexBuffer)
+	rpf := NewRenderPassFactory(device, context, testRenderShader, vertexBuffers, []ComputePassBuffer{params, values})
+	if err := rpf.CompilationErr(); err != nil {
+		t.Fatalf("CompilationErr() = %v", err)
+	}
+	pass := rpf.InitPass(
+		Draw{VertexEntryPoint: "vertex_main", FragmentEntryPoint: "fragment_main", Overrides: wgsl.Overrides{"scale": 2}, VertexCount: 3, InstanceCount: 10},
+		Draw{VertexEntryPoint: "vertex_alt", FragmentEntryPoint: "fragment_main", VertexCount: 3, InstanceCount: 20},
+	)
+
+	const frames = 2
+	for i := 0; i < frames; i++ {
+		vertexBuffer.Set(vertices[i%2])
+		encoder := device.CreateCommandEncoder()
+		pass(encoder)
+		device.Queue().Submit(encoder.Finish())
+	}
+
+	type draw struct {
+		Frame         int
+		EntryPoint    string
+		Constants     map[string]float64
+		VertexBuffer  gpu.Buffer
+		InstanceCount uint32
+	}
+	var got []draw
+	for _, d := range device.Draws() {
+		got = append(got, draw{
+			Frame:         d.Pass.ColorAttachments[0].View.(*gpufake.TextureView).Frame,
+			EntryPoint:    d.Pipeline.Desc.Vertex.EntryPoint,
+			Constants:     d.Pipeline.Desc.Fragment.Constants,
+			VertexBuffer:  d.VertexBuffers[0],
+			InstanceCount: d.InstanceCount,
+		})
+		if bg := d.BindGroups[0]; bg.BufferAt(0) != params.Buffer() || bg.BufferAt(1) != values.Buffer() {
+			t.Errorf("bind group entries = %+v, want params at 0 and values at 1", bg.Desc.Entries)
+		}
+		if got := d.Pipeline.Desc.Fragment.Targets[0].Format; got != context.TextureFormat {
+			t.Errorf("target format = %q, want %q", got, context.TextureFormat)
+		}
+	}
+	want := []draw{
+		{Frame: 1, EntryPoint: "vertex_main", Constants: map[string]float64{"scale": 2}, VertexBuffer: vertices[0].Buffer(), InstanceCount: 10},
+		{Frame: 1, EntryPoint: "vertex_alt", Constants: map[string]float64{}, VertexBuffer: vertices[0].Buffer(), InstanceCount: 20},
+		{Frame: 2, EntryPoint: "vertex_main", Constants: map[string]float64{"scale": 2}, VertexBuffer: vertices[1].Buffer(), InstanceCount: 10},
+		{Frame: 2, EntryPoint: "vertex_alt", Constants: map[string]float64{}, VertexBuffer: vertices[1].Buffer(), InstanceCount: 20},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("draws diff (-want +got):\n%s", diff)
+	}
+
+	layout := device.BindGroupLayouts[0]
+	wantEntries := []gpu.BindGroupLayoutEntry{
+		{Binding: 0, Visibility: renderStages, Buffer: gpu.BufferBindingLayout{Type: gpu.BufferBindingTypeUniform}},
+		{Binding: 1, Visibility: renderStages, Buffer: gpu.BufferBindingLayout{Type: gpu.BufferBindingTypeReadOnlyStorage}},
+	}
+	if diff := cmp.Diff(wantEntries, layout.Desc.Entries); diff != "" {
+		t.Errorf("bind group layout entries diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestRenderPassPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		draw Draw
+	}{
+		{
+			name: "missing vertex entry point",
+			draw: Draw{VertexEntryPoint: "missing", FragmentEntryPoint: "fragment_main"},
+		},
+		{
+			name: "fragment entry point used as vertex",
+			draw: Draw{VertexEntryPoint: "fragment_main", FragmentEntryPoint: "fragment_main"},
+		},
+		{
+			name: "unknown override",
+			draw: Draw{VertexEntryPoint: "vertex_main", FragmentEntryPoint: "fragment_main", Overrides: wgsl.Overrides{"missing": 1}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rpf := NewRenderPassFactory(gpufake.NewDevice(), &gpufake.CanvasContext{}, testRenderShader, nil, nil)
+			defer func() {
+				if recover() == nil {
+					t.Errorf("InitPass() didn't panic")
+				}
+			}()
+			rpf.InitPass(tc.draw)
+		})
+	}
+}
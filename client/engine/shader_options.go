@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+type shaderModuleConfig struct {
+	consts []wgsltypes.Const
+
+	name    string
+	loader  wgsl.FileLoader
+	defines []string
+}
+
+type ShaderModuleOption func(c *shaderModuleConfig)
+
+func newShaderModuleConfig(opts []ShaderModuleOption) shaderModuleConfig {
+	cfg := shaderModuleConfig{name: "shader.wgsl"}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// preprocess expands preprocessor directives in code.
+func (c shaderModuleConfig) preprocess(code string) (wgsl.Source, error) {
+	p := wgsl.Preprocessor{Load: c.loader, Defines: c.defines}
+	return p.Process(c.name, code)
+}
+
+// WithConsts declares the constants in the shader module's prologue.
+func WithConsts(consts ...wgsltypes.Const) ShaderModuleOption {
+	return func(c *shaderModuleConfig) {
+		c.consts = append(c.consts, consts...)
+	}
+}
+
+// WithSourceName sets the file name of the shader, used to resolve relative #includes and report errors.
+func WithSourceName(name string) ShaderModuleOption {
+	return func(c *shaderModuleConfig) {
+		c.name = name
+	}
+}
+
+// WithIncludes sets the loader used to resolve #include directives, e.g. wgsl.FSLoader(embeddedFS).
+func WithIncludes(loader wgsl.FileLoader) ShaderModuleOption {
+	return func(c *shaderModuleConfig) {
+		c.loader = loader
+	}
+}
+
+// WithDefines defines names for #ifdef and #ifndef directives.
+func WithDefines(names ...string) ShaderModuleOption {
+	return func(c *shaderModuleConfig) {
+		c.defines = append(c.defines, names...)
+	}
+}
@@ -2,17 +2,9 @@ package engine
 
 import (
 	"runtime"
-	"syscall/js"
 	"unsafe"
 )
 
-var (
-	objectCtor       = js.Global().Get("Object")
-	arrayBufferCtor  = js.Global().Get("ArrayBuffer")
-	uint8ArrayCtor   = js.Global().Get("Uint8Array")
-	float32ArrayCtor = js.Global().Get("Float32Array")
-)
-
 // sliceAsBytesSlice reinterprets the provided slice of data as a []byte.
 // See https://github.com/golang/go/issues/32402.
 func sliceAsBytesSlice[T any](data []T) []byte {
@@ -46,10 +38,8 @@ func byteSliceAsStructSlice[T any](data []byte) []T {
 	return s
 }
 
-func setFloat32Array(f32arr js.Value, values []float32) {
-	// Ideally we could all something like: `f32arr.Call("set", vertexBufferData)`
-	// but the js only handles []any.
-	for i := 0; i < len(values); i++ {
-		f32arr.SetIndex(i, values[i])
-	}
+// elementSize returns the size of T, which is the stride of a slice of T.
+func elementSize[T any]() uint64 {
+	var zero T
+	return uint64(unsafe.Sizeof(zero))
 }
@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+)
+
+// A bufferWriter writes data to a buffer: either the device's gpu.Queue, or an UploadRing.
+type bufferWriter interface {
+	WriteBuffer(buffer gpu.Buffer, offset uint64, data []byte)
+}
+
+// writer returns what writes to the buffer go through.
+func (b GPUBuffer[T]) writer() bufferWriter {
+	if b.ring != nil {
+		return b.ring
+	}
+	return b.device.Queue()
+}
+
+// Staged returns the buffer with its writes staged in ring, so they're applied when the ring is flushed.
+func (b GPUBuffer[T]) Staged(ring *UploadRing) GPUBuffer[T] {
+	b.ring = ring
+	return b
+}
+
+// WriteElement writes value to the element at index i.
+// Panics if i is out of range, or the element's offset and size aren't multiples of 4 bytes.
+func (b GPUBuffer[T]) WriteElement(i int, value T) {
+	if i < 0 || i >= b.Len() {
+		panic(fmt.Sprintf("WriteElement(%d): index out of range in a buffer of %d elements", i, b.Len()))
+	}
+	b.write("WriteElement", uint64(i)*elementSize[T](), structAsByteSlice(value))
+}
+
+// WriteRange writes values to the elements starting at index start.
+// Panics if the elements aren't in the buffer, or their offset and size aren't multiples of 4 bytes.
+func (b GPUBuffer[T]) WriteRange(start int, values []T) {
+	if start < 0 || start+len(values) > b.Len() {
+		panic(fmt.Sprintf("WriteRange(%d): %d values don't fit in a buffer of %d elements", start, len(values), b.Len()))
+	}
+	if len(values) == 0 {
+		return
+	}
+	b.write("WriteRange", uint64(start)*elementSize[T](), sliceAsBytesSlice(values))
+}
+
+// WriteField writes value to a field of the struct at index i, leaving the rest of the struct unchanged.
+// Panics if T isn't a struct, i is out of range, value's type doesn't match the field's, or the field's
+// offset and size aren't multiples of 4 bytes.
+func (b GPUBuffer[T]) WriteField(i int, field string, value any) {
+	if len(b.structDefs) == 0 {
+		var t T
+		panic(fmt.Sprintf("WriteField: %T isn't a struct", t))
+	}
+	s := b.structDefs[0]
+	f, ok := s.FieldMap[field]
+	if !ok {
+		panic(fmt.Sprintf("WriteField: %s has no field %q", s.Name, field))
+	}
+	if i < 0 || i >= b.Len() {
+		panic(fmt.Sprintf("WriteField(%d, %q): index out of range in a buffer of %d elements", i, field, b.Len()))
+	}
+	sf, _ := reflect.TypeFor[T]().FieldByName(field)
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || v.Type() != sf.Type {
+		panic(fmt.Sprintf("WriteField: %s.%s has type %v, got %T", s.Name, field, sf.Type, value))
+	}
+	// Copy the value so its bytes can be addressed.
+	p := reflect.New(sf.Type)
+	p.Elem().Set(v)
+	data := unsafe.Slice((*byte)(p.UnsafePointer()), sf.Type.Size())
+	b.write("WriteField", uint64(i)*elementSize[T]()+uint64(f.Offset), data)
+}
+
+// write writes data at offset, panicking if it isn't aligned as WebGPU requires.
+func (b GPUBuffer[T]) write(op string, offset uint64, data []byte) {
+	if offset%4 != 0 || len(data)%4 != 0 {
+		panic(fmt.Sprintf("%s: %d bytes at offset %d aren't aligned to 4 bytes", op, len(data), offset))
+	}
+	b.writer().WriteBuffer(b.buffer, offset, data)
+}
+
+// An UploadRing stages writes to buffers in a ring on the GPU. Flush uploads everything staged since the last
+// flush with a single queue write, and records copies to apply each write. Many small writes made from Go,
+// such as spawning entities, then don't each need their own upload, and they're ordered with the passes
+// recorded in the same command encoder rather than happening before all of them.
+//
+// Each flush uses the next part of the ring, wrapping around to its start, so the ring must be large enough to
+// hold everything flushed to the command buffers submitted together. Call Submitted once they've been submitted,
+// so the ring knows their part of it can be reused.
+type UploadRing struct {
+	device gpu.Device
+	buffer gpu.Buffer
+	// head is the offset in the ring the next flush is uploaded to.
+	head uint64
+	// unsubmitted is the number of bytes of the ring, up to head, used by flushes since the last call to
+	// Submitted, including any skipped at the end of the ring when wrapping around.
+	unsubmitted uint64
+
+	staged []byte
+	copies []stagedCopy
+}
+
+// A stagedCopy copies a staged write to its destination, from offset in the staged data.
+type stagedCopy struct {
+	destination       gpu.Buffer
+	destinationOffset uint64
+	offset            uint64
+	size              uint64
+}
+
+// NewUploadRing returns a ring which can hold size bytes, rounded up to a multiple of 4.
+func NewUploadRing(device gpu.Device, size int) *UploadRing {
+	return &UploadRing{
+		device: device,
+		buffer: device.CreateBuffer(gpu.BufferDescriptor{
+			Label: "upload ring",
+			Size:  (uint64(size) + 3) &^ 3,
+			Usage: gpu.BufferUsageCopySrc | gpu.BufferUsageCopyDst,
+		}),
+	}
+}
+
+// WriteBuffer stages data to be written to buffer at offset when the ring is next flushed. The data is copied,
+// so it can be reused once WriteBuffer returns. The destination must have BufferUsageCopyDst.
+// Panics if the offset and size aren't multiples of 4 bytes, as copies must be, or more than the ring can hold
+// is staged between flushes.
+func (r *UploadRing) WriteBuffer(buffer gpu.Buffer, offset uint64, data []byte) {
+	if offset%4 != 0 || len(data)%4 != 0 {
+		panic(fmt.Sprintf("UploadRing: %d bytes at offset %d aren't aligned to 4 bytes", len(data), offset))
+	}
+	if uint64(len(r.staged)+len(data)) > r.buffer.Size() {
+		panic(fmt.Sprintf("UploadRing: staging %d bytes on top of %d exceeds its size of %d", len(data), len(r.staged), r.buffer.Size()))
+	}
+	r.copies = append(r.copies, stagedCopy{
+		destination:       buffer,
+		destinationOffset: offset,
+		offset:            uint64(len(r.staged)),
+		size:              uint64(len(data)),
+	})
+	r.staged = append(r.staged, data...)
+}
+
+// Flush uploads the writes staged since the last flush, and records the copies which apply them.
+// Panics if the upload would overwrite data flushed since the last call to Submitted, whose copies haven't run.
+func (r *UploadRing) Flush(commandEncoder gpu.CommandEncoder) {
+	if len(r.copies) == 0 {
+		return
+	}
+	size := uint64(len(r.staged))
+	head, used := r.head, r.unsubmitted+size
+	if head+size > r.buffer.Size() {
+		// The rest of the ring is skipped, and still counts as used if the data before it hasn't been submitted.
+		used += r.buffer.Size() - head
+		head = 0
+	}
+	if r.unsubmitted == 0 {
+		used = size
+	}
+	if used > r.buffer.Size() {
+		panic(fmt.Sprintf("UploadRing: flushing %d bytes overwrites data which hasn't been submitted, in a ring of %d bytes", size, r.buffer.Size()))
+	}
+	r.head, r.unsubmitted = head, used
+	r.device.Queue().WriteBuffer(r.buffer, r.head, r.staged)
+	for _, c := range r.copies {
+		commandEncoder.CopyBufferToBuffer(r.buffer, r.head+c.offset, c.destination, c.destinationOffset, c.size)
+	}
+	r.head += size
+	r.staged = r.staged[:0]
+	r.copies = r.copies[:0]
+}
+
+// Submitted tells the ring the command buffers recorded by the flushes so far have been submitted, so the parts
+// of the ring they copy from can be reused.
+func (r *UploadRing) Submitted() {
+	r.unsubmitted = 0
+}
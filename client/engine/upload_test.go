@@ -0,0 +1,197 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+)
+
+func TestWrites(t *testing.T) {
+	device := gpufake.NewDevice()
+	params := InitStorageBufferSlice(device, make([]bufferTestParams, 4), WithCopyDstUsage())
+
+	params.WriteElement(1, bufferTestParams{scale: 1, offset: 2})
+	params.WriteRange(2, []bufferTestParams{{scale: 3, offset: 4}, {scale: 5, offset: 6}})
+	params.WriteField(3, "offset", float32(7))
+
+	wantWrites := []gpufake.Write{
+		{Buffer: params.Buffer().(*gpufake.Buffer), Offset: 8, Data: sliceAsBytesSlice([]float32{1, 2})},
+		{Buffer: params.Buffer().(*gpufake.Buffer), Offset: 16, Data: sliceAsBytesSlice([]float32{3, 4, 5, 6})},
+		{Buffer: params.Buffer().(*gpufake.Buffer), Offset: 28, Data: sliceAsBytesSlice([]float32{7})},
+	}
+	if diff := cmp.Diff(wantWrites, device.Writes); diff != "" {
+		t.Errorf("Writes diff (-want +got):\n%s", diff)
+	}
+	want := []bufferTestParams{{}, {scale: 1, offset: 2}, {scale: 3, offset: 4}, {scale: 5, offset: 7}}
+	got := byteSliceAsStructSlice[bufferTestParams](params.Buffer().(*gpufake.Buffer).Data)
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(bufferTestParams{})); diff != "" {
+		t.Errorf("contents diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestUploadRing(t *testing.T) {
+	device := gpufake.NewDevice()
+	ring := NewUploadRing(device, 30)
+	params := InitStorageBufferSlice(device, make([]bufferTestParams, 4), WithCopyDstUsage())
+	staged := params.Staged(ring)
+
+	// Each frame's writes are uploaded to the ring together, and copied from it in the order they were made.
+	frames := []struct {
+		write       func()
+		wantOffsets []uint64
+		want        []bufferTestParams
+	}{
+		{
+			write: func() {
+				staged.WriteElement(0, bufferTestParams{scale: 1, offset: 2})
+				staged.WriteField(0, "scale", float32(3))
+			},
+			wantOffsets: []uint64{0, 8},
+			want:        []bufferTestParams{{scale: 3, offset: 2}, {}, {}, {}},
+		},
+		{
+			// The ring is 32 bytes, so this frame's 24 don't fit after the last frame's 12 and wrap around.
+			write: func() {
+				staged.WriteRange(1, []bufferTestParams{{scale: 4}, {scale: 5}, {scale: 6}})
+			},
+			wantOffsets: []uint64{0},
+			want:        []bufferTestParams{{scale: 3, offset: 2}, {scale: 4}, {scale: 5}, {scale: 6}},
+		},
+	}
+	for i, frame := range frames {
+		frame.write()
+		if got := len(device.WritesTo(params.Buffer())); got != 0 {
+			t.Errorf("frame %d: got %d writes to the buffer, want them all staged", i, got)
+		}
+
+		encoder := device.CreateCommandEncoder()
+		ring.Flush(encoder)
+		cb := encoder.Finish()
+		device.Queue().Submit(cb)
+		ring.Submitted()
+
+		var gotOffsets []uint64
+		for _, cmd := range cb.(*gpufake.CommandBuffer).Commands {
+			c := cmd.(gpufake.Copy)
+			if c.Destination != params.Buffer() {
+				t.Errorf("frame %d: copied to %q, want the params buffer", i, c.Destination.Desc.Label)
+			}
+			gotOffsets = append(gotOffsets, c.SourceOffset)
+		}
+		if diff := cmp.Diff(frame.wantOffsets, gotOffsets); diff != "" {
+			t.Errorf("frame %d: copy source offsets diff (-want +got):\n%s", i, diff)
+		}
+		got := byteSliceAsStructSlice[bufferTestParams](params.Buffer().(*gpufake.Buffer).Data)
+		if diff := cmp.Diff(frame.want, got, cmp.AllowUnexported(bufferTestParams{})); diff != "" {
+			t.Errorf("frame %d: contents diff (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestUploadRingOverflow(t *testing.T) {
+	device := gpufake.NewDevice()
+	values := InitStorageBufferSlice(device, make([]uint32, 8), WithCopyDstUsage())
+
+	tests := []struct {
+		name string
+		// flushes are the number of values written and flushed before each submit.
+		flushes   [][]int
+		wantPanic bool
+	}{
+		{
+			name:    "fills the ring",
+			flushes: [][]int{{2, 2, 4}},
+		},
+		{
+			name:    "wraps after a submit",
+			flushes: [][]int{{6}, {4}, {4}},
+		},
+		{
+			name:    "wraps into the submitted start of the ring",
+			flushes: [][]int{{4}, {2, 4}},
+		},
+		{
+			name:      "wraps over unsubmitted data",
+			flushes:   [][]int{{4, 6}},
+			wantPanic: true,
+		},
+		{
+			name:      "wraps around onto unsubmitted data",
+			flushes:   [][]int{{4}, {4, 2, 4}},
+			wantPanic: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ring := NewUploadRing(device, 32)
+			staged := values.Staged(ring)
+			defer func() {
+				if r := recover(); (r != nil) != tc.wantPanic {
+					t.Errorf("panicked = %v, want %v", r, tc.wantPanic)
+				}
+			}()
+			for _, frame := range tc.flushes {
+				encoder := device.CreateCommandEncoder()
+				for _, n := range frame {
+					staged.WriteRange(0, make([]uint32, n))
+					ring.Flush(encoder)
+				}
+				device.Queue().Submit(encoder.Finish())
+				ring.Submitted()
+			}
+		})
+	}
+}
+
+func TestWritePanics(t *testing.T) {
+	device := gpufake.NewDevice()
+	params := InitStorageBufferSlice(device, make([]bufferTestParams, 4), WithCopyDstUsage())
+	values := InitStorageBufferSlice(device, make([]uint32, 4), WithCopyDstUsage())
+	halves := InitStorageBufferSlice(device, make([]uint16, 4), WithCopyDstUsage())
+	ring := NewUploadRing(device, 8)
+
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{
+			name: "element out of range",
+			fn:   func() { params.WriteElement(4, bufferTestParams{}) },
+		},
+		{
+			name: "range past the last element",
+			fn:   func() { params.WriteRange(3, make([]bufferTestParams, 2)) },
+		},
+		{
+			name: "unknown field",
+			fn:   func() { params.WriteField(0, "missing", float32(1)) },
+		},
+		{
+			name: "field of the wrong type",
+			fn:   func() { params.WriteField(0, "scale", 1.0) },
+		},
+		{
+			name: "field of a non-struct",
+			fn:   func() { values.WriteField(0, "scale", float32(1)) },
+		},
+		{
+			name: "unaligned element",
+			fn:   func() { halves.WriteElement(1, 1) },
+		},
+		{
+			name: "ring overflow",
+			fn:   func() { values.Staged(ring).WriteRange(0, make([]uint32, 3)) },
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("didn't panic")
+				}
+			}()
+			tc.fn()
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+var vertexFormatTypeMap = map[wgsltypes.TypeName]gpu.VertexFormat{
+	"f32":       gpu.VertexFormatFloat32,
+	"i32":       gpu.VertexFormatSint32,
+	"u32":       gpu.VertexFormatUint32,
+	"vec2<f32>": gpu.VertexFormatFloat32x2,
+	"vec3<f32>": gpu.VertexFormatFloat32x3,
+	"vec4<f32>": gpu.VertexFormatFloat32x4,
+	"vec2<f16>": gpu.VertexFormatFloat16x2,
+	"vec4<f16>": gpu.VertexFormatFloat16x4,
+	"vec2<i32>": gpu.VertexFormatSint32x2,
+	"vec3<i32>": gpu.VertexFormatSint32x3,
+	"vec4<i32>": gpu.VertexFormatSint32x4,
+	"vec2<u32>": gpu.VertexFormatUint32x2,
+	"vec3<u32>": gpu.VertexFormatUint32x3,
+	"vec4<u32>": gpu.VertexFormatUint32x4,
+}
+
+// vertexFormatPackingMap maps packed types to the formats which unpack them into floating point vectors.
+var vertexFormatPackingMap = map[string]gpu.VertexFormat{
+	"unorm8x4":  gpu.VertexFormatUnorm8x4,
+	"snorm8x4":  gpu.VertexFormatSnorm8x4,
+	"unorm16x2": gpu.VertexFormatUnorm16x2,
+	"snorm16x2": gpu.VertexFormatSnorm16x2,
+}
+
+func makeGPUVertexAttribute(shaderLocation int, s wgsltypes.Struct, fieldName string) gpu.VertexAttribute {
+	field, ok := s.FieldMap[fieldName]
+	if !ok {
+		panic(fmt.Sprintf("field %s.%s does not exist (fields are %s)", s.GoName, fieldName, strings.Join(s.Fields, ", ")))
+	}
+	return gpu.VertexAttribute{
+		ShaderLocation: shaderLocation,
+		Format:         mustFormatFromFieldType(field.WGSLType),
+		Offset:         uint64(s.MustOffsetOf(fieldName)),
+	}
+}
+
+func mustFormatFromFieldType(fieldType wgsltypes.Type) gpu.VertexFormat {
+	if fieldType.Packing != "" {
+		format, ok := vertexFormatPackingMap[fieldType.Packing]
+		if !ok {
+			panic("unhandled packing: " + fieldType.Packing)
+		}
+		return format
+	}
+	format, ok := vertexFormatTypeMap[fieldType.Name]
+	if !ok {
+		panic("unhandled wgsltype: " + fieldType.Name)
+	}
+	return format
+}
+
+type BufferDescriptor struct {
+	Struct *wgsltypes.Struct
+	// Instanced specifices whether the buffer is stepped as a vertex or instance buffer.
+	Instanced bool
+}
+
+type VertexAttribute struct {
+	BufferIndex int
+	FieldName   string
+}
+
+type VertexBuffers struct {
+	Layout  []gpu.VertexBufferLayout
+	Buffers []gpu.Buffer
+
+	// fronts holds, for slots bound to a PingPongBuffer, a func returning its front copy, which is bound in
+	// place of the slot's entry in Buffers.
+	fronts map[int]func() gpu.Buffer
+}
+
+func NewVertexBuffers(bufDefs []BufferDescriptor, vtxAttrs []VertexAttribute) *VertexBuffers {
+	result := make([]gpu.VertexBufferLayout, len(bufDefs))
+	for idx, bd := range bufDefs {
+		stepMode := gpu.VertexStepModeVertex
+		if bd.Instanced {
+			stepMode = gpu.VertexStepModeInstance
+		}
+		result[idx] = gpu.VertexBufferLayout{
+			ArrayStride: uint64(bd.Struct.Size),
+			StepMode:    stepMode,
+		}
+	}
+
+	for idx, a := range vtxAttrs {
+		if a.BufferIndex >= len(result) {
+			panic("buffer index out of bounds")
+		}
+		attribute := makeGPUVertexAttribute(idx, *bufDefs[a.BufferIndex].Struct, a.FieldName)
+		result[a.BufferIndex].Attributes = append(result[a.BufferIndex].Attributes, attribute)
+	}
+
+	return &VertexBuffers{
+		Layout:  result,
+		Buffers: make([]gpu.Buffer, len(result)),
+	}
+}
+
+func (v *VertexBuffers) Bind(passEncoder gpu.RenderPassEncoder) {
+	for idx, buffer := range v.Buffers {
+		if front, ok := v.fronts[idx]; ok {
+			buffer = front()
+		}
+		passEncoder.SetVertexBuffer(idx, buffer)
+	}
+}
+
+// A VertexBufferSource is a buffer, and the attributes read from it, to add to VertexBuffers.
+// It's implemented by *VertexBuffer[T].
+type VertexBufferSource interface {
+	layout(firstLocation int) gpu.VertexBufferLayout
+	attach(v *VertexBuffers, slot int)
+}
+
+// A VertexBuffer is a GPUBuffer[T] bound to a slot of VertexBuffers, with fields of T read as vertex attributes.
+type VertexBuffer[T any] struct {
+	structDef wgsltypes.Struct
+	stepMode  gpu.VertexStepMode
+	fields    []string
+	buffer    gpu.Buffer
+	// pingPong, if set, is the PingPongBuffer whose front copy is bound in place of buffer.
+	pingPong *PingPongBuffer[T]
+
+	buffers *VertexBuffers
+	slot    int
+}
+
+// NewVertexBuffer returns a buffer which is stepped once per vertex, and provides the named fields of T as attributes.
+// Panics if T isn't a struct, or a field doesn't exist or can't be used as an attribute.
+func NewVertexBuffer[T any](buf GPUBuffer[T], fields ...string) *VertexBuffer[T] {
+	return newVertexBuffer(buf, gpu.VertexStepModeVertex, fields)
+}
+
+// NewInstanceBuffer returns a buffer which is stepped once per instance, and provides the named fields of T as attributes.
+// Panics if T isn't a struct, or a field doesn't exist or can't be used as an attribute.
+func NewInstanceBuffer[T any](buf GPUBuffer[T], fields ...string) *VertexBuffer[T] {
+	return newVertexBuffer(buf, gpu.VertexStepModeInstance, fields)
+}
+
+// NewPingPongVertexBuffer returns a buffer which is stepped once per vertex, and provides the named fields of T as
+// attributes. Its slot is bound to whichever copy of buf is the front when the render pass is recorded, so it
+// reads what the last compute pass wrote.
+// Panics if T isn't a struct, or a field doesn't exist or can't be used as an attribute.
+func NewPingPongVertexBuffer[T any](buf PingPongBuffer[T], fields ...string) *VertexBuffer[T] {
+	vb := newVertexBuffer(buf.Front(), gpu.VertexStepModeVertex, fields)
+	vb.pingPong = &buf
+	return vb
+}
+
+// NewPingPongInstanceBuffer returns a buffer which is stepped once per instance, and provides the named fields of T
+// as attributes. Its slot is bound to whichever copy of buf is the front when the render pass is recorded, so it
+// reads what the last compute pass wrote.
+// Panics if T isn't a struct, or a field doesn't exist or can't be used as an attribute.
+func NewPingPongInstanceBuffer[T any](buf PingPongBuffer[T], fields ...string) *VertexBuffer[T] {
+	vb := newVertexBuffer(buf.Front(), gpu.VertexStepModeInstance, fields)
+	vb.pingPong = &buf
+	return vb
+}
+
+func newVertexBuffer[T any](buf GPUBuffer[T], stepMode gpu.VertexStepMode, fields []string) *VertexBuffer[T] {
+	structDefs := buf.StructDefs()
+	if len(structDefs) == 0 {
+		var zero T
+		panic(fmt.Sprintf("vertex buffer of %T: must be a buffer of structs", zero))
+	}
+	vb := &VertexBuffer[T]{structDef: structDefs[0], stepMode: stepMode, fields: fields, buffer: buf.Buffer()}
+	// Check the fields now, rather than when the buffer is added to VertexBuffers.
+	vb.layout(0)
+	return vb
+}
+
+func (b *VertexBuffer[T]) layout(firstLocation int) gpu.VertexBufferLayout {
+	layout := gpu.VertexBufferLayout{
+		ArrayStride: uint64(b.structDef.Size),
+		StepMode:    b.stepMode,
+	}
+	for i, f := range b.fields {
+		layout.Attributes = append(layout.Attributes, makeGPUVertexAttribute(firstLocation+i, b.structDef, f))
+	}
+	return layout
+}
+
+func (b *VertexBuffer[T]) attach(v *VertexBuffers, slot int) {
+	if b.buffers != nil {
+		panic(fmt.Sprintf("vertex buffer of %s is already bound to slot %d", b.structDef.GoName, b.slot))
+	}
+	b.buffers, b.slot = v, slot
+	v.Buffers[slot] = b.buffer
+	if b.pingPong != nil {
+		if v.fronts == nil {
+			v.fronts = map[int]func() gpu.Buffer{}
+		}
+		pp := b.pingPong
+		v.fronts[slot] = func() gpu.Buffer { return pp.Front().Buffer() }
+	}
+}
+
+// Slot returns the index of the vertex buffer slot the buffer is bound to.
+func (b *VertexBuffer[T]) Slot() int {
+	return b.slot
+}
+
+// Set binds buf to the slot in place of the current buffer, including a PingPongBuffer the buffer was created with.
+func (b *VertexBuffer[T]) Set(buf GPUBuffer[T]) {
+	b.buffer = buf.Buffer()
+	b.pingPong = nil
+	if b.buffers != nil {
+		b.buffers.Buffers[b.slot] = b.buffer
+		delete(b.buffers.fronts, b.slot)
+	}
+}
+
+// NewVertexBuffersFrom binds sources[i] to vertex buffer slot i.
+// Shader locations are assigned to the attributes in order, starting at @location(0).
+func NewVertexBuffersFrom(sources ...VertexBufferSource) *VertexBuffers {
+	v := &VertexBuffers{
+		Layout:  make([]gpu.VertexBufferLayout, len(sources)),
+		Buffers: make([]gpu.Buffer, len(sources)),
+	}
+	location := 0
+	for i, src := range sources {
+		v.Layout[i] = src.layout(location)
+		location += len(v.Layout[i].Attributes)
+		src.attach(v, i)
+	}
+	return v
+}
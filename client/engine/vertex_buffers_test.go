@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+type testInstance struct {
+	pos   vmath.V2
+	angle float32
+	meta  uint32
+	col   vmath.Unorm8x4
+	pad   uint32
+}
+
+func TestNewVertexBuffersFrom(t *testing.T) {
+	device := gpufake.NewDevice()
+	vertices := InitStorageBufferSlice(device, make([]testVertex, 3), WithVertexUsage())
+	instances := []GPUBuffer[testInstance]{
+		InitStorageBufferSlice(device, make([]testInstance, 10), WithVertexUsage()),
+		InitStorageBufferSlice(device, make([]testInstance, 10), WithVertexUsage()),
+	}
+
+	instanceBuffer := NewInstanceBuffer(instances[0], "pos", "col", "meta")
+	vb := NewVertexBuffersFrom(
+		NewVertexBuffer(vertices, "pos"),
+		instanceBuffer,
+	)
+
+	wantLayout := []gpu.VertexBufferLayout{
+		{
+			ArrayStride: 8,
+			StepMode:    gpu.VertexStepModeVertex,
+			Attributes: []gpu.VertexAttribute{
+				{Format: gpu.VertexFormatFloat32x2, Offset: 0, ShaderLocation: 0},
+			},
+		},
+		{
+			ArrayStride: 24,
+			StepMode:    gpu.VertexStepModeInstance,
+			Attributes: []gpu.VertexAttribute{
+				{Format: gpu.VertexFormatFloat32x2, Offset: 0, ShaderLocation: 1},
+				{Format: gpu.VertexFormatUnorm8x4, Offset: 16, ShaderLocation: 2},
+				{Format: gpu.VertexFormatUint32, Offset: 12, ShaderLocation: 3},
+			},
+		},
+	}
+	if diff := cmp.Diff(wantLayout, vb.Layout); diff != "" {
+		t.Errorf("Layout diff (-want +got):\n%s", diff)
+	}
+	if got := instanceBuffer.Slot(); got != 1 {
+		t.Errorf("Slot() = %d, want 1", got)
+	}
+
+	wantBuffers := []gpu.Buffer{vertices.Buffer(), instances[0].Buffer()}
+	if diff := cmp.Diff(wantBuffers, vb.Buffers); diff != "" {
+		t.Errorf("Buffers diff (-want +got):\n%s", diff)
+	}
+	instanceBuffer.Set(instances[1])
+	wantBuffers = []gpu.Buffer{vertices.Buffer(), instances[1].Buffer()}
+	if diff := cmp.Diff(wantBuffers, vb.Buffers); diff != "" {
+		t.Errorf("Buffers after Set diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestPingPongVertexBuffer(t *testing.T) {
+	device := gpufake.NewDevice()
+	instances := InitPingPongBufferSlice(device, 2, make([]testInstance, 10), WithVertexUsage())
+	other := InitStorageBufferSlice(device, make([]testInstance, 10), WithVertexUsage())
+
+	instanceBuffer := NewPingPongInstanceBuffer(instances, "pos")
+	vb := NewVertexBuffersFrom(instanceBuffer)
+	pipeline := device.CreateRenderPipeline(gpu.RenderPipelineDescriptor{})
+
+	// bound returns the buffer Bind binds to the instance buffer's slot.
+	bound := func() gpu.Buffer {
+		encoder := device.CreateCommandEncoder()
+		pass := encoder.BeginRenderPass(gpu.RenderPassDescriptor{})
+		pass.SetPipeline(pipeline)
+		vb.Bind(pass)
+		pass.Draw(3, 1, 0, 0)
+		pass.End()
+		device.Queue().Submit(encoder.Finish())
+		draws := device.Draws()
+		return draws[len(draws)-1].VertexBuffers[instanceBuffer.Slot()]
+	}
+
+	if got, want := bound(), instances.Buffers()[0].Buffer(); got != want {
+		t.Errorf("bound %q before swapping, want the first copy", got.(*gpufake.Buffer).Desc.Label)
+	}
+	instances.Swap()
+	if got, want := bound(), instances.Buffers()[1].Buffer(); got != want {
+		t.Errorf("bound %q after swapping, want the second copy", got.(*gpufake.Buffer).Desc.Label)
+	}
+	instanceBuffer.Set(other)
+	instances.Swap()
+	if got, want := bound(), other.Buffer(); got != want {
+		t.Errorf("bound %q after Set, want the buffer set", got.(*gpufake.Buffer).Desc.Label)
+	}
+}
+
+func TestFormatFromFieldType(t *testing.T) {
+	tests := []struct {
+		name   string
+		lookup func() (wgsltypes.Type, error)
+		want   gpu.VertexFormat
+	}{
+		{name: "f32", lookup: wgsltypes.LookupType[float32], want: gpu.VertexFormatFloat32},
+		{name: "i32", lookup: wgsltypes.LookupType[int32], want: gpu.VertexFormatSint32},
+		{name: "u32", lookup: wgsltypes.LookupType[uint32], want: gpu.VertexFormatUint32},
+		{name: "vec2<f32>", lookup: wgsltypes.LookupType[vmath.V2], want: gpu.VertexFormatFloat32x2},
+		{name: "vec3<f32>", lookup: wgsltypes.LookupType[vmath.V3], want: gpu.VertexFormatFloat32x3},
+		{name: "vec4<f32>", lookup: wgsltypes.LookupType[vmath.V4], want: gpu.VertexFormatFloat32x4},
+		{name: "vec2<i32>", lookup: wgsltypes.LookupType[vmath.V2i], want: gpu.VertexFormatSint32x2},
+		{name: "vec3<i32>", lookup: wgsltypes.LookupType[vmath.V3i], want: gpu.VertexFormatSint32x3},
+		{name: "vec4<i32>", lookup: wgsltypes.LookupType[vmath.V4i], want: gpu.VertexFormatSint32x4},
+		{name: "vec2<u32>", lookup: wgsltypes.LookupType[vmath.V2u], want: gpu.VertexFormatUint32x2},
+		{name: "vec3<u32>", lookup: wgsltypes.LookupType[vmath.V3u], want: gpu.VertexFormatUint32x3},
+		{name: "vec4<u32>", lookup: wgsltypes.LookupType[vmath.V4u], want: gpu.VertexFormatUint32x4},
+		{name: "vec2<f16>", lookup: wgsltypes.LookupType[vmath.V2h], want: gpu.VertexFormatFloat16x2},
+		{name: "vec4<f16>", lookup: wgsltypes.LookupType[vmath.V4h], want: gpu.VertexFormatFloat16x4},
+		// Packed types are u32s in WGSL, but unpacked into floating point vectors by their vertex formats.
+		{name: "unorm8x4", lookup: wgsltypes.LookupType[vmath.Unorm8x4], want: gpu.VertexFormatUnorm8x4},
+		{name: "snorm8x4", lookup: wgsltypes.LookupType[vmath.Snorm8x4], want: gpu.VertexFormatSnorm8x4},
+		{name: "unorm16x2", lookup: wgsltypes.LookupType[vmath.Unorm16x2], want: gpu.VertexFormatUnorm16x2},
+		{name: "snorm16x2", lookup: wgsltypes.LookupType[vmath.Snorm16x2], want: gpu.VertexFormatSnorm16x2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			typ, err := tc.lookup()
+			if err != nil {
+				t.Fatalf("LookupType() = %v", err)
+			}
+			if got := mustFormatFromFieldType(typ); got != tc.want {
+				t.Errorf("mustFormatFromFieldType(%s) = %q, want %q", typ.Name, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVertexBufferPanics(t *testing.T) {
+	device := gpufake.NewDevice()
+	instances := InitStorageBufferSlice(device, make([]testInstance, 10), WithVertexUsage())
+	floats := InitStorageBufferSlice(device, make([]float32, 10), WithVertexUsage())
+
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{
+			name: "unknown field",
+			fn:   func() { NewInstanceBuffer(instances, "pos", "vel") },
+		},
+		{
+			name: "buffer of non-structs",
+			fn:   func() { NewVertexBuffer(floats) },
+		},
+		{
+			name: "buffer bound twice",
+			fn: func() {
+				b := NewInstanceBuffer(instances, "pos")
+				NewVertexBuffersFrom(b)
+				NewVertexBuffersFrom(b)
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("didn't panic")
+				}
+			}()
+			tc.fn()
+		})
+	}
+}
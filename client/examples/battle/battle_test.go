@@ -0,0 +1,121 @@
+package battle
+
+import (
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+	"github.com/hulkholden/gowebgpu/client/examples/battle/sim"
+)
+
+// initTestBuffers returns buffers for the full simulation, initialized the same way on each run.
+func initTestBuffers(device gpu.Device) simBuffers {
+	return initSimBuffers(device, rand.New(rand.NewSource(1)), initialShipCount, sim.MaxParticleCount)
+}
+
+func TestFrames(t *testing.T) {
+	device := gpufake.NewDevice()
+	context := &gpufake.CanvasContext{}
+	update, err := setup(device, context, initTestBuffers(device))
+	if err != nil {
+		t.Fatalf("setup() = %v", err)
+	}
+
+	const frames = 2
+	for i := 0; i < frames; i++ {
+		update()
+	}
+
+	if got := len(device.Submissions); got != frames {
+		t.Fatalf("got %d submissions, want %d", got, frames)
+	}
+	for i, cb := range device.Submissions {
+		var got []string
+		for _, cmd := range cb.Commands {
+			switch cmd := cmd.(type) {
+			case gpufake.Clear:
+				got = append(got, "clear")
+			case gpufake.Dispatch:
+				entryPoint := cmd.Pipeline.Desc.Compute.EntryPoint
+				got = append(got, entryPoint)
+				// applyCollisions runs once per contact, so its workgroups are counted on the GPU.
+				if indirect := cmd.IndirectBuffer != nil; indirect != (entryPoint == "applyCollisions") {
+					t.Errorf("frame %d: %s: indirect = %t", i, entryPoint, indirect)
+				}
+			case gpufake.Draw:
+				got = append(got, cmd.Pipeline.Desc.Vertex.EntryPoint)
+				// The draws end at the last live particle, so their instance counts are set on the GPU.
+				if cmd.IndirectBuffer == nil {
+					t.Errorf("frame %d: %s: draw isn't indirect", i, cmd.Pipeline.Desc.Vertex.EntryPoint)
+				}
+				if view := cmd.Pass.ColorAttachments[0].View.(*gpufake.TextureView); view.Frame != i+1 {
+					t.Errorf("frame %d: rendered to view for frame %d", i, view.Frame)
+				}
+			}
+		}
+		want := []string{
+			"clear",
+			"clear",
+			"computeAcceleration",
+			"applyAcceleration",
+			"computeCollisions",
+			"dispatchArgs",
+			"applyCollisions",
+			"updateMissileLifecycle",
+			"selectTargets",
+			"spawnMissiles",
+			"findParticleExtent",
+			"drawArgs",
+			"drawArgs",
+			"vertex_main_ship",
+			"vertex_main_missile",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("frame %d: commands diff (-want +got):\n%s", i, diff)
+		}
+	}
+
+	if got := len(device.Writes); got != frames {
+		t.Fatalf("got %d buffer writes, want %d", got, frames)
+	}
+	want := sim.DefaultParams()
+	for i, w := range device.Writes {
+		want.Advance()
+		params := *(*sim.SimParams)(unsafe.Pointer(&w.Data[0]))
+		if diff := cmp.Diff(want, params, cmp.AllowUnexported(sim.SimParams{})); diff != "" {
+			t.Errorf("frame %d: SimParams diff (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestProfiledFrames(t *testing.T) {
+	device := gpufake.NewDevice()
+	device.Features = []gpu.FeatureName{gpu.FeatureTimestampQuery}
+	update, err := setup(device, &gpufake.CanvasContext{}, initTestBuffers(device))
+	if err != nil {
+		t.Fatalf("setup() = %v", err)
+	}
+	update()
+
+	var resolves int
+	for _, cmd := range device.Commands() {
+		switch cmd := cmd.(type) {
+		case gpufake.Dispatch:
+			if cmd.Pass.TimestampWrites == nil {
+				t.Errorf("%s: pass isn't timed", cmd.Pipeline.Desc.Compute.EntryPoint)
+			}
+		case gpufake.Draw:
+			if cmd.Pass.TimestampWrites == nil {
+				t.Errorf("%s: pass isn't timed", cmd.Pipeline.Desc.Vertex.EntryPoint)
+			}
+		case gpufake.Resolve:
+			resolves++
+		}
+	}
+	if resolves != 1 {
+		t.Errorf("got %d resolves, want 1", resolves)
+	}
+}
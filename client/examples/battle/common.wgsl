@@ -0,0 +1,42 @@
+// Helpers shared by the compute and render shaders.
+#pragma once
+
+const pi = 3.14159265359;
+const twoPi = 2 * pi;
+
+fn rotVec(v : vec2f, a : f32) -> vec2f {
+  let c = cos(a);
+  let s = sin(a);
+  let transform = mat2x2f(vec2f(c, -s), vec2f(s, c));
+  return v * transform;
+}
+
+fn angleDiff(a : f32, b : f32) -> f32 {
+  return normalizeAngle(a - b);
+}
+
+fn normalizeAngle(a : f32) -> f32 {
+  var n = modReplacement(a + pi, twoPi);
+  if (n < 0) {
+    n += twoPi;
+  }
+  return n - pi;
+}
+
+// modReplacement returns the floating point remainder of x/y.
+// TOOD: check whathappens if x is < 0.
+fn modReplacement(x : f32, y : f32) -> f32 {
+  return x - (y * floor(x/y));
+}
+
+fn makeParticleMetadata(bodyType : u32, team : u32) -> u32 {
+  return (bodyType << 8) | team;
+}
+
+fn metadataBodyType(metadata : u32) -> u32 {
+  return (metadata >> 8) & 0xff;
+}
+
+fn metadataTeam(metadata : u32) -> u32 {
+  return metadata & 0xff;
+}
@@ -1,3 +1,5 @@
+#include "common.wgsl"
+
 
 @binding(0) @group(0) var<uniform> params : SimParams;
 // TODO: is there any performance difference binding these as read only when
@@ -9,17 +11,16 @@
 @binding(5) @group(0) var<storage, read_write> gAccelerations : array<Acceleration>;
 @binding(6) @group(0) var<storage, read_write> gContacts : ContactsContainer;
 @binding(7) @group(0) var<storage, read_write> gFreeIDs : FreeIDsContainer;
+@binding(8) @group(0) var<storage, read_write> gExtent : ParticleExtent;
 
-const pi = 3.14159265359;
-const twoPi = 2 * pi;
-
-const proNavGain = 3.0;
+// proNavGain is the navigation constant for missile proportional navigation.
+override proNavGain : f32 = 3.0;
 
-const bodyTypeNone = 0u;
-const bodyTypeShip = 1u;
-const bodyTypeMissile = 2u;
+// particleWorkgroupSize is the workgroup size for entry points which run once per particle.
+override particleWorkgroupSize : u32 = 64;
 
-const particleFlagHit = 1u;
+// contactWorkgroupSize is the workgroup size for entry points which run once per contact.
+override contactWorkgroupSize : u32 = 64;
 
 fn bodySub(a : Body, b : Body) -> Body {
   return Body(a.pos - b.pos, a.vel - b.vel, angleDiff(a.angle, b.angle), a.angularVel - b.angularVel);
@@ -32,36 +33,11 @@ fn bodyRotate(a : Body, angle : f32) -> Body {
   return Body(a.pos * transform, a.vel * transform, a.angle + angle, a.angularVel);
 }
 
-fn rotVec(v : vec2f, a : f32) -> vec2f {
-  let c = cos(a);
-  let s = sin(a);
-  let transform = mat2x2f(vec2f(c, -s), vec2f(s, c));
-  return v * transform;
-}
-
 fn angleOf(v : vec2f, def : f32) -> f32 {
   return select(def, atan2(-v.x, v.y), length(v) > 0);
 }
 
-fn angleDiff(a : f32, b : f32) -> f32 {
-  return normalizeAngle(a - b);
-}
-
-fn normalizeAngle(a : f32) -> f32 {
-  var n = modReplacement(a + pi, twoPi);
-  if (n < 0) {
-    n += twoPi;
-  }
-  return n - pi;
-}
-
-// modReplacement returns the floating point remainder of x/y.
-// TOOD: check whathappens if x is < 0.
-fn modReplacement(x : f32, y : f32) -> f32 {
-  return x - (y * floor(x/y));
-}
-
-@compute @workgroup_size(64)
+@compute @workgroup_size(particleWorkgroupSize)
 fn computeAcceleration(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>) {
 
   let index = GlobalInvocationID.x;
@@ -84,7 +60,7 @@ fn computeAcceleration(@builtin(global_invocation_id) GlobalInvocationID : vec3<
   gAccelerations[index] = acc;
 }
 
-@compute @workgroup_size(64)
+@compute @workgroup_size(particleWorkgroupSize)
 fn applyAcceleration(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>) {
   let index = GlobalInvocationID.x;
   var body = gBodies[index];
@@ -110,7 +86,7 @@ fn applyAcceleration(@builtin(global_invocation_id) GlobalInvocationID : vec3<u3
   gBodies[index] = body;
 }
 
-@compute @workgroup_size(64)
+@compute @workgroup_size(particleWorkgroupSize)
 fn computeCollisions(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>) {
   let index = GlobalInvocationID.x;
 
@@ -135,19 +111,19 @@ fn addContact(aIdx : u32, bIdx : u32) {
   }
 }
 
-// This is not parallelised but the number of contacts should be low each frame.
-@compute @workgroup_size(1)
+// This runs once per contact. Particles may be in several contacts, but every invocation sets the same flag.
+@compute @workgroup_size(contactWorkgroupSize)
 fn applyCollisions(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>) {
+  let contactIdx = GlobalInvocationID.x;
   let contactCount = min(atomicLoad(&gContacts.count), u32(arrayLength(&gContacts.elements)));
-  for (var contactIdx = 0u; contactIdx < contactCount; contactIdx++) {
-    let aIdx = gContacts.elements[contactIdx].aIdx;
-    let bIdx = gContacts.elements[contactIdx].bIdx;
-    setParticleHit(aIdx);
-    setParticleHit(bIdx);
+  if (contactIdx >= contactCount) {
+    return;
   }
+  setParticleHit(gContacts.elements[contactIdx].aIdx);
+  setParticleHit(gContacts.elements[contactIdx].bIdx);
 }
 
-@compute @workgroup_size(64)
+@compute @workgroup_size(particleWorkgroupSize)
 fn updateMissileLifecycle(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>) {
   let index = GlobalInvocationID.x;
   if (particleHit(index)) {
@@ -172,7 +148,7 @@ fn updateMissileLifecycle(@builtin(global_invocation_id) GlobalInvocationID : ve
   }
 }
 
-@compute @workgroup_size(64)
+@compute @workgroup_size(particleWorkgroupSize)
 fn selectTargets(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>) {
   let index = GlobalInvocationID.x;
 
@@ -192,7 +168,7 @@ fn selectTargets(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>)
   }
 }
 
-@compute @workgroup_size(64)
+@compute @workgroup_size(particleWorkgroupSize)
 fn spawnMissiles(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>) {
   let index = GlobalInvocationID.x;
 
@@ -226,6 +202,21 @@ fn spawnMissiles(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>)
   }
 }
 
+// findParticleExtent finds the end of the live particles, which bounds the draws.
+// gExtent must be cleared first.
+@compute @workgroup_size(particleWorkgroupSize)
+fn findParticleExtent(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>) {
+  let index = GlobalInvocationID.x;
+  // Out of bounds reads are clamped, so the invocations past the last particle would extend past the end.
+  if (index >= arrayLength(&gParticles)) {
+    return;
+  }
+
+  if (particleType(index) != bodyTypeNone) {
+    atomicMax(&gExtent.end, index + 1);
+  }
+}
+
 fn addFreeID(freeIdx : u32) -> bool {
   let capacity = arrayLength(&gFreeIDs.elements);
 
@@ -333,18 +324,12 @@ fn particleHit(index : u32) -> bool {
   return (flags & particleFlagHit) != 0;
 }
 
-fn makeParticleMetadata(bodyType : u32, team : u32) -> u32 {
-  return (bodyType << 8) | team;
-}
-
 fn particleType(index : u32) -> u32 {
-  let metadata = gParticles[index].metadata;
-  return (metadata >> 8) & 0xff;
+  return metadataBodyType(gParticles[index].metadata);
 }
 
 fn particleTeam(index : u32) -> u32 {
-  let metadata = gParticles[index].metadata;
-  return metadata & 0xff;
+  return metadataTeam(gParticles[index].metadata);
 }
 
 fn flock(selfIdx : u32) -> Acceleration {
@@ -0,0 +1,129 @@
+package battle
+
+import (
+	"maps"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpucpu"
+	"github.com/hulkholden/gowebgpu/client/examples/battle/sim"
+)
+
+// simKernels implements the entry points of compute.wgsl with the passes of sim.Sim, so the simulation can run
+// on a gpucpu.Device. The passes don't update the contacts and free IDs atomically, so the device must have a
+// single worker, which also runs the invocations in the same order as sim.Sim.Step.
+var simKernels = newKernels()
+
+func newKernels() gpucpu.Kernels {
+	kernels := gpucpu.Kernels{
+		"computeAcceleration":    particleKernel((*sim.Sim).ComputeAcceleration),
+		"applyAcceleration":      particleKernel((*sim.Sim).ApplyAcceleration),
+		"computeCollisions":      particleKernel((*sim.Sim).ComputeCollisions),
+		"applyCollisions":        func(inv gpucpu.Invocation) { bindSim(inv).ApplyCollisions(inv.GlobalID[0]) },
+		"updateMissileLifecycle": particleKernel((*sim.Sim).UpdateMissileLifecycle),
+		"selectTargets":          particleKernel((*sim.Sim).SelectTargets),
+		"spawnMissiles":          particleKernel((*sim.Sim).SpawnMissiles),
+		"findParticleExtent":     particleKernel((*sim.Sim).FindParticleExtent),
+	}
+	// applyCollisions is dispatched by a counted pass, and the draws' instance counts are set from the extent,
+	// both of which run the engine's shaders.
+	maps.Copy(kernels, gpucpu.CounterKernels)
+	return kernels
+}
+
+// particleKernel returns a kernel which runs pass for the particle at the invocation's global ID.
+func particleKernel(pass func(s *sim.Sim, index uint32)) gpucpu.Kernel {
+	return func(inv gpucpu.Invocation) {
+		s := bindSim(inv)
+		// Out of bounds writes are discarded in WGSL.
+		if index := inv.GlobalID[0]; index < uint32(len(s.Particles)) {
+			pass(s, index)
+		}
+	}
+}
+
+// bindSim returns a sim.Sim whose state is the buffers bound by compute.wgsl.
+func bindSim(inv gpucpu.Invocation) *sim.Sim {
+	b := inv.Bindings
+	return &sim.Sim{
+		Params:        *gpucpu.Ptr[sim.SimParams](b, 0, 0),
+		ProNavGain:    float32(inv.Overrides["proNavGain"]),
+		Bodies:        gpucpu.Slice[sim.Body](b, 0, 1),
+		Particles:     gpucpu.Slice[sim.Particle](b, 0, 2),
+		Ships:         gpucpu.Slice[sim.Ship](b, 0, 3),
+		Missiles:      gpucpu.Slice[sim.Missile](b, 0, 4),
+		Accelerations: gpucpu.Slice[sim.Acceleration](b, 0, 5),
+		Contacts:      gpucpu.Ptr[sim.ContactsContainer](b, 0, 6),
+		FreeIDs:       gpucpu.Ptr[sim.FreeIDsContainer](b, 0, 7),
+		Extent:        gpucpu.Ptr[sim.ParticleExtent](b, 0, 8),
+	}
+}
+
+// Small enough for the O(n²) passes to run quickly, but with plenty of missiles spawned and recycled.
+const (
+	testShipCount     = 200
+	testParticleCount = 400
+
+	// testFrames is long enough for the first missiles to expire.
+	testFrames = 1000
+)
+
+// simOpt compares sim.Sims.
+var simOpt = cmp.AllowUnexported(sim.SimParams{}, sim.Body{}, sim.Particle{}, sim.Ship{}, sim.Missile{}, sim.Acceleration{}, sim.Contact{}, sim.ContactsContainer{}, sim.FreeIDsContainer{}, sim.ParticleExtent{})
+
+// TestSetupPlumbing runs sim.Sim's own passes as gpucpu kernels through setup, and checks they produce the same
+// state as sim.Sim.Step. This checks the buffers setup binds, its dispatch sizes and its counted pass. It doesn't
+// check compute.wgsl, as the kernels and sim.Sim run the same Go code: nothing compares the shader's results
+// with sim.Sim's.
+func TestSetupPlumbing(t *testing.T) {
+	const seed = 1
+	s := sim.New(rand.New(rand.NewSource(seed)), testShipCount, testParticleCount)
+
+	device := gpucpu.NewDevice(simKernels)
+	device.Workers = 1
+	buffers := initSimBuffers(device, rand.New(rand.NewSource(seed)), testShipCount, testParticleCount)
+	update, err := setup(device, gpucpu.CanvasContext{}, buffers)
+	if err != nil {
+		t.Fatalf("setup() = %v", err)
+	}
+	pool := engine.NewStagingPool(device)
+	// The buffers the passes update are read into the same slices each time.
+	contacts, freeIDs, extent := make([]sim.ContactsContainer, 1), make([]sim.FreeIDsContainer, 1), make([]sim.ParticleExtent, 1)
+	got := &sim.Sim{
+		ProNavGain:    s.ProNavGain,
+		Bodies:        make([]sim.Body, buffers.bodies.Len()),
+		Particles:     make([]sim.Particle, buffers.particles.Len()),
+		Ships:         make([]sim.Ship, buffers.ships.Len()),
+		Missiles:      make([]sim.Missile, buffers.missiles.Len()),
+		Accelerations: make([]sim.Acceleration, buffers.accelerations.Len()),
+		Contacts:      &contacts[0],
+		FreeIDs:       &freeIDs[0],
+		Extent:        &extent[0],
+	}
+
+	for frame := 0; frame < testFrames; frame++ {
+		s.Step()
+		update()
+		// Comparing is much slower than stepping, and once the states diverge they stay different.
+		if frame%100 != 0 && frame != testFrames-1 {
+			continue
+		}
+
+		// The parameters are written by update rather than the passes, so only the buffers the passes update
+		// are compared.
+		got.Params = s.Params
+		<-buffers.bodies.ReadInto(pool, 0, got.Bodies)
+		<-buffers.particles.ReadInto(pool, 0, got.Particles)
+		<-buffers.ships.ReadInto(pool, 0, got.Ships)
+		<-buffers.missiles.ReadInto(pool, 0, got.Missiles)
+		<-buffers.accelerations.ReadInto(pool, 0, got.Accelerations)
+		<-buffers.contacts.ReadInto(pool, 0, contacts)
+		<-buffers.freeIDs.ReadInto(pool, 0, freeIDs)
+		<-buffers.extent.ReadInto(pool, 0, extent)
+		if diff := cmp.Diff(s, got, simOpt); diff != "" {
+			t.Fatalf("frame %d: state diff (-sim +gpucpu):\n%s", frame, diff)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package battle
+
+import (
+	"maps"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+	"github.com/hulkholden/gowebgpu/common/wgsltest"
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+func TestShaders(t *testing.T) {
+	// Each shader is given the structs of the buffers setup binds to it.
+	buffers := initSimBuffers(gpufake.NewDevice(), rand.New(rand.NewSource(1)), 0, 1)
+	computeBuffers := buffers.computeBuffers()
+	var computeStructs []wgsltypes.Struct
+	for _, name := range slices.Sorted(maps.Keys(computeBuffers)) {
+		computeStructs = append(computeStructs, computeBuffers[name].StructDefs()...)
+	}
+	var renderStructs []wgsltypes.Struct
+	for _, b := range buffers.renderBuffers() {
+		renderStructs = append(renderStructs, b.StructDefs()...)
+	}
+
+	tests := []struct {
+		name    string
+		structs []wgsltypes.Struct
+	}{
+		{
+			name:    "compute.wgsl",
+			structs: computeStructs,
+		},
+		{
+			name:    "render.wgsl",
+			structs: renderStructs,
+		},
+	}
+	p := wgsl.Preprocessor{Load: wgsl.FSLoader(shaderFS)}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src, err := p.ProcessFile(tc.name)
+			if err != nil {
+				t.Fatalf("ProcessFile() = %v", err)
+			}
+			for _, d := range wgsl.Check(wgsl.ModuleSource(src, tc.structs, shaderConsts)) {
+				t.Error(d)
+			}
+			wgsltest.CheckPrologue(t, tc.name, wgsltypes.Prologue(tc.structs, shaderConsts))
+		})
+	}
+}
@@ -0,0 +1,523 @@
+// Package sim is a Go implementation of the battle example's simulation, which runs on the GPU in compute.wgsl.
+//
+// Nothing checks compute.wgsl against it, so the two must be kept in step by hand: update this package alongside
+// the shader when changing the simulation's behaviour.
+package sim
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/hulkholden/gowebgpu/common/math32"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+	"github.com/mroth/weightedrand/v2"
+)
+
+// DefaultProNavGain is the default navigation constant for missile proportional navigation, which is the
+// proNavGain override in compute.wgsl.
+const DefaultProNavGain = 3.0
+
+// A Sim holds the state of the simulation, which is the contents of the buffers bound by compute.wgsl, and
+// implements its passes.
+//
+// Each pass runs over the particles in index order. Where the shader's invocations race (adding
+// contacts and free IDs, and SpawnMissiles reusing particles) that's one of the orders the GPU could
+// produce, so the results are deterministic.
+type Sim struct {
+	Params     SimParams
+	ProNavGain float32
+
+	Bodies        []Body
+	Particles     []Particle
+	Ships         []Ship
+	Missiles      []Missile
+	Accelerations []Acceleration
+	Contacts      *ContactsContainer
+	FreeIDs       *FreeIDsContainer
+	Extent        *ParticleExtent
+}
+
+// New returns a simulation with the default parameters and room for maxParticles particles, the first numShips
+// of which are initialized from r.
+func New(r *rand.Rand, numShips, maxParticles int) *Sim {
+	params := DefaultParams()
+	bodies, particles, ships, missiles, freeIDs := initParticleData(r, numShips, maxParticles, params)
+	return &Sim{
+		Params:        params,
+		ProNavGain:    DefaultProNavGain,
+		Bodies:        bodies,
+		Particles:     particles,
+		Ships:         ships,
+		Missiles:      missiles,
+		Accelerations: make([]Acceleration, maxParticles),
+		Contacts:      &ContactsContainer{},
+		FreeIDs:       &freeIDs,
+		Extent:        &ParticleExtent{},
+	}
+}
+
+// Step advances the simulation by one frame, running the passes in the same order as the battle example.
+func (s *Sim) Step() {
+	s.Params.Advance()
+	*s.Contacts = ContactsContainer{}
+	*s.Extent = ParticleExtent{}
+
+	s.forEachParticle(s.ComputeAcceleration)
+	s.forEachParticle(s.ApplyAcceleration)
+	s.forEachParticle(s.ComputeCollisions)
+	s.forEachContact(s.ApplyCollisions)
+	s.forEachParticle(s.UpdateMissileLifecycle)
+	s.forEachParticle(s.SelectTargets)
+	s.forEachParticle(s.SpawnMissiles)
+	s.forEachParticle(s.FindParticleExtent)
+}
+
+func (s *Sim) forEachParticle(fn func(index uint32)) {
+	for i := range s.Particles {
+		fn(uint32(i))
+	}
+}
+
+func (s *Sim) forEachContact(fn func(contactIdx uint32)) {
+	for i := range s.contactCount() {
+		fn(i)
+	}
+}
+
+func (s *Sim) contactCount() uint32 {
+	return min(s.Contacts.count, uint32(len(s.Contacts.elements)))
+}
+
+// ComputeAcceleration runs compute.wgsl's computeAcceleration entry point for the particle at index.
+func (s *Sim) ComputeAcceleration(index uint32) {
+	var acc Acceleration
+	switch s.Particles[index].BodyType() {
+	case BodyTypeShip:
+		acc = s.flock(index)
+	case BodyTypeMissile:
+		if m := s.Missiles[index]; m.targetIdx >= 0 {
+			acc = s.updateMissile(index, uint32(m.targetIdx))
+		}
+	}
+	s.Accelerations[index] = acc
+}
+
+// ApplyAcceleration runs compute.wgsl's applyAcceleration entry point for the particle at index.
+func (s *Sim) ApplyAcceleration(index uint32) {
+	p := s.Params
+	body := s.Bodies[index]
+	acc := s.Accelerations[index]
+
+	body.vel = body.vel.Add(acc.linearAcc.Scale(p.deltaT))
+	body.pos = body.pos.Add(body.vel.Scale(p.deltaT))
+
+	body.angularVel += acc.angularAcc * p.deltaT
+	body.angle = normalizeAngle(body.angle + body.angularVel*p.deltaT)
+
+	if s.Particles[index].BodyType() == BodyTypeShip {
+		// Bounce off the boundary.
+		body.pos.X, body.vel.X = bounce(body.pos.X, body.vel.X, p.minBound.X, p.maxBound.X, p.boundaryBounceFactor)
+		body.pos.Y, body.vel.Y = bounce(body.pos.Y, body.vel.Y, p.minBound.Y, p.maxBound.Y, p.boundaryBounceFactor)
+
+		// clamp velocity for a more pleasing simulation
+		dir, speed := body.vel.Normal()
+		body.vel = dir.Scale(math32.Clamp(speed, 0, p.maxShipSpeed))
+	}
+
+	s.Bodies[index] = body
+}
+
+// bounce reflects vel if pos is outside [lo, hi] and moving further out, then clamps pos to the range.
+func bounce(pos, vel, lo, hi, factor float32) (float32, float32) {
+	if (pos < lo && vel < 0) || (pos > hi && vel > 0) {
+		vel = -vel * factor
+	}
+	return math32.Clamp(pos, lo, hi), vel
+}
+
+// ComputeCollisions runs compute.wgsl's computeCollisions entry point for the particle at index.
+func (s *Sim) ComputeCollisions(index uint32) {
+	if s.Particles[index].BodyType() != BodyTypeMissile {
+		return
+	}
+	targetIdx := s.Missiles[index].targetIdx
+	if targetIdx < 0 {
+		return
+	}
+	if s.Bodies[index].pos.Distance(s.Bodies[targetIdx].pos) < s.Params.missileCollisionDist {
+		s.addContact(index, uint32(targetIdx))
+	}
+}
+
+func (s *Sim) addContact(aIdx, bIdx uint32) {
+	contactIdx := s.Contacts.count
+	s.Contacts.count++
+	if contactIdx < uint32(len(s.Contacts.elements)) {
+		s.Contacts.elements[contactIdx] = Contact{aIdx: aIdx, bIdx: bIdx}
+	}
+}
+
+// ApplyCollisions runs compute.wgsl's applyCollisions entry point for the contact at contactIdx.
+func (s *Sim) ApplyCollisions(contactIdx uint32) {
+	if contactIdx >= s.contactCount() {
+		return
+	}
+	c := s.Contacts.elements[contactIdx]
+	s.Particles[c.aIdx].flags |= ParticleFlagHit
+	s.Particles[c.bIdx].flags |= ParticleFlagHit
+}
+
+// UpdateMissileLifecycle runs compute.wgsl's updateMissileLifecycle entry point for the particle at index.
+func (s *Sim) UpdateMissileLifecycle(index uint32) {
+	if s.Particles[index].flags&ParticleFlagHit != 0 {
+		s.killParticle(index)
+		return
+	}
+	if s.Particles[index].BodyType() == BodyTypeMissile {
+		s.Missiles[index].age += s.Params.deltaT
+		if s.Missiles[index].age > s.Params.maxMissileAge {
+			s.killParticle(index)
+		}
+	}
+}
+
+// SelectTargets runs compute.wgsl's selectTargets entry point for the particle at index.
+func (s *Sim) SelectTargets(index uint32) {
+	if s.Particles[index].BodyType() != BodyTypeShip {
+		return
+	}
+	if s.Params.time >= s.Ships[index].nextShotTime {
+		s.Ships[index].targetIdx = s.findTarget(index)
+	}
+}
+
+// SpawnMissiles runs compute.wgsl's spawnMissiles entry point for the particle at index.
+func (s *Sim) SpawnMissiles(index uint32) {
+	if s.Particles[index].BodyType() != BodyTypeShip {
+		return
+	}
+	ship := s.Ships[index]
+	if s.Params.time < ship.nextShotTime || ship.targetIdx < 0 {
+		return
+	}
+	mIdx, ok := s.getFreeID()
+	if !ok {
+		return
+	}
+	s.Bodies[mIdx] = s.Bodies[index]
+	s.Particles[mIdx].metadata = makeMeta(BodyTypeMissile, s.Particles[index].Team())
+	s.Particles[mIdx].flags = 0
+	s.Particles[mIdx].col = vmath.Unorm8x4{X: 0xff, Y: 0xff, Z: 0xff, W: 0xff}
+	s.Missiles[mIdx].age = 0
+	s.Missiles[mIdx].targetIdx = ship.targetIdx
+
+	s.Ships[index].nextShotTime = s.Params.time + s.Params.shipShotCooldown
+	s.Ships[index].targetIdx = -1
+}
+
+// FindParticleExtent runs compute.wgsl's findParticleExtent entry point for the particle at index.
+func (s *Sim) FindParticleExtent(index uint32) {
+	if s.Particles[index].BodyType() != BodyTypeNone {
+		s.Extent.end = max(s.Extent.end, index+1)
+	}
+}
+
+func (s *Sim) addFreeID(freeIdx uint32) bool {
+	if s.FreeIDs.count >= uint32(len(s.FreeIDs.elements)) {
+		return false
+	}
+	s.FreeIDs.elements[s.FreeIDs.count] = freeIdx
+	s.FreeIDs.count++
+	return true
+}
+
+func (s *Sim) getFreeID() (uint32, bool) {
+	if s.FreeIDs.count == 0 {
+		return 0, false
+	}
+	s.FreeIDs.count--
+	return s.FreeIDs.elements[s.FreeIDs.count], true
+}
+
+func (s *Sim) killParticle(index uint32) {
+	s.Bodies[index] = randomizeBody(s.Bodies[index])
+	s.Particles[index] = Particle{}
+	s.Ships[index] = Ship{}
+	s.Missiles[index] = Missile{targetIdx: -1}
+	s.addFreeID(index)
+}
+
+// randomizeBody returns a stationary body at a position hashed from b's.
+func randomizeBody(b Body) Body {
+	r := rand22(b.pos)
+	return Body{pos: vmath.NewV2(2*(r.X-0.5)*1000, 2*(r.Y-0.5)*1000)}
+}
+
+func (s *Sim) findTarget(selfIdx uint32) int32 {
+	selfTeam := s.Particles[selfIdx].Team()
+	if s.Particles[selfIdx].BodyType() != BodyTypeShip {
+		return -1
+	}
+
+	pos := s.Bodies[selfIdx].pos
+	closestIdx := int32(-1)
+	var closestDist float32
+	for otherIdx := range s.Bodies {
+		other := s.Particles[otherIdx]
+		if uint32(otherIdx) == selfIdx || other.Team() == selfTeam || other.BodyType() != BodyTypeShip {
+			continue
+		}
+		dist := pos.Distance(s.Bodies[otherIdx].pos)
+		if closestIdx < 0 || dist < closestDist {
+			closestDist = dist
+			closestIdx = int32(otherIdx)
+		}
+	}
+	return closestIdx
+}
+
+func (s *Sim) flock(selfIdx uint32) Acceleration {
+	p := s.Params
+	var cMass, cVel, colVel vmath.V2
+	var cMassCount, cVelCount int
+
+	selfTeam := s.Particles[selfIdx].Team()
+	current := s.Bodies[selfIdx]
+	for otherIdx, other := range s.Bodies {
+		if uint32(otherIdx) == selfIdx || s.Particles[otherIdx].BodyType() != BodyTypeShip {
+			continue
+		}
+		dPos := other.pos.Sub(current.pos)
+		dist := dPos.Length()
+		if dist < p.avoidDistance {
+			colVel = colVel.Sub(dPos)
+		}
+		if s.Particles[otherIdx].Team() == selfTeam {
+			if dist < p.cMassDistance {
+				cMass = cMass.Add(other.pos)
+				cMassCount++
+			}
+			if dist < p.cVelDistance {
+				cVel = cVel.Add(other.vel)
+				cVelCount++
+			}
+		}
+	}
+	if cMassCount > 0 {
+		cMass = cMass.Scale(1 / float32(cMassCount)).Sub(current.pos)
+	}
+	if cVelCount > 0 {
+		cVel = cVel.Scale(1 / float32(cVelCount))
+	}
+
+	dVel := colVel.Scale(p.avoidScale).Add(cMass.Scale(p.cMassScale)).Add(cVel.Scale(p.cVelScale))
+	// Turn to face along the velocity vector.
+	desired := Body{pos: current.pos, vel: current.vel, angle: angleOf(current.vel, current.angle)}
+	rel := bodySub(desired, current)
+	return Acceleration{
+		linearAcc:  dVel.Scale(1 / p.deltaT),
+		angularAcc: s.computeTurnAcceleration(rel.angle, rel.angularVel),
+	}
+}
+
+func (s *Sim) updateMissile(selfIdx, targetIdx uint32) Acceleration {
+	p := s.Params
+	current := s.Bodies[selfIdx]
+	target := s.Bodies[targetIdx]
+
+	// The shader clamps the desired distance from the target to zero, so the desired position is the target's.
+	desired := Body{pos: target.pos, vel: target.vel, angle: angleOf(current.vel, current.angle)}
+	rel := bodySub(desired, current)
+	// Transform into the missile's coordinate system.
+	localRel := bodyRotate(rel, -current.angle)
+
+	var localLinAcc vmath.V2
+	// Apply proportional navigation to track towards the target.
+	localLinAcc.X = proNav2D(s.ProNavGain, localRel.pos, localRel.vel)
+	// Accelerate forward as fast as possible while staying under maxMissileSpeed (with respect to target).
+	if p.maxMissileSpeed == 0 {
+		localLinAcc.Y = p.maxMissileAcc
+	} else {
+		// Relative velocity is negative as we're closing on the target.
+		if speed := -localRel.vel.Y; speed < p.maxMissileSpeed {
+			localLinAcc.Y = math32.Min((p.maxMissileSpeed-speed)/p.deltaT, p.maxMissileAcc)
+		}
+	}
+
+	// Limit acceleration
+	if l := localLinAcc.Length(); l > p.maxMissileAcc {
+		localLinAcc = localLinAcc.Scale(p.maxMissileAcc / l)
+	}
+
+	return Acceleration{
+		linearAcc:  localLinAcc.Rotate(current.angle),
+		angularAcc: s.computeTurnAcceleration(rel.angle, rel.angularVel),
+	}
+}
+
+// proNav2D is a version of https://en.wikipedia.org/wiki/Proportional_navigation simplified for 2D.
+func proNav2D(gain float32, r, v vmath.V2) float32 {
+	return -gain * r.Cross(v) * v.Length() / r.Dot(r)
+}
+
+func (s *Sim) computeTurnAcceleration(relAng, relAngVel float32) float32 {
+	maxAcc := s.Params.maxMissileAngAcc
+	// Compute the maximum velocity we can turn at and still stop in time.
+	// Given v^2 = u^2 + 2as, assuming v=0 then u = sqrt(-2as).
+	// The most we can accelerate in this frame is (sqrt(2as)-u)/t.
+	absAngDiff, angSign := math32.MagnitudeAndSign(relAng)
+	maxBrakingVel := math32.Sqrt(2*maxAcc*absAngDiff) * angSign
+	return math32.Clamp((maxBrakingVel+relAngVel)/s.Params.deltaT, -maxAcc, maxAcc)
+}
+
+func bodySub(a, b Body) Body {
+	return Body{
+		pos:        a.pos.Sub(b.pos),
+		vel:        a.vel.Sub(b.vel),
+		angle:      normalizeAngle(a.angle - b.angle),
+		angularVel: a.angularVel - b.angularVel,
+	}
+}
+
+func bodyRotate(a Body, angle float32) Body {
+	return Body{
+		pos:        a.pos.Rotate(angle),
+		vel:        a.vel.Rotate(angle),
+		angle:      a.angle + angle,
+		angularVel: a.angularVel,
+	}
+}
+
+func angleOf(v vmath.V2, def float32) float32 {
+	if v.Length() > 0 {
+		return v.ToAngle()
+	}
+	return def
+}
+
+// normalizeAngle matches the shader's normalizeAngle, which uses a floored modulo.
+func normalizeAngle(a float32) float32 {
+	n := a + math32.Pi
+	n -= math32.TwoPi * math32.Floor(n/math32.TwoPi)
+	if n < 0 {
+		n += math32.TwoPi
+	}
+	return n - math32.Pi
+}
+
+// hash22 is the shader's PCG-style hash: https://gist.github.com/munrocket/236ed5ba7e409b8bdf1ff6eca5dcdc39
+func hash22(x, y uint32) (uint32, uint32) {
+	x = x*1664525 + 1013904223
+	y = y*1664525 + 1013904223
+	x += y * 1664525
+	y += x * 1664525
+	x ^= x >> 16
+	y ^= y >> 16
+	x += y * 1664525
+	y += x * 1664525
+	x ^= x >> 16
+	y ^= y >> 16
+	return x, y
+}
+
+func rand22(f vmath.V2) vmath.V2 {
+	x, y := hash22(math.Float32bits(f.X), math.Float32bits(f.Y))
+	return vmath.NewV2(float32(x)/float32(math.MaxUint32), float32(y)/float32(math.MaxUint32))
+}
+
+// DefaultParams returns the parameters the simulation starts with.
+func DefaultParams() SimParams {
+	return SimParams{
+		// TODO: get from the canvas
+		minBound: vmath.NewV2(-1000, -1000),
+		maxBound: vmath.NewV2(+1000, +1000),
+
+		deltaT: 1 / 50.0,
+
+		avoidDistance: 25.0,
+		cMassDistance: 100,
+		cVelDistance:  25.0,
+
+		cMassScale: 0.02,
+		avoidScale: 0.05,
+		cVelScale:  0.005,
+
+		maxMissileAge:        10.0,
+		missileCollisionDist: 10.0,
+
+		maxShipSpeed:     100.0,
+		shipShotCooldown: shipShotCooldown,
+
+		maxMissileSpeed:  150.0,
+		maxMissileAcc:    150.0,
+		maxMissileAngAcc: 16.0,
+
+		boundaryBounceFactor: 0.95,
+	}
+}
+
+// initParticleData returns the initial contents of the particle buffers, using r for all random values.
+func initParticleData(r *rand.Rand, numShips, maxParticles int, params SimParams) ([]Body, []Particle, []Ship, []Missile, FreeIDsContainer) {
+	type particleChoice struct {
+		bodyType BodyType
+		team     Team
+	}
+
+	chooser, _ := weightedrand.NewChooser(
+		weightedrand.NewChoice(particleChoice{BodyTypeShip, 0}, 100),
+		weightedrand.NewChoice(particleChoice{BodyTypeShip, 1}, 100),
+		weightedrand.NewChoice(particleChoice{BodyTypeMissile, 0}, 5),
+		weightedrand.NewChoice(particleChoice{BodyTypeMissile, 1}, 5),
+		// TODO: figure out a better way to represent anti-missile missiles.
+		// weightedrand.NewChoice(particleChoice{BodyTypeMissile, 0}, 1),
+	)
+
+	bs := make([]Body, maxParticles)
+	ps := make([]Particle, maxParticles)
+	ss := make([]Ship, maxParticles)
+	ms := make([]Missile, maxParticles)
+	fids := FreeIDsContainer{}
+	for i := 0; i < numShips; i++ {
+		bs[i].pos = randomLocation(r, params)
+		bs[i].vel = randomVelocity(r)
+		bs[i].angle = 2 * (r.Float32() - 0.5) * 3.141
+		bs[i].angularVel = (r.Float32() - 0.5) * 1
+
+		choice := chooser.PickSource(r)
+
+		// For debugging single missile.
+		// if i == 0 {
+		// 	data[0].pos = vmath.NewV2(0, 0)
+		// 	choice.bodyType = BodyTypeShip
+		// 	choice.team = 0
+		// } else {
+		// 	choice.bodyType = BodyTypeMissile
+		// 	choice.team = 1
+		// 	data[i].vel = vmath.NewV2(0, 0)
+		// }
+
+		ps[i].metadata = makeMeta(choice.bodyType, choice.team)
+		ps[i].col = choice.team.Color().Unorm8x4()
+		ms[i].targetIdx = -1
+
+		ss[i].nextShotTime = r.Float32() * params.shipShotCooldown
+		ss[i].targetIdx = -1
+	}
+	fids.count = uint32(maxParticles - numShips)
+	for i := numShips; i < maxParticles; i++ {
+		fids.elements[i-numShips] = uint32(i)
+	}
+	return bs, ps, ss, ms, fids
+}
+
+func randomLocation(r *rand.Rand, params SimParams) vmath.V2 {
+	x := (r.Float32() * (params.maxBound.X - params.minBound.X)) + params.minBound.X
+	y := (r.Float32() * (params.maxBound.Y - params.minBound.Y)) + params.minBound.Y
+	return vmath.NewV2(x, y)
+}
+
+func randomVelocity(r *rand.Rand) vmath.V2 {
+	x := 2 * (r.Float32() - 0.5) * initialVelScale
+	y := 2 * (r.Float32() - 0.5) * initialVelScale
+	return vmath.NewV2(x, y)
+}
@@ -0,0 +1,158 @@
+package sim
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+)
+
+// Small enough for the O(n²) passes to run quickly, but with plenty of missiles spawned and recycled.
+const (
+	testShipCount     = 200
+	testParticleCount = 400
+)
+
+// testFrames is long enough for the first missiles to expire.
+var testFrames = int(2 * DefaultParams().maxMissileAge / DefaultParams().deltaT)
+
+func TestInvariants(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			s := New(rand.New(rand.NewSource(seed)), testShipCount, testParticleCount)
+			spawned := 0
+			for frame := 0; frame < testFrames; frame++ {
+				freeBefore := s.FreeIDs.count
+				s.Step()
+				if s.FreeIDs.count < freeBefore {
+					spawned += int(freeBefore - s.FreeIDs.count)
+				}
+				if errs := s.checkInvariants(); len(errs) > 0 {
+					t.Fatalf("frame %d:\n%s", frame, errs)
+				}
+			}
+			if spawned == 0 {
+				t.Errorf("no missiles were spawned")
+			}
+		})
+	}
+}
+
+// simOpt compares Sims.
+var simOpt = cmp.AllowUnexported(SimParams{}, Body{}, Particle{}, Ship{}, Missile{}, Acceleration{}, Contact{}, ContactsContainer{}, FreeIDsContainer{}, ParticleExtent{})
+
+func TestDeterministic(t *testing.T) {
+	const frames = 50
+	run := func(seed int64) *Sim {
+		s := New(rand.New(rand.NewSource(seed)), testShipCount, testParticleCount)
+		for i := 0; i < frames; i++ {
+			s.Step()
+		}
+		return s
+	}
+
+	opt := simOpt
+	if diff := cmp.Diff(run(1), run(1), opt); diff != "" {
+		t.Errorf("same seed diff (-first +second):\n%s", diff)
+	}
+	if cmp.Equal(run(1), run(2), opt) {
+		t.Errorf("different seeds produced the same state")
+	}
+}
+
+func TestMissileExpiry(t *testing.T) {
+	s := New(rand.New(rand.NewSource(1)), 0, 2)
+	launchMissile(s, 0, vmath.V2{}, -1)
+
+	// Step time forward the same way as the simulation to find the frame the age first exceeds the limit.
+	wantFrame := 0
+	for age := float32(0); age <= s.Params.maxMissileAge; age += s.Params.deltaT {
+		wantFrame++
+	}
+
+	for frame := 1; frame <= wantFrame; frame++ {
+		s.Step()
+		alive := s.Particles[0].BodyType() == BodyTypeMissile
+		if wantAlive := frame < wantFrame; alive != wantAlive {
+			t.Fatalf("frame %d: missile alive = %t, want %t (age %v, maxMissileAge %v)", frame, alive, wantAlive, s.Missiles[0].age, s.Params.maxMissileAge)
+		}
+	}
+	if diff := cmp.Diff([]uint32{1, 0}, s.FreeIDs.elements[:s.FreeIDs.count]); diff != "" {
+		t.Errorf("free IDs diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestMissileHitsTarget(t *testing.T) {
+	s := New(rand.New(rand.NewSource(1)), 0, 3)
+	s.Ships[1].nextShotTime = 1e9
+	s.Bodies[1].vel = vmath.NewV2(50, 0)
+	s.Particles[1].metadata = makeMeta(BodyTypeShip, 1)
+	takeFreeID(s, 1)
+	launchMissile(s, 0, vmath.NewV2(-200, -300), 1)
+
+	for frame := 0; frame < testFrames && s.Particles[0].BodyType() == BodyTypeMissile; frame++ {
+		s.Step()
+		if errs := s.checkInvariants(); len(errs) > 0 {
+			t.Fatalf("frame %d:\n%s", frame, errs)
+		}
+	}
+	if got := s.Particles[1].BodyType(); got != BodyTypeNone {
+		t.Errorf("target type = %d, want destroyed", got)
+	}
+	if s.Missiles[0].age >= s.Params.maxMissileAge {
+		t.Errorf("missile expired instead of hitting its target")
+	}
+}
+
+// launchMissile turns the free particle idx into a stationary missile at pos, chasing targetIdx.
+func launchMissile(s *Sim, idx uint32, pos vmath.V2, targetIdx int32) {
+	takeFreeID(s, idx)
+	s.Bodies[idx] = Body{pos: pos}
+	s.Particles[idx].metadata = makeMeta(BodyTypeMissile, 0)
+	s.Missiles[idx] = Missile{targetIdx: targetIdx}
+}
+
+// takeFreeID removes idx from the free IDs.
+func takeFreeID(s *Sim, idx uint32) {
+	ids := slices.DeleteFunc(s.FreeIDs.elements[:s.FreeIDs.count], func(id uint32) bool { return id == idx })
+	s.FreeIDs.count = uint32(len(ids))
+}
+
+// checkInvariants returns a description of each way the state is inconsistent.
+func (s *Sim) checkInvariants() []string {
+	var errs []string
+	addErr := func(format string, args ...any) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	free := map[uint32]bool{}
+	for _, id := range s.FreeIDs.elements[:s.FreeIDs.count] {
+		free[id] = true
+	}
+	for i, p := range s.Particles {
+		bodyType := p.BodyType()
+		if bodyType == BodyTypeNone && !free[uint32(i)] {
+			addErr("particle %d has type %d, but isn't free", i, bodyType)
+		}
+		if bodyType == BodyTypeMissile && s.Missiles[i].age > s.Params.maxMissileAge {
+			addErr("missile %d has age %v > maxMissileAge %v", i, s.Missiles[i].age, s.Params.maxMissileAge)
+		}
+		// Only ships are kept inside the bounds.
+		if pos := s.Bodies[i].pos; bodyType == BodyTypeShip && !s.inBounds(pos) {
+			addErr("particle %d (type %d) at %v is out of bounds", i, bodyType, pos)
+		}
+		// Only the particles before the extent are drawn.
+		if bodyType != BodyTypeNone && uint32(i) >= s.Extent.end {
+			addErr("particle %d (type %d) is past the extent %d", i, bodyType, s.Extent.end)
+		}
+	}
+	return errs
+}
+
+func (s *Sim) inBounds(pos vmath.V2) bool {
+	p := s.Params
+	return pos.X >= p.minBound.X && pos.Y >= p.minBound.Y && pos.X <= p.maxBound.X && pos.Y <= p.maxBound.Y
+}
@@ -0,0 +1,162 @@
+package sim
+
+import "github.com/hulkholden/gowebgpu/common/vmath"
+
+const (
+	// MaxParticleCount is the most particles a simulation can have, as FreeIDsContainer has room for all of them.
+	MaxParticleCount = 4000
+	// MaxContactCount is the most contacts ContactsContainer can hold each frame.
+	MaxContactCount = 1024
+
+	maxFreeIDsCount = MaxParticleCount
+
+	initialVelScale = 100.0
+
+	shipShotCooldown = 5.0
+)
+
+type ARGB uint32
+
+const (
+	NiceRed    ARGB = 0xfffc0335
+	NiceBlue   ARGB = 0xff035efc
+	NiceOrange ARGB = 0xfffc8803
+	NicePurple ARGB = 0xff891cb8
+	Magenta    ARGB = 0xffff00ff
+)
+
+type SimParams struct {
+	minBound vmath.V2
+	maxBound vmath.V2
+
+	time   float32
+	deltaT float32
+
+	avoidDistance float32
+	cMassDistance float32
+	cVelDistance  float32
+	cMassScale    float32
+	avoidScale    float32
+	cVelScale     float32
+
+	maxMissileAge        float32
+	missileCollisionDist float32
+
+	// boundaryBounceFactor is the velocity preserved after colliding with the boundary.
+	boundaryBounceFactor float32
+
+	maxShipSpeed     float32
+	shipShotCooldown float32
+
+	maxMissileSpeed  float32
+	maxMissileAcc    float32
+	maxMissileAngAcc float32
+}
+
+// Advance advances the time by one frame.
+func (p *SimParams) Advance() {
+	p.time += p.deltaT
+}
+
+// Bounds returns the corners of the area the ships are kept inside.
+func (p SimParams) Bounds() (vmath.V2, vmath.V2) {
+	return p.minBound, p.maxBound
+}
+
+// ParticleFlagHit is set in a particle's flags when it collides, so it's destroyed by UpdateMissileLifecycle.
+const ParticleFlagHit uint32 = 1
+
+type Body struct {
+	pos        vmath.V2
+	vel        vmath.V2
+	angle      float32
+	angularVel float32
+}
+
+type Particle struct {
+	metadata uint32
+	flags    uint32
+	col      vmath.Unorm8x4
+	debugVal float32
+}
+
+type Ship struct {
+	nextShotTime float32
+	targetIdx    int32
+}
+
+type Missile struct {
+	// TODO: compress these down. Use 16 bits for each?
+	targetIdx int32
+	age       float32
+}
+
+func (p Particle) BodyType() BodyType {
+	return BodyType((p.metadata >> 8) & 0xff)
+}
+
+func (p Particle) Team() Team {
+	return Team(p.metadata & 0xff)
+}
+
+type Acceleration struct {
+	linearAcc  vmath.V2
+	angularAcc float32
+	pad        uint32
+}
+
+type Contact struct {
+	aIdx uint32
+	bIdx uint32
+}
+
+type ContactsContainer struct {
+	count    uint32 `atomic:"true"`
+	pad      uint32
+	elements [MaxContactCount]Contact `runtimeArray:"true"`
+}
+
+type FreeIDsContainer struct {
+	count    uint32 `atomic:"true"`
+	pad      uint32
+	elements [maxFreeIDsCount]uint32 `runtimeArray:"true"`
+}
+
+// ParticleExtent bounds the live particles, so the draws can skip the free slots after the last one.
+type ParticleExtent struct {
+	// end is one past the index of the last live particle.
+	end uint32 `atomic:"true"`
+}
+
+type Team uint8
+
+var teamColMap = map[Team]ARGB{
+	0: NiceRed,
+	1: NiceBlue,
+	2: NicePurple,
+	3: NiceOrange,
+}
+
+// Unorm8x4 returns the color as RGBA components.
+func (c ARGB) Unorm8x4() vmath.Unorm8x4 {
+	return vmath.Unorm8x4{X: uint8(c >> 16), Y: uint8(c >> 8), Z: uint8(c), W: uint8(c >> 24)}
+}
+
+func (t Team) Color() ARGB {
+	if col, ok := teamColMap[t]; ok {
+		return col
+	}
+	return Magenta
+}
+
+type BodyType uint8
+
+const (
+	BodyTypeNone BodyType = iota
+	BodyTypeShip
+	BodyTypeMissile
+)
+
+func makeMeta(bodyType BodyType, team Team) uint32 {
+	return uint32(bodyType)<<8 | uint32(team)
+}
@@ -1,13 +1,13 @@
 package boids
 
 import (
+	"fmt"
 	"math/rand"
 
 	"github.com/hulkholden/gowebgpu/client/engine"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
 	"github.com/hulkholden/gowebgpu/common/vmath"
 	"github.com/hulkholden/gowebgpu/common/wgsltypes"
-	"github.com/mokiat/gog/opt"
-	"github.com/mokiat/wasmgpu"
 
 	_ "embed"
 )
@@ -36,7 +36,6 @@ type Vertex struct {
 var (
 	simParamsStruct = wgsltypes.MustRegisterStruct[SimParams]()
 	particleStruct  = wgsltypes.MustRegisterStruct[Particle]()
-	vertexStruct    = wgsltypes.MustRegisterStruct[Vertex]()
 )
 
 //go:embed compute.wgsl
@@ -46,146 +45,91 @@ var computeShaderCode string
 var renderShaderCode string
 
 // https://webgpu.github.io/webgpu-samples/samples/computeBoids
-func Run(device wasmgpu.GPUDevice, context wasmgpu.GPUCanvasContext) error {
-	simParams := SimParams{
-		deltaT:        0.04,
-		avoidDistance: 0.025,
-		cMassDistance: 0.1,
-		cVelDistance:  0.025,
-		avoidScale:    0.05,
-		cMassScale:    0.02,
-		cVelScale:     0.005,
+func Run(device gpu.Device, context gpu.CanvasContext) error {
+	sim, err := setup(device, context, initParticleData(numParticles))
+	if err != nil {
+		return err
 	}
+	engine.InitRenderCallback(sim.update)
+	return nil
+}
+
+// A simulation holds the resources created by setup.
+type simulation struct {
+	// update advances the simulation by a frame and renders it.
+	update func()
+	// particles holds the particles, with the latest frame in the front copy.
+	particles engine.PingPongBuffer[Particle]
+}
+
+// setup creates the resources for simulating the particles.
+func setup(device gpu.Device, context gpu.CanvasContext, particles []Particle) (*simulation, error) {
+	simParams := defaultSimParams()
 	simParamBuffer := engine.InitUniformBuffer(device, simParams)
 	// TODO: add sim params to GUI.
 
 	const boidScale = 0.5
-	vertexBufferData := []float32{
-		-0.01 * boidScale, -0.02 * boidScale,
-		0.01 * boidScale, -0.02 * boidScale,
-		0.0 * boidScale, 0.02 * boidScale,
+	vertexBufferData := []Vertex{
+		{pos: vmath.NewV2(-0.01*boidScale, -0.02*boidScale)},
+		{pos: vmath.NewV2(0.01*boidScale, -0.02*boidScale)},
+		{pos: vmath.NewV2(0.0*boidScale, 0.02*boidScale)},
 	}
 	spriteVertexBuffer := engine.InitStorageBufferSlice(device, vertexBufferData, engine.WithVertexUsage())
 
-	initialParticleData := initParticleData(numParticles)
-	particleBuffers := []engine.GPUBuffer[Particle]{
-		engine.InitStorageBufferSlice(device, initialParticleData, engine.WithVertexUsage()),
-		engine.InitStorageBufferSlice(device, initialParticleData, engine.WithVertexUsage()),
-	}
+	// The compute pass reads the particles from the front buffer and writes them to the back buffer,
+	// which becomes the front buffer for rendering.
+	particleBuffer := engine.InitPingPongBufferSlice(device, 2, particles, engine.WithVertexUsage())
 
-	// TODO: Figure out a nice way to retreive these from VertexBuffers.
-	const particleBufferIdx = 0
-	const vertexBufferIdx = 1
+	vertexBuffers := engine.NewVertexBuffersFrom(
+		engine.NewPingPongInstanceBuffer(particleBuffer, "pos", "vel"),
+		engine.NewVertexBuffer(spriteVertexBuffer, "pos"),
+	)
 
-	bufDefs := []engine.BufferDescriptor{
-		{Struct: &particleStruct, Instanced: true},
-		{Struct: &vertexStruct},
-	}
-	vtxAttrs := []engine.VertexAttribute{
-		{BufferIndex: particleBufferIdx, FieldName: "pos"},
-		{BufferIndex: particleBufferIdx, FieldName: "vel"},
-		{BufferIndex: vertexBufferIdx, FieldName: "pos"},
-	}
-	vertexBuffers := engine.NewVertexBuffers(bufDefs, vtxAttrs)
-
-	spriteShaderModule := engine.InitShaderModule(device, renderShaderCode, nil)
-	renderPipelineDescriptor := wasmgpu.GPURenderPipelineDescriptor{
-		// Layout: "auto",
-		Vertex: wasmgpu.GPUVertexState{
-			Module:     spriteShaderModule,
-			EntryPoint: "vertex_main",
-			Buffers:    vertexBuffers.Layout,
-		},
-		Fragment: opt.V(wasmgpu.GPUFragmentState{
-			Module:     spriteShaderModule,
-			EntryPoint: "fragment_main",
-			Targets: []wasmgpu.GPUColorTargetState{
-				{
-					//Format: navigator.gpu.getPreferredCanvasFormat(),
-					Format: wasmgpu.GPUTextureFormatBGRA8Unorm,
-				},
-			},
-		}),
-		Primitive: opt.V(wasmgpu.GPUPrimitiveState{
-			Topology: opt.V(wasmgpu.GPUPrimitiveTopologyTriangleList),
-		}),
-	}
-	renderPipeline := device.CreateRenderPipeline(renderPipelineDescriptor)
-
-	structDefinitions := []wgsltypes.Struct{
-		simParamsStruct,
-		particleStruct,
-	}
+	rpf := engine.NewRenderPassFactory(device, context, renderShaderCode, vertexBuffers, nil)
+	renderPass := rpf.InitPass(engine.Draw{
+		VertexEntryPoint:   "vertex_main",
+		FragmentEntryPoint: "fragment_main",
+		VertexCount:        3,
+		InstanceCount:      len(particles),
+	})
 
 	// Compute
-	updateSpritesShaderModule := engine.InitShaderModule(device, computeShaderCode, structDefinitions)
-	computePipelineDescriptor := wasmgpu.GPUComputePipelineDescriptor{
-		// Layout: "auto",
-		Compute: wasmgpu.GPUProgrammableStage{
-			Module:     updateSpritesShaderModule,
-			EntryPoint: "main",
-		},
+	cpf, err := engine.NewNamedComputePassFactory(device, computeShaderCode, map[string]engine.ComputePassBuffer{
+		"params":     simParamBuffer,
+		"particlesA": particleBuffer.Read(),
+		"particlesB": particleBuffer.Write(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating compute pass: %v", err)
 	}
-	computePipeline := device.CreateComputePipeline(computePipelineDescriptor)
-
-	particleBindGroups := make([]wasmgpu.GPUBindGroup, 2)
-	for i := 0; i < 2; i++ {
-		particleBindGroups[i] = device.CreateBindGroup(wasmgpu.GPUBindGroupDescriptor{
-			Layout: computePipeline.GetBindGroupLayout(0),
-			Entries: []wasmgpu.GPUBindGroupEntry{
-				{Binding: 0, Resource: wasmgpu.GPUBufferBinding{Buffer: simParamBuffer.Buffer()}},
-				{Binding: 1, Resource: wasmgpu.GPUBufferBinding{Buffer: particleBuffers[i].Buffer()}},
-				{Binding: 2, Resource: wasmgpu.GPUBufferBinding{Buffer: particleBuffers[(i+1)%2].Buffer()}},
-			},
-		})
+	if err := rpf.CompilationErr(); err != nil {
+		return nil, fmt.Errorf("render shader: %w", err)
 	}
-
-	renderPassDescriptor := wasmgpu.GPURenderPassDescriptor{
-		ColorAttachments: []wasmgpu.GPURenderPassColorAttachment{
-			{
-				View:       context.GetCurrentTexture().CreateView(),
-				ClearValue: opt.V(wasmgpu.GPUColor{R: 0.0, G: 0.0, B: 0.0, A: 1.0}),
-				LoadOp:     wasmgpu.GPULoadOpClear,
-				StoreOp:    wasmgpu.GPUStoreOPStore,
-			},
-		},
+	if err := cpf.CompilationErr(); err != nil {
+		return nil, fmt.Errorf("compute shader: %w", err)
 	}
+	computePass := cpf.InitPass("main", nil, len(particles))
 
-	computePassDescriptor := wasmgpu.GPUComputePassDescriptor{}
-
-	t := 0
 	update := func() {
-		renderPassDescriptor.ColorAttachments[0].View = context.GetCurrentTexture().CreateView()
 		commandEncoder := device.CreateCommandEncoder()
-
-		// Flip the buffer used for rendering.
-		vertexBuffers.Buffers[particleBufferIdx] = particleBuffers[(t+1)%2].Buffer()
-		vertexBuffers.Buffers[vertexBufferIdx] = spriteVertexBuffer.Buffer()
-
-		{
-			passEncoder := commandEncoder.BeginComputePass(opt.V(computePassDescriptor))
-			passEncoder.SetPipeline(computePipeline)
-			passEncoder.SetBindGroup(0, particleBindGroups[t%2], nil)
-			passEncoder.DispatchWorkgroups(wasmgpu.GPUSize32((numParticles+63)/64), 0, 0)
-			passEncoder.End()
-		}
-		{
-			passEncoder := commandEncoder.BeginRenderPass(renderPassDescriptor)
-			passEncoder.SetPipeline(renderPipeline)
-			vertexBuffers.Bind(passEncoder)
-			passEncoder.Draw(3, opt.V(wasmgpu.GPUSize32(numParticles)), opt.Unspecified[wasmgpu.GPUSize32](), opt.Unspecified[wasmgpu.GPUSize32]())
-			passEncoder.End()
-		}
-
-		device.Queue().Submit([]wasmgpu.GPUCommandBuffer{
-			commandEncoder.Finish(),
-		})
-
-		t++
+		computePass(commandEncoder)
+		renderPass(commandEncoder)
+		device.Queue().Submit(commandEncoder.Finish())
 	}
+	return &simulation{update: update, particles: particleBuffer}, nil
+}
 
-	engine.InitRenderCallback(update)
-	return nil
+// defaultSimParams returns the parameters the simulation runs with.
+func defaultSimParams() SimParams {
+	return SimParams{
+		deltaT:        0.04,
+		avoidDistance: 0.025,
+		cMassDistance: 0.1,
+		cVelDistance:  0.025,
+		avoidScale:    0.05,
+		cMassScale:    0.02,
+		cVelScale:     0.005,
+	}
 }
 
 func initParticleData(n int) []Particle {
@@ -0,0 +1,91 @@
+package boids
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/client/engine"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpucpu"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpufake"
+)
+
+func TestFrames(t *testing.T) {
+	device := gpufake.NewDevice()
+	sim, err := setup(device, &gpufake.CanvasContext{}, initParticleData(numParticles))
+	if err != nil {
+		t.Fatalf("setup() = %v", err)
+	}
+
+	const frames = 3
+	for i := 0; i < frames; i++ {
+		sim.update()
+	}
+
+	dispatches := device.Dispatches()
+	draws := device.Draws()
+	if len(dispatches) != frames || len(draws) != frames {
+		t.Fatalf("got %d dispatches and %d draws, want %d of each", len(dispatches), len(draws), frames)
+	}
+	for i := 0; i < frames; i++ {
+		d := dispatches[i]
+		if want := uint32((numParticles + 63) / 64); d.X != want || d.Y != 1 || d.Z != 1 {
+			t.Errorf("frame %d: dispatched (%d, %d, %d) workgroups, want (%d, 1, 1)", i, d.X, d.Y, d.Z, want)
+		}
+		// The compute pass reads the particles from binding 1 and writes them to binding 2,
+		// and the render pass draws the particles it wrote.
+		src, dst := d.BindGroups[0].BufferAt(1), d.BindGroups[0].BufferAt(2)
+		if i > 0 && src != dispatches[i-1].BindGroups[0].BufferAt(2) {
+			t.Errorf("frame %d: compute pass doesn't read the previous frame's particles", i)
+		}
+		if got := draws[i].VertexBuffers[0]; got != dst {
+			t.Errorf("frame %d: render pass doesn't draw the particles written by the compute pass", i)
+		}
+		if got := draws[i].InstanceCount; got != numParticles {
+			t.Errorf("frame %d: InstanceCount = %d, want %d", i, got, numParticles)
+		}
+	}
+}
+
+func TestFramesOnCPU(t *testing.T) {
+	// Small enough for the O(n²) kernel to run quickly, but not a multiple of the workgroup size.
+	const numTestParticles = 200
+	particles := initParticleData(numTestParticles)
+
+	fakeDevice := gpufake.NewDevice()
+	fakeSim, err := setup(fakeDevice, &gpufake.CanvasContext{}, particles)
+	if err != nil {
+		t.Fatalf("setup() on gpufake = %v", err)
+	}
+	cpuDevice := gpucpu.NewDevice(kernels)
+	cpuSim, err := setup(cpuDevice, gpucpu.CanvasContext{}, particles)
+	if err != nil {
+		t.Fatalf("setup() on gpucpu = %v", err)
+	}
+	pool := engine.NewStagingPool(cpuDevice)
+	got := make([]Particle, numTestParticles)
+
+	want := particles
+	const frames = 3
+	for i := 0; i < frames; i++ {
+		fakeSim.update()
+		cpuSim.update()
+
+		next := make([]Particle, len(want))
+		for j := range next {
+			next[j] = updateParticle(defaultSimParams(), want, uint32(j))
+		}
+		want = next
+
+		// The copy gpufake records being drawn is the one gpucpu wrote this frame's particles to.
+		drawn := fakeDevice.Draws()[i].VertexBuffers[0]
+		copyIdx := slices.IndexFunc(fakeSim.particles.Buffers(), func(b engine.GPUBuffer[Particle]) bool { return b.Buffer() == drawn })
+		if copyIdx < 0 {
+			t.Fatalf("frame %d: drew a buffer which doesn't hold the particles", i)
+		}
+		<-cpuSim.particles.Buffers()[copyIdx].ReadInto(pool, 0, got)
+		if diff := cmp.Diff(want, got, cmp.AllowUnexported(Particle{})); diff != "" {
+			t.Errorf("frame %d: drawn particles diff (-want +got):\n%s", i, diff)
+		}
+	}
+}
@@ -1,30 +1,27 @@
-struct Particles {
-  particles : array<Particle>,
-}
 @binding(0) @group(0) var<uniform> params : SimParams;
-@binding(1) @group(0) var<storage, read> particlesA : Particles;
-@binding(2) @group(0) var<storage, read_write> particlesB : Particles;
+@binding(1) @group(0) var<storage, read> particlesA : array<Particle>;
+@binding(2) @group(0) var<storage, read_write> particlesB : array<Particle>;
 
 // https://github.com/austinEng/Project6-Vulkan-Flocking/blob/master/data/shaders/computeparticles/particle.comp
 @compute @workgroup_size(64)
 fn main(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>) {
   var index = GlobalInvocationID.x;
 
-  var vPos = particlesA.particles[index].pos;
-  var vVel = particlesA.particles[index].vel;
+  var vPos = particlesA[index].pos;
+  var vVel = particlesA[index].vel;
   var cMass = vec2(0.0);
   var cVel = vec2(0.0);
   var colVel = vec2(0.0);
   var cMassCount = 0u;
   var cVelCount = 0u;
 
-  for (var i = 0u; i < arrayLength(&particlesA.particles); i++) {
+  for (var i = 0u; i < arrayLength(&particlesA); i++) {
     if (i == index) {
       continue;
     }
 
-    let pos = particlesA.particles[i].pos.xy;
-    let vel = particlesA.particles[i].vel.xy;
+    let pos = particlesA[i].pos.xy;
+    let vel = particlesA[i].vel.xy;
     let dPos = pos - vPos;
     let dist = length(dPos);
     if (dist < params.avoidDistance) {
@@ -66,6 +63,6 @@ fn main(@builtin(global_invocation_id) GlobalInvocationID : vec3<u32>) {
   }
 
   // Write back
-  particlesB.particles[index].pos = vPos;
-  particlesB.particles[index].vel = vVel;
+  particlesB[index].pos = vPos;
+  particlesB[index].vel = vVel;
 }
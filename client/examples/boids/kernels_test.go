@@ -0,0 +1,75 @@
+package boids
+
+import (
+	"github.com/hulkholden/gowebgpu/client/engine/gpu/gpucpu"
+	"github.com/hulkholden/gowebgpu/common/math32"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+)
+
+// kernels implements the entry points of compute.wgsl, so the simulation can run on a gpucpu.Device.
+var kernels = gpucpu.Kernels{
+	"main": func(inv gpucpu.Invocation) {
+		params := gpucpu.Ptr[SimParams](inv.Bindings, 0, 0)
+		particlesA := gpucpu.Slice[Particle](inv.Bindings, 0, 1)
+		particlesB := gpucpu.Slice[Particle](inv.Bindings, 0, 2)
+		// Out of bounds writes are discarded in WGSL.
+		if index := inv.GlobalID[0]; index < uint32(len(particlesB)) {
+			particlesB[index] = updateParticle(*params, particlesA, index)
+		}
+	},
+}
+
+// updateParticle returns the particle at index after one step, as main computes it.
+func updateParticle(params SimParams, particles []Particle, index uint32) Particle {
+	vPos := particles[index].pos
+	vVel := particles[index].vel
+	var cMass, cVel, colVel vmath.V2
+	cMassCount, cVelCount := 0, 0
+
+	for i, p := range particles {
+		if uint32(i) == index {
+			continue
+		}
+		dPos := p.pos.Sub(vPos)
+		dist := dPos.Length()
+		if dist < params.avoidDistance {
+			colVel = colVel.Sub(dPos)
+		}
+		if dist < params.cMassDistance {
+			cMass = cMass.Add(p.pos)
+			cMassCount++
+		}
+		if dist < params.cVelDistance {
+			cVel = cVel.Add(p.vel)
+			cVelCount++
+		}
+	}
+	if cMassCount > 0 {
+		cMass = cMass.Scale(1 / float32(cMassCount)).Sub(vPos)
+	}
+	if cVelCount > 0 {
+		cVel = cVel.Scale(1 / float32(cVelCount))
+	}
+	vVel = vVel.Add(colVel.Scale(params.avoidScale)).Add(cMass.Scale(params.cMassScale)).Add(cVel.Scale(params.cVelScale))
+
+	// clamp velocity for a more pleasing simulation
+	dir, speed := vVel.Normal()
+	vVel = dir.Scale(math32.Clamp(speed, 0, 0.1))
+	// kinematic update
+	vPos = vPos.Add(vVel.Scale(params.deltaT))
+	// Wrap around boundary. This matches the shader, which adds 2 to positions past 1 rather than subtracting it.
+	if vPos.X < -1 {
+		vPos.X += 2
+	}
+	if vPos.X > 1 {
+		vPos.X -= -2
+	}
+	if vPos.Y < -1 {
+		vPos.Y += 2
+	}
+	if vPos.Y > 1 {
+		vPos.Y -= -2
+	}
+
+	return Particle{pos: vPos, vel: vVel}
+}
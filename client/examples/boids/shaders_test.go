@@ -0,0 +1,42 @@
+package boids
+
+import (
+	"testing"
+
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+	"github.com/hulkholden/gowebgpu/common/wgsltest"
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+func TestShaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		structs []wgsltypes.Struct
+	}{
+		{
+			name:    "compute.wgsl",
+			code:    computeShaderCode,
+			structs: []wgsltypes.Struct{simParamsStruct, particleStruct},
+		},
+		{
+			name: "render.wgsl",
+			code: renderShaderCode,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src, err := wgsl.Preprocessor{}.Process(tc.name, tc.code)
+			if err != nil {
+				t.Fatalf("Process() = %v", err)
+			}
+			for _, d := range wgsl.Check(wgsl.ModuleSource(src, tc.structs, nil)) {
+				t.Error(d)
+			}
+			// render.wgsl is created without a prologue, so it has no golden file.
+			if len(tc.structs) > 0 {
+				wgsltest.CheckPrologue(t, tc.name, wgsltypes.Prologue(tc.structs, nil))
+			}
+		})
+	}
+}
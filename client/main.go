@@ -1,16 +1,21 @@
+//go:build js && wasm
+
 package main
 
 import (
+	"errors"
 	"log"
 	"syscall/js"
 	"time"
 
+	"github.com/hulkholden/gowebgpu/client/engine"
+	"github.com/hulkholden/gowebgpu/client/engine/gpu"
 	"github.com/hulkholden/gowebgpu/client/examples/battle"
 	"github.com/hulkholden/gowebgpu/client/examples/boids"
 	"github.com/mokiat/wasmgpu"
 )
 
-type runFunc func(device wasmgpu.GPUDevice, context wasmgpu.GPUCanvasContext) error
+type runFunc func(device gpu.Device, context gpu.CanvasContext) error
 
 var examples = map[string]runFunc{
 	"battle": battle.Run,
@@ -36,8 +41,8 @@ func main() {
 
 	jsContext := js.Global().Call("getContext")
 	jsDevice := js.Global().Call("getDevice")
-	context := wasmgpu.NewCanvasContext(jsContext)
-	device := wasmgpu.NewDevice(jsDevice)
+	context := gpu.NewCanvasContext(wasmgpu.NewCanvasContext(jsContext))
+	device := gpu.NewDevice(wasmgpu.NewDevice(jsDevice))
 
 	const defaultExample = "battle"
 	example := defaultExample
@@ -51,7 +56,9 @@ func main() {
 	err := run(device, context)
 	if err != nil {
 		log.Printf("runRender() failed: %v", err)
-		if fn := js.Global().Get("showError"); !fn.IsUndefined() {
+		// The engine has already shown compilation errors on the page.
+		var compilationErr *engine.CompilationError
+		if fn := js.Global().Get("showError"); !fn.IsUndefined() && !errors.As(err, &compilationErr) {
 			fn.Invoke("Run error: " + err.Error())
 		}
 	}
@@ -0,0 +1,89 @@
+// Command wgslcheck reports problems in WGSL shaders without compiling them in a browser.
+//
+// Usage:
+//
+//	wgslcheck [-D NAME]... [-prologue FILE] SHADER...
+//
+// Each shader is preprocessed, with #includes resolved relative to the including file,
+// then the prologue is prepended and the result is checked with wgsl.Check. The exit status
+// is 1 if any problems are found.
+//
+// The engine declares the structs of the buffers a shader binds from their Go types (see
+// wgsltypes.Prologue), and the consts it's created with. A command can't load the Go types of
+// another package, so the examples' shaders_test.go files write those declarations to
+// testdata/<shader>.prologue, and fail if the file no longer matches the Go types. Passing that
+// file as the prologue checks exactly the module the engine creates.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/hulkholden/gowebgpu/common/wgsl"
+)
+
+// stringList is a flag which can be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func main() {
+	var defines stringList
+	flag.Var(&defines, "D", "define `NAME` for #ifdef directives (may be repeated)")
+	prologue := flag.String("prologue", "", "`FILE` of WGSL declarations to prepend to each shader, such as an example's testdata/<shader>.prologue")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	n, err := checkFiles(os.Stdout, flag.Args(), defines, *prologue)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if n > 0 {
+		os.Exit(1)
+	}
+}
+
+// checkFiles checks the named shaders, writes any problems to w and returns how many were found.
+func checkFiles(w io.Writer, names []string, defines []string, prologuePath string) (int, error) {
+	var prologue string
+	if prologuePath != "" {
+		data, err := os.ReadFile(prologuePath)
+		if err != nil {
+			return 0, fmt.Errorf("reading prologue: %v", err)
+		}
+		prologue = string(data)
+	}
+
+	p := wgsl.Preprocessor{Load: os.ReadFile, Defines: defines}
+	count := 0
+	for _, name := range names {
+		src, err := p.ProcessFile(name)
+		if err != nil {
+			fmt.Fprintln(w, err)
+			count++
+			continue
+		}
+		if prologuePath != "" {
+			src = src.WithPrologue(prologuePath, prologue)
+		}
+		for _, d := range wgsl.Check(src) {
+			fmt.Fprintln(w, d)
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []string
+		defines   []string
+		prologue  string
+		wantCount int
+		want      string
+	}{
+		{
+			name:     "good",
+			files:    []string{"testdata/good.wgsl"},
+			defines:  []string{"DOUBLE"},
+			prologue: "testdata/prologue.wgsl",
+		},
+		{
+			name:      "missing prologue",
+			files:     []string{"testdata/good.wgsl"},
+			wantCount: 1,
+			want:      "testdata/good.wgsl:3: undeclared identifier \"Item\"\n",
+		},
+		{
+			name:      "bad",
+			files:     []string{"testdata/bad.wgsl"},
+			prologue:  "testdata/prologue.wgsl",
+			wantCount: 1,
+			want:      "testdata/bad.wgsl:10: gItems[] (of type Item) has no field \"counter\"\n",
+		},
+		{
+			name:      "bad with defines",
+			files:     []string{"testdata/bad.wgsl"},
+			defines:   []string{"DOUBLE"},
+			prologue:  "testdata/prologue.wgsl",
+			wantCount: 2,
+			want: "testdata/bad.wgsl:8: undeclared identifier \"scaled\"\n" +
+				"testdata/bad.wgsl:10: gItems[] (of type Item) has no field \"counter\"\n",
+		},
+		{
+			name:      "missing file",
+			files:     []string{"testdata/missing.wgsl"},
+			wantCount: 1,
+			want:      "loading \"testdata/missing.wgsl\": open testdata/missing.wgsl: no such file or directory\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			n, err := checkFiles(&out, tc.files, tc.defines, tc.prologue)
+			if err != nil {
+				t.Fatalf("checkFiles() = %v", err)
+			}
+			if n != tc.wantCount {
+				t.Errorf("checkFiles() = %d, want %d", n, tc.wantCount)
+			}
+			if diff := cmp.Diff(tc.want, out.String()); diff != "" {
+				t.Errorf("checkFiles() output diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
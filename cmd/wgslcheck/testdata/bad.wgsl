@@ -0,0 +1,11 @@
+#include "common.wgsl"
+
+@group(0) @binding(0) var<storage, read_write> gItems : array<Item>;
+
+@compute @workgroup_size(64)
+fn main(@builtin(global_invocation_id) id : vec3<u32>) {
+#ifdef DOUBLE
+  gItems[id.x].pos = scaled(gItems[id.x].pos, 2.0);
+#endif
+  gItems[id.x].counter += 1u;
+}
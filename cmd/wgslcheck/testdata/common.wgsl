@@ -0,0 +1,5 @@
+#pragma once
+
+fn scale(v : vec2f, s : f32) -> vec2f {
+  return v * s;
+}
@@ -0,0 +1,4 @@
+struct Item {
+  pos : vec2<f32>,
+  count : u32,
+}
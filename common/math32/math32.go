@@ -44,6 +44,10 @@ func Cos(x float32) float32 {
 	return float32(math.Cos(float64(x)))
 }
 
+func Tan(x float32) float32 {
+	return float32(math.Tan(float64(x)))
+}
+
 func SinCos(x float32) (float32, float32) {
 	return Sin(x), Cos(x)
 }
@@ -0,0 +1,95 @@
+package vmath
+
+import "math"
+
+// F16 is an IEEE 754 half precision float, corresponding to the WGSL f16 type.
+type F16 uint16
+
+// NewF16 converts f to half precision, rounding to the nearest representable value.
+func NewF16(f float32) F16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	// Infinity and NaN.
+	if exp == 0xff {
+		if mant != 0 {
+			return F16(sign | 0x7e00)
+		}
+		return F16(sign | 0x7c00)
+	}
+
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		// Too large - round to infinity.
+		return F16(sign | 0x7c00)
+	}
+	if e <= 0 {
+		// Subnormal or zero.
+		if e < -10 {
+			return F16(sign)
+		}
+		mant |= 0x800000
+		shift := uint32(14 - e)
+		half := mant >> shift
+		rem := mant & ((1 << shift) - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++
+		}
+		return F16(sign | uint16(half))
+	}
+
+	half := uint32(e)<<10 | mant>>13
+	rem := mant & 0x1fff
+	// Rounding may carry into the exponent, which correctly rounds up to the next power of two (or infinity).
+	if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return F16(sign | uint16(half))
+}
+
+func (h F16) Float32() float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h) & 0x3ff
+
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// Normalize the subnormal value.
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		mant &= 0x3ff
+		return math.Float32frombits(sign | e<<23 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// V2h is a half precision vector, corresponding to the WGSL vec2<f16> type.
+type V2h struct {
+	X, Y F16
+}
+
+func NewV2h(v V2) V2h { return V2h{X: NewF16(v.X), Y: NewF16(v.Y)} }
+
+func (v V2h) ToV2() V2 { return V2{X: v.X.Float32(), Y: v.Y.Float32()} }
+
+// V4h is a half precision vector, corresponding to the WGSL vec4<f16> type.
+type V4h struct {
+	X, Y, Z, W F16
+}
+
+func NewV4h(v V4) V4h { return V4h{X: NewF16(v.X), Y: NewF16(v.Y), Z: NewF16(v.Z), W: NewF16(v.W)} }
+
+func (v V4h) ToV4() V4 {
+	return V4{X: v.X.Float32(), Y: v.Y.Float32(), Z: v.Z.Float32(), W: v.W.Float32()}
+}
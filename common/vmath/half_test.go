@@ -0,0 +1,64 @@
+package vmath
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewF16(t *testing.T) {
+	tests := []struct {
+		name string
+		f    float32
+		want F16
+	}{
+		{name: "zero", f: 0, want: 0x0000},
+		{name: "negative zero", f: float32(math.Copysign(0, -1)), want: 0x8000},
+		{name: "one", f: 1, want: 0x3c00},
+		{name: "minus two", f: -2, want: 0xc000},
+		{name: "half", f: 0.5, want: 0x3800},
+		{name: "max", f: 65504, want: 0x7bff},
+		{name: "overflow", f: 65536, want: 0x7c00},
+		{name: "round to overflow", f: 65520, want: 0x7c00},
+		{name: "infinity", f: float32(math.Inf(-1)), want: 0xfc00},
+		{name: "smallest normal", f: 1.0 / 16384, want: 0x0400},
+		{name: "smallest subnormal", f: 1.0 / 16777216, want: 0x0001},
+		{name: "underflow", f: 1.0 / 67108864, want: 0x0000},
+		{name: "round to nearest even down", f: 1 + 1.0/2048, want: 0x3c00},
+		{name: "round to nearest even up", f: 1 + 3.0/2048, want: 0x3c02},
+		{name: "round up", f: 1 + 1.5/2048, want: 0x3c01},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := NewF16(tc.f); got != tc.want {
+				t.Errorf("NewF16(%v) = %#04x, want %#04x", tc.f, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestF16NaN(t *testing.T) {
+	h := NewF16(float32(math.NaN()))
+	if got := h.Float32(); !math.IsNaN(float64(got)) {
+		t.Errorf("NewF16(NaN).Float32() = %v, want NaN", got)
+	}
+}
+
+func TestF16RoundTrip(t *testing.T) {
+	// All finite half values should survive a round trip through float32.
+	for bits := 0; bits < 0x10000; bits++ {
+		h := F16(bits)
+		if (bits & 0x7c00) == 0x7c00 {
+			continue
+		}
+		if got := NewF16(h.Float32()); got != h {
+			t.Fatalf("NewF16(F16(%#04x).Float32()) = %#04x", bits, got)
+		}
+	}
+}
+
+func TestV2hRoundTrip(t *testing.T) {
+	v := NewV2(1.5, -0.25)
+	if got := NewV2h(v).ToV2(); got != v {
+		t.Errorf("NewV2h(%v).ToV2() = %v, want %v", v, got, v)
+	}
+}
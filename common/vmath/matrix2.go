@@ -0,0 +1,69 @@
+package vmath
+
+import (
+	"fmt"
+
+	"github.com/hulkholden/gowebgpu/common/math32"
+)
+
+// M2 is a 2x2 matrix stored in column-major order, laid out like a WGSL mat2x2<f32>.
+type M2 struct {
+	m [2][2]float32
+}
+
+// NewM2 returns a matrix with the provided columns.
+func NewM2(c0, c1 V2) M2 {
+	return M2{m: [2][2]float32{
+		{c0.X, c0.Y},
+		{c1.X, c1.Y},
+	}}
+}
+
+func IdentityM2() M2 { return NewM2(NewV2(1, 0), NewV2(0, 1)) }
+
+// NewM2Rotation returns a matrix which rotates vectors by a radians (consistent with V2.Rotate).
+func NewM2Rotation(a float32) M2 {
+	s, c := math32.SinCos(a)
+	return NewM2(NewV2(c, s), NewV2(-s, c))
+}
+
+func NewM2Scale(s V2) M2 { return NewM2(NewV2(s.X, 0), NewV2(0, s.Y)) }
+
+func (m M2) String() string {
+	return fmt.Sprintf("{%v, %v}", m.Col(0), m.Col(1))
+}
+
+func (m M2) At(row, col int) float32 { return m.m[col][row] }
+func (m M2) Col(i int) V2            { return NewV2(m.m[i][0], m.m[i][1]) }
+func (m M2) Row(i int) V2            { return NewV2(m.m[0][i], m.m[1][i]) }
+
+func (m M2) MulV(v V2) V2 {
+	return m.Col(0).Scale(v.X).Add(m.Col(1).Scale(v.Y))
+}
+
+func (m M2) Mul(n M2) M2 {
+	return NewM2(m.MulV(n.Col(0)), m.MulV(n.Col(1)))
+}
+
+func (m M2) Transpose() M2 {
+	return NewM2(m.Row(0), m.Row(1))
+}
+
+func (m M2) Determinant() float32 {
+	return m.Col(0).Cross(m.Col(1))
+}
+
+// Inverse returns the inverse of the matrix, or false if the matrix is singular.
+func (m M2) Inverse() (M2, bool) {
+	det := m.Determinant()
+	if det == 0 {
+		return M2{}, false
+	}
+	invDet := 1 / det
+	a, b := m.At(0, 0), m.At(0, 1)
+	c, d := m.At(1, 0), m.At(1, 1)
+	return NewM2(
+		NewV2(d, -c).Scale(invDet),
+		NewV2(-b, a).Scale(invDet),
+	), true
+}
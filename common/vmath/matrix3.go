@@ -0,0 +1,96 @@
+package vmath
+
+import (
+	"fmt"
+
+	"github.com/hulkholden/gowebgpu/common/math32"
+)
+
+// M3 is a 3x3 matrix stored in column-major order, laid out like a WGSL mat3x3<f32>.
+// Each column is padded to 16 bytes to match the alignment of vec3<f32>.
+type M3 struct {
+	m [3][4]float32
+}
+
+// NewM3 returns a matrix with the provided columns.
+func NewM3(c0, c1, c2 V3) M3 {
+	return M3{m: [3][4]float32{
+		{c0.X, c0.Y, c0.Z},
+		{c1.X, c1.Y, c1.Z},
+		{c2.X, c2.Y, c2.Z},
+	}}
+}
+
+func IdentityM3() M3 { return NewM3(NewV3(1, 0, 0), NewV3(0, 1, 0), NewV3(0, 0, 1)) }
+
+// NewM3Translation returns a 2D affine transform which translates by t.
+func NewM3Translation(t V2) M3 {
+	return NewM3(NewV3(1, 0, 0), NewV3(0, 1, 0), NewV3(t.X, t.Y, 1))
+}
+
+// NewM3Rotation returns a 2D affine transform which rotates by a radians (consistent with V2.Rotate).
+func NewM3Rotation(a float32) M3 {
+	s, c := math32.SinCos(a)
+	return NewM3(NewV3(c, s, 0), NewV3(-s, c, 0), NewV3(0, 0, 1))
+}
+
+// NewM3Scale returns a 2D affine transform which scales by s.
+func NewM3Scale(s V2) M3 {
+	return NewM3(NewV3(s.X, 0, 0), NewV3(0, s.Y, 0), NewV3(0, 0, 1))
+}
+
+// NewM3Orthographic returns a 2D affine transform which maps the rectangle [min, max] to clip space ([-1, +1]).
+func NewM3Orthographic(min, max V2) M3 {
+	size := max.Sub(min)
+	sx, sy := 2/size.X, 2/size.Y
+	tx, ty := -(max.X+min.X)/size.X, -(max.Y+min.Y)/size.Y
+	return NewM3(NewV3(sx, 0, 0), NewV3(0, sy, 0), NewV3(tx, ty, 1))
+}
+
+func (m M3) String() string {
+	return fmt.Sprintf("{%v, %v, %v}", m.Col(0), m.Col(1), m.Col(2))
+}
+
+func (m M3) At(row, col int) float32 { return m.m[col][row] }
+func (m M3) Col(i int) V3            { return NewV3(m.m[i][0], m.m[i][1], m.m[i][2]) }
+func (m M3) Row(i int) V3            { return NewV3(m.m[0][i], m.m[1][i], m.m[2][i]) }
+
+func (m M3) MulV(v V3) V3 {
+	var r [3]float32
+	for row := 0; row < 3; row++ {
+		r[row] = m.m[0][row]*v.X + m.m[1][row]*v.Y + m.m[2][row]*v.Z
+	}
+	return NewV3(r[0], r[1], r[2])
+}
+
+// TransformPoint applies the 2D affine transform to a point.
+func (m M3) TransformPoint(p V2) V2 {
+	r := m.MulV(NewV3(p.X, p.Y, 1))
+	return NewV2(r.X, r.Y)
+}
+
+func (m M3) Mul(n M3) M3 {
+	return NewM3(m.MulV(n.Col(0)), m.MulV(n.Col(1)), m.MulV(n.Col(2)))
+}
+
+func (m M3) Transpose() M3 {
+	return NewM3(m.Row(0), m.Row(1), m.Row(2))
+}
+
+func (m M3) Determinant() float32 {
+	c0, c1, c2 := m.Col(0), m.Col(1), m.Col(2)
+	return c0.Dot(c1.Cross(c2))
+}
+
+// Inverse returns the inverse of the matrix, or false if the matrix is singular.
+func (m M3) Inverse() (M3, bool) {
+	c0, c1, c2 := m.Col(0), m.Col(1), m.Col(2)
+	r0, r1, r2 := c1.Cross(c2), c2.Cross(c0), c0.Cross(c1)
+	det := c0.Dot(r0)
+	if det == 0 {
+		return M3{}, false
+	}
+	invDet := 1 / det
+	// The rows of the inverse are the scaled cross products.
+	return NewM3(r0.Scale(invDet), r1.Scale(invDet), r2.Scale(invDet)).Transpose(), true
+}
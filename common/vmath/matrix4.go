@@ -0,0 +1,124 @@
+package vmath
+
+import (
+	"fmt"
+
+	"github.com/hulkholden/gowebgpu/common/math32"
+)
+
+// M4 is a 4x4 matrix stored in column-major order, laid out like a WGSL mat4x4<f32>.
+type M4 struct {
+	m [4][4]float32
+}
+
+// NewM4 returns a matrix with the provided columns.
+func NewM4(c0, c1, c2, c3 V4) M4 {
+	return M4{m: [4][4]float32{
+		{c0.X, c0.Y, c0.Z, c0.W},
+		{c1.X, c1.Y, c1.Z, c1.W},
+		{c2.X, c2.Y, c2.Z, c2.W},
+		{c3.X, c3.Y, c3.Z, c3.W},
+	}}
+}
+
+func IdentityM4() M4 {
+	return NewM4(NewV4(1, 0, 0, 0), NewV4(0, 1, 0, 0), NewV4(0, 0, 1, 0), NewV4(0, 0, 0, 1))
+}
+
+func NewM4Translation(t V3) M4 {
+	return NewM4(NewV4(1, 0, 0, 0), NewV4(0, 1, 0, 0), NewV4(0, 0, 1, 0), NewV4(t.X, t.Y, t.Z, 1))
+}
+
+func NewM4Scale(s V3) M4 {
+	return NewM4(NewV4(s.X, 0, 0, 0), NewV4(0, s.Y, 0, 0), NewV4(0, 0, s.Z, 0), NewV4(0, 0, 0, 1))
+}
+
+// NewM4Orthographic returns a right-handed orthographic projection.
+// Depth is mapped to WebGPU's clip space range of [0, 1].
+func NewM4Orthographic(left, right, bottom, top, near, far float32) M4 {
+	lr := 1 / (left - right)
+	bt := 1 / (bottom - top)
+	nf := 1 / (near - far)
+	return NewM4(
+		NewV4(-2*lr, 0, 0, 0),
+		NewV4(0, -2*bt, 0, 0),
+		NewV4(0, 0, nf, 0),
+		NewV4((left+right)*lr, (top+bottom)*bt, near*nf, 1),
+	)
+}
+
+// NewM4Perspective returns a right-handed perspective projection with a vertical field of view of fovY radians.
+// Depth is mapped to WebGPU's clip space range of [0, 1].
+func NewM4Perspective(fovY, aspect, near, far float32) M4 {
+	f := 1 / math32.Tan(fovY/2)
+	nf := 1 / (near - far)
+	return NewM4(
+		NewV4(f/aspect, 0, 0, 0),
+		NewV4(0, f, 0, 0),
+		NewV4(0, 0, far*nf, -1),
+		NewV4(0, 0, far*near*nf, 0),
+	)
+}
+
+func (m M4) String() string {
+	return fmt.Sprintf("{%v, %v, %v, %v}", m.Col(0), m.Col(1), m.Col(2), m.Col(3))
+}
+
+func (m M4) At(row, col int) float32 { return m.m[col][row] }
+func (m M4) Col(i int) V4            { return NewV4(m.m[i][0], m.m[i][1], m.m[i][2], m.m[i][3]) }
+func (m M4) Row(i int) V4            { return NewV4(m.m[0][i], m.m[1][i], m.m[2][i], m.m[3][i]) }
+
+func (m M4) MulV(v V4) V4 {
+	var r [4]float32
+	for row := 0; row < 4; row++ {
+		r[row] = m.m[0][row]*v.X + m.m[1][row]*v.Y + m.m[2][row]*v.Z + m.m[3][row]*v.W
+	}
+	return NewV4(r[0], r[1], r[2], r[3])
+}
+
+func (m M4) Mul(n M4) M4 {
+	return NewM4(m.MulV(n.Col(0)), m.MulV(n.Col(1)), m.MulV(n.Col(2)), m.MulV(n.Col(3)))
+}
+
+func (m M4) Transpose() M4 {
+	return NewM4(m.Row(0), m.Row(1), m.Row(2), m.Row(3))
+}
+
+// Inverse returns the inverse of the matrix, or false if the matrix is singular.
+func (m M4) Inverse() (M4, bool) {
+	a00, a01, a02, a03 := m.m[0][0], m.m[0][1], m.m[0][2], m.m[0][3]
+	a10, a11, a12, a13 := m.m[1][0], m.m[1][1], m.m[1][2], m.m[1][3]
+	a20, a21, a22, a23 := m.m[2][0], m.m[2][1], m.m[2][2], m.m[2][3]
+	a30, a31, a32, a33 := m.m[3][0], m.m[3][1], m.m[3][2], m.m[3][3]
+
+	b00 := a00*a11 - a01*a10
+	b01 := a00*a12 - a02*a10
+	b02 := a00*a13 - a03*a10
+	b03 := a01*a12 - a02*a11
+	b04 := a01*a13 - a03*a11
+	b05 := a02*a13 - a03*a12
+	b06 := a20*a31 - a21*a30
+	b07 := a20*a32 - a22*a30
+	b08 := a20*a33 - a23*a30
+	b09 := a21*a32 - a22*a31
+	b10 := a21*a33 - a23*a31
+	b11 := a22*a33 - a23*a32
+
+	det := b00*b11 - b01*b10 + b02*b09 + b03*b08 - b04*b07 + b05*b06
+	if det == 0 {
+		return M4{}, false
+	}
+	invDet := 1 / det
+
+	var r M4
+	r.m[0] = [4]float32{a11*b11 - a12*b10 + a13*b09, a02*b10 - a01*b11 - a03*b09, a31*b05 - a32*b04 + a33*b03, a22*b04 - a21*b05 - a23*b03}
+	r.m[1] = [4]float32{a12*b08 - a10*b11 - a13*b07, a00*b11 - a02*b08 + a03*b07, a32*b02 - a30*b05 - a33*b01, a20*b05 - a22*b02 + a23*b01}
+	r.m[2] = [4]float32{a10*b10 - a11*b08 + a13*b06, a01*b08 - a00*b10 - a03*b06, a30*b04 - a31*b02 + a33*b00, a21*b02 - a20*b04 - a23*b00}
+	r.m[3] = [4]float32{a11*b07 - a10*b09 - a12*b06, a00*b09 - a01*b07 + a02*b06, a31*b01 - a30*b03 - a32*b00, a20*b03 - a21*b01 + a22*b00}
+	for c := range r.m {
+		for row := range r.m[c] {
+			r.m[c][row] *= invDet
+		}
+	}
+	return r, true
+}
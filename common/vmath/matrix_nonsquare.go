@@ -0,0 +1,106 @@
+package vmath
+
+// Non-square matrices follow the WGSL matCxR naming convention: C columns of R rows.
+// Columns with 3 rows are padded to 16 bytes to match the alignment of vec3<f32>.
+
+// M2x3 is a matrix with 2 columns and 3 rows, laid out like a WGSL mat2x3<f32>.
+type M2x3 struct {
+	m [2][4]float32
+}
+
+func NewM2x3(c0, c1 V3) M2x3 {
+	return M2x3{m: [2][4]float32{{c0.X, c0.Y, c0.Z}, {c1.X, c1.Y, c1.Z}}}
+}
+
+func (m M2x3) At(row, col int) float32 { return m.m[col][row] }
+func (m M2x3) Col(i int) V3            { return NewV3(m.m[i][0], m.m[i][1], m.m[i][2]) }
+func (m M2x3) Row(i int) V2            { return NewV2(m.m[0][i], m.m[1][i]) }
+func (m M2x3) Transpose() M3x2         { return NewM3x2(m.Row(0), m.Row(1), m.Row(2)) }
+func (m M2x3) MulV(v V2) V3            { return m.Col(0).Scale(v.X).Add(m.Col(1).Scale(v.Y)) }
+
+// M2x4 is a matrix with 2 columns and 4 rows, laid out like a WGSL mat2x4<f32>.
+type M2x4 struct {
+	m [2][4]float32
+}
+
+func NewM2x4(c0, c1 V4) M2x4 {
+	return M2x4{m: [2][4]float32{{c0.X, c0.Y, c0.Z, c0.W}, {c1.X, c1.Y, c1.Z, c1.W}}}
+}
+
+func (m M2x4) At(row, col int) float32 { return m.m[col][row] }
+func (m M2x4) Col(i int) V4            { return NewV4(m.m[i][0], m.m[i][1], m.m[i][2], m.m[i][3]) }
+func (m M2x4) Row(i int) V2            { return NewV2(m.m[0][i], m.m[1][i]) }
+func (m M2x4) Transpose() M4x2         { return NewM4x2(m.Row(0), m.Row(1), m.Row(2), m.Row(3)) }
+func (m M2x4) MulV(v V2) V4            { return m.Col(0).Scale(v.X).Add(m.Col(1).Scale(v.Y)) }
+
+// M3x2 is a matrix with 3 columns and 2 rows, laid out like a WGSL mat3x2<f32>.
+type M3x2 struct {
+	m [3][2]float32
+}
+
+func NewM3x2(c0, c1, c2 V2) M3x2 {
+	return M3x2{m: [3][2]float32{{c0.X, c0.Y}, {c1.X, c1.Y}, {c2.X, c2.Y}}}
+}
+
+func (m M3x2) At(row, col int) float32 { return m.m[col][row] }
+func (m M3x2) Col(i int) V2            { return NewV2(m.m[i][0], m.m[i][1]) }
+func (m M3x2) Row(i int) V3            { return NewV3(m.m[0][i], m.m[1][i], m.m[2][i]) }
+func (m M3x2) Transpose() M2x3         { return NewM2x3(m.Row(0), m.Row(1)) }
+
+func (m M3x2) MulV(v V3) V2 {
+	return m.Col(0).Scale(v.X).Add(m.Col(1).Scale(v.Y)).Add(m.Col(2).Scale(v.Z))
+}
+
+// M3x4 is a matrix with 3 columns and 4 rows, laid out like a WGSL mat3x4<f32>.
+type M3x4 struct {
+	m [3][4]float32
+}
+
+func NewM3x4(c0, c1, c2 V4) M3x4 {
+	return M3x4{m: [3][4]float32{{c0.X, c0.Y, c0.Z, c0.W}, {c1.X, c1.Y, c1.Z, c1.W}, {c2.X, c2.Y, c2.Z, c2.W}}}
+}
+
+func (m M3x4) At(row, col int) float32 { return m.m[col][row] }
+func (m M3x4) Col(i int) V4            { return NewV4(m.m[i][0], m.m[i][1], m.m[i][2], m.m[i][3]) }
+func (m M3x4) Row(i int) V3            { return NewV3(m.m[0][i], m.m[1][i], m.m[2][i]) }
+func (m M3x4) Transpose() M4x3         { return NewM4x3(m.Row(0), m.Row(1), m.Row(2), m.Row(3)) }
+
+func (m M3x4) MulV(v V3) V4 {
+	return m.Col(0).Scale(v.X).Add(m.Col(1).Scale(v.Y)).Add(m.Col(2).Scale(v.Z))
+}
+
+// M4x2 is a matrix with 4 columns and 2 rows, laid out like a WGSL mat4x2<f32>.
+type M4x2 struct {
+	m [4][2]float32
+}
+
+func NewM4x2(c0, c1, c2, c3 V2) M4x2 {
+	return M4x2{m: [4][2]float32{{c0.X, c0.Y}, {c1.X, c1.Y}, {c2.X, c2.Y}, {c3.X, c3.Y}}}
+}
+
+func (m M4x2) At(row, col int) float32 { return m.m[col][row] }
+func (m M4x2) Col(i int) V2            { return NewV2(m.m[i][0], m.m[i][1]) }
+func (m M4x2) Row(i int) V4            { return NewV4(m.m[0][i], m.m[1][i], m.m[2][i], m.m[3][i]) }
+func (m M4x2) Transpose() M2x4         { return NewM2x4(m.Row(0), m.Row(1)) }
+
+func (m M4x2) MulV(v V4) V2 {
+	return m.Col(0).Scale(v.X).Add(m.Col(1).Scale(v.Y)).Add(m.Col(2).Scale(v.Z)).Add(m.Col(3).Scale(v.W))
+}
+
+// M4x3 is a matrix with 4 columns and 3 rows, laid out like a WGSL mat4x3<f32>.
+type M4x3 struct {
+	m [4][4]float32
+}
+
+func NewM4x3(c0, c1, c2, c3 V3) M4x3 {
+	return M4x3{m: [4][4]float32{{c0.X, c0.Y, c0.Z}, {c1.X, c1.Y, c1.Z}, {c2.X, c2.Y, c2.Z}, {c3.X, c3.Y, c3.Z}}}
+}
+
+func (m M4x3) At(row, col int) float32 { return m.m[col][row] }
+func (m M4x3) Col(i int) V3            { return NewV3(m.m[i][0], m.m[i][1], m.m[i][2]) }
+func (m M4x3) Row(i int) V4            { return NewV4(m.m[0][i], m.m[1][i], m.m[2][i], m.m[3][i]) }
+func (m M4x3) Transpose() M3x4         { return NewM3x4(m.Row(0), m.Row(1), m.Row(2)) }
+
+func (m M4x3) MulV(v V4) V3 {
+	return m.Col(0).Scale(v.X).Add(m.Col(1).Scale(v.Y)).Add(m.Col(2).Scale(v.Z)).Add(m.Col(3).Scale(v.W))
+}
@@ -0,0 +1,211 @@
+package vmath
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/hulkholden/gowebgpu/common/math32"
+)
+
+func approxEqualV3(a, b V3) bool {
+	return approxEqualF(a.X, b.X, eps) && approxEqualF(a.Y, b.Y, eps) && approxEqualF(a.Z, b.Z, eps)
+}
+
+func approxEqualV4(a, b V4) bool {
+	return approxEqualF(a.X, b.X, eps) && approxEqualF(a.Y, b.Y, eps) && approxEqualF(a.Z, b.Z, eps) && approxEqualF(a.W, b.W, eps)
+}
+
+func approxEqualM4(a, b M4) bool {
+	for i := 0; i < 4; i++ {
+		if !approxEqualV4(a.Col(i), b.Col(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMatrixSizes(t *testing.T) {
+	// Sizes must match SizeOf for the corresponding WGSL type.
+	tests := []struct {
+		name string
+		got  uintptr
+		want uintptr
+	}{
+		{name: "M2", got: unsafe.Sizeof(M2{}), want: 16},
+		{name: "M2x3", got: unsafe.Sizeof(M2x3{}), want: 32},
+		{name: "M2x4", got: unsafe.Sizeof(M2x4{}), want: 32},
+		{name: "M3x2", got: unsafe.Sizeof(M3x2{}), want: 24},
+		{name: "M3", got: unsafe.Sizeof(M3{}), want: 48},
+		{name: "M3x4", got: unsafe.Sizeof(M3x4{}), want: 48},
+		{name: "M4x2", got: unsafe.Sizeof(M4x2{}), want: 32},
+		{name: "M4x3", got: unsafe.Sizeof(M4x3{}), want: 64},
+		{name: "M4", got: unsafe.Sizeof(M4{}), want: 64},
+	}
+	for _, tc := range tests {
+		if tc.got != tc.want {
+			t.Errorf("unsafe.Sizeof(%s) = %d, want %d", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestM2Rotation(t *testing.T) {
+	v := NewV2(1, 2)
+	for _, a := range []float32{0, math32.Pi / 4, math32.Pi / 2, -1} {
+		got := NewM2Rotation(a).MulV(v)
+		want := v.Rotate(a)
+		if !approxEqualV2(got, want) {
+			t.Errorf("NewM2Rotation(%v).MulV(%v) = %v, want %v", a, v, got, want)
+		}
+	}
+}
+
+func TestM2Inverse(t *testing.T) {
+	m := NewM2(NewV2(2, 1), NewV2(1, 3))
+	inv, ok := m.Inverse()
+	if !ok {
+		t.Fatalf("(%v).Inverse() failed, want success", m)
+	}
+	got := m.Mul(inv)
+	for i := 0; i < 2; i++ {
+		if !approxEqualV2(got.Col(i), IdentityM2().Col(i)) {
+			t.Errorf("m * m.Inverse() = %v, want identity", got)
+		}
+	}
+
+	if _, ok := NewM2(NewV2(1, 2), NewV2(2, 4)).Inverse(); ok {
+		t.Errorf("Inverse() of singular matrix succeeded, want failure")
+	}
+}
+
+func TestM3Transforms(t *testing.T) {
+	p := NewV2(1, 2)
+	tests := []struct {
+		name string
+		m    M3
+		want V2
+	}{
+		{name: "identity", m: IdentityM3(), want: p},
+		{name: "translation", m: NewM3Translation(NewV2(3, 4)), want: NewV2(4, 6)},
+		{name: "rotation", m: NewM3Rotation(math32.Pi / 2), want: NewV2(-2, 1)},
+		{name: "scale", m: NewM3Scale(NewV2(2, 3)), want: NewV2(2, 6)},
+		{name: "translate then scale", m: NewM3Scale(NewV2(2, 2)).Mul(NewM3Translation(NewV2(1, 1))), want: NewV2(4, 6)},
+		{name: "orthographic", m: NewM3Orthographic(NewV2(-1000, -1000), NewV2(1000, 1000)), want: NewV2(0.001, 0.002)},
+		{name: "orthographic offset", m: NewM3Orthographic(NewV2(1, 2), NewV2(3, 4)), want: NewV2(-1, -1)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.m.TransformPoint(p)
+			if !approxEqualV2(got, tc.want) {
+				t.Errorf("(%v).TransformPoint(%v) = %v, want %v", tc.m, p, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestM3Inverse(t *testing.T) {
+	m := NewM3Translation(NewV2(3, 4)).Mul(NewM3Rotation(0.5)).Mul(NewM3Scale(NewV2(2, 3)))
+	inv, ok := m.Inverse()
+	if !ok {
+		t.Fatalf("(%v).Inverse() failed, want success", m)
+	}
+	got := inv.Mul(m)
+	for i := 0; i < 3; i++ {
+		if !approxEqualV3(got.Col(i), IdentityM3().Col(i)) {
+			t.Errorf("m.Inverse() * m = %v, want identity", got)
+		}
+	}
+
+	if _, ok := NewM3Scale(NewV2(0, 1)).Inverse(); ok {
+		t.Errorf("Inverse() of singular matrix succeeded, want failure")
+	}
+}
+
+func TestM3Transpose(t *testing.T) {
+	m := NewM3(NewV3(1, 2, 3), NewV3(4, 5, 6), NewV3(7, 8, 9))
+	got := m.Transpose()
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			if got.At(row, col) != m.At(col, row) {
+				t.Errorf("Transpose().At(%d, %d) = %v, want %v", row, col, got.At(row, col), m.At(col, row))
+			}
+		}
+	}
+}
+
+func TestM4Inverse(t *testing.T) {
+	m := NewM4Translation(NewV3(1, 2, 3)).Mul(NewM4Scale(NewV3(2, 3, 4))).Mul(NewM4Perspective(1, 1.5, 0.1, 100))
+	inv, ok := m.Inverse()
+	if !ok {
+		t.Fatalf("(%v).Inverse() failed, want success", m)
+	}
+	if got := m.Mul(inv); !approxEqualM4(got, IdentityM4()) {
+		t.Errorf("m * m.Inverse() = %v, want identity", got)
+	}
+
+	if _, ok := NewM4Scale(NewV3(1, 0, 1)).Inverse(); ok {
+		t.Errorf("Inverse() of singular matrix succeeded, want failure")
+	}
+}
+
+func TestM4Orthographic(t *testing.T) {
+	m := NewM4Orthographic(-10, 10, -5, 5, 1, 100)
+	tests := []struct {
+		p    V4
+		want V4
+	}{
+		{p: NewV4(-10, -5, -1, 1), want: NewV4(-1, -1, 0, 1)},
+		{p: NewV4(10, 5, -100, 1), want: NewV4(1, 1, 1, 1)},
+		{p: NewV4(0, 0, -50.5, 1), want: NewV4(0, 0, 0.5, 1)},
+	}
+	for _, tc := range tests {
+		if got := m.MulV(tc.p); !approxEqualV4(got, tc.want) {
+			t.Errorf("(%v).MulV(%v) = %v, want %v", m, tc.p, got, tc.want)
+		}
+	}
+}
+
+func TestM4Perspective(t *testing.T) {
+	const near, far = 1, 100
+	m := NewM4Perspective(math32.Pi/2, 2, near, far)
+	tests := []struct {
+		p     V4
+		wantZ float32
+	}{
+		{p: NewV4(0, 0, -near, 1), wantZ: 0},
+		{p: NewV4(0, 0, -far, 1), wantZ: 1},
+	}
+	for _, tc := range tests {
+		got := m.MulV(tc.p)
+		if z := got.Z / got.W; !approxEqualF(z, tc.wantZ, eps) {
+			t.Errorf("(%v).MulV(%v) depth = %v, want %v", m, tc.p, z, tc.wantZ)
+		}
+	}
+
+	// A point on the edge of the 90 degree field of view should map to the edge of clip space.
+	got := m.MulV(NewV4(0, 1, -1, 1))
+	if y := got.Y / got.W; !approxEqualF(y, 1, eps) {
+		t.Errorf("(%v).MulV() y = %v, want 1", m, y)
+	}
+}
+
+func TestNonSquareTranspose(t *testing.T) {
+	m := NewM2x3(NewV3(1, 2, 3), NewV3(4, 5, 6))
+	tr := m.Transpose()
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 2; col++ {
+			if tr.At(col, row) != m.At(row, col) {
+				t.Errorf("Transpose().At(%d, %d) = %v, want %v", col, row, tr.At(col, row), m.At(row, col))
+			}
+		}
+	}
+	if got := tr.Transpose(); got != m {
+		t.Errorf("Transpose().Transpose() = %v, want %v", got, m)
+	}
+
+	if got, want := m.MulV(NewV2(1, 1)), NewV3(5, 7, 9); got != want {
+		t.Errorf("(%v).MulV() = %v, want %v", m, got, want)
+	}
+	if got, want := tr.MulV(NewV3(1, 0, 1)), NewV2(4, 10); got != want {
+		t.Errorf("(%v).MulV() = %v, want %v", tr, got, want)
+	}
+}
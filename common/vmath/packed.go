@@ -0,0 +1,75 @@
+package vmath
+
+import "github.com/hulkholden/gowebgpu/common/math32"
+
+// Packed types store normalized values in a single 32 bit word.
+// In WGSL they are u32 values which can be unpacked with the corresponding
+// unpack builtin (e.g. unpack4x8unorm), and when used as vertex attributes
+// they are unpacked automatically into vecN<f32>.
+
+// Unorm8x4 stores four values in the range [0, 1] with 8 bits of precision.
+type Unorm8x4 struct {
+	X, Y, Z, W uint8
+}
+
+func NewUnorm8x4(v V4) Unorm8x4 {
+	return Unorm8x4{X: packUnorm8(v.X), Y: packUnorm8(v.Y), Z: packUnorm8(v.Z), W: packUnorm8(v.W)}
+}
+
+func (u Unorm8x4) ToV4() V4 {
+	return V4{X: float32(u.X) / 255, Y: float32(u.Y) / 255, Z: float32(u.Z) / 255, W: float32(u.W) / 255}
+}
+
+// Snorm8x4 stores four values in the range [-1, 1] with 8 bits of precision.
+type Snorm8x4 struct {
+	X, Y, Z, W int8
+}
+
+func NewSnorm8x4(v V4) Snorm8x4 {
+	return Snorm8x4{X: packSnorm8(v.X), Y: packSnorm8(v.Y), Z: packSnorm8(v.Z), W: packSnorm8(v.W)}
+}
+
+func (s Snorm8x4) ToV4() V4 {
+	return V4{X: unpackSnorm(float32(s.X), 127), Y: unpackSnorm(float32(s.Y), 127), Z: unpackSnorm(float32(s.Z), 127), W: unpackSnorm(float32(s.W), 127)}
+}
+
+// Unorm16x2 stores two values in the range [0, 1] with 16 bits of precision.
+type Unorm16x2 struct {
+	X, Y uint16
+}
+
+func NewUnorm16x2(v V2) Unorm16x2 {
+	return Unorm16x2{X: packUnorm16(v.X), Y: packUnorm16(v.Y)}
+}
+
+func (u Unorm16x2) ToV2() V2 {
+	return V2{X: float32(u.X) / 65535, Y: float32(u.Y) / 65535}
+}
+
+// Snorm16x2 stores two values in the range [-1, 1] with 16 bits of precision.
+type Snorm16x2 struct {
+	X, Y int16
+}
+
+func NewSnorm16x2(v V2) Snorm16x2 {
+	return Snorm16x2{X: packSnorm16(v.X), Y: packSnorm16(v.Y)}
+}
+
+func (s Snorm16x2) ToV2() V2 {
+	return V2{X: unpackSnorm(float32(s.X), 32767), Y: unpackSnorm(float32(s.Y), 32767)}
+}
+
+func packUnorm8(f float32) uint8   { return uint8(math32.Floor(math32.Clamp(f, 0, 1)*255 + 0.5)) }
+func packUnorm16(f float32) uint16 { return uint16(math32.Floor(math32.Clamp(f, 0, 1)*65535 + 0.5)) }
+func packSnorm8(f float32) int8    { return int8(roundHalfAway(math32.Clamp(f, -1, 1) * 127)) }
+func packSnorm16(f float32) int16  { return int16(roundHalfAway(math32.Clamp(f, -1, 1) * 32767)) }
+
+// unpackSnorm follows the WebGPU conversion rules, where the most negative value maps to -1.
+func unpackSnorm(v, scale float32) float32 { return math32.Max(v/scale, -1) }
+
+func roundHalfAway(f float32) float32 {
+	if f < 0 {
+		return -math32.Floor(-f + 0.5)
+	}
+	return math32.Floor(f + 0.5)
+}
@@ -0,0 +1,37 @@
+package vmath
+
+import "testing"
+
+func TestNewUnorm8x4(t *testing.T) {
+	got := NewUnorm8x4(NewV4(0, 0.5, 1, 2))
+	want := Unorm8x4{X: 0, Y: 128, Z: 255, W: 255}
+	if got != want {
+		t.Errorf("NewUnorm8x4() = %v, want %v", got, want)
+	}
+	if v := want.ToV4(); v.X != 0 || v.Z != 1 {
+		t.Errorf("(%v).ToV4() = %v, want X=0 and Z=1", want, v)
+	}
+}
+
+func TestNewSnorm8x4(t *testing.T) {
+	got := NewSnorm8x4(NewV4(-1, -0.5, 0.5, 2))
+	want := Snorm8x4{X: -127, Y: -64, Z: 64, W: 127}
+	if got != want {
+		t.Errorf("NewSnorm8x4() = %v, want %v", got, want)
+	}
+	// The most negative value must be clamped to -1.
+	if v := (Snorm8x4{X: -128}).ToV4(); v.X != -1 {
+		t.Errorf("Snorm8x4{X: -128}.ToV4().X = %v, want -1", v.X)
+	}
+}
+
+func TestPacked16x2RoundTrip(t *testing.T) {
+	v := NewV2(0.25, 1)
+	if got := NewUnorm16x2(v).ToV2(); !approxEqualV2(got, v) {
+		t.Errorf("NewUnorm16x2(%v).ToV2() = %v", v, got)
+	}
+	w := NewV2(-0.75, 0.5)
+	if got := NewSnorm16x2(w).ToV2(); !approxEqualF(got.X, w.X, 1e-4) || !approxEqualF(got.Y, w.Y, 1e-4) {
+		t.Errorf("NewSnorm16x2(%v).ToV2() = %v", w, got)
+	}
+}
@@ -5,3 +5,16 @@ type V3 struct {
 }
 
 func NewV3(x, y, z float32) V3 { return V3{X: x, Y: y, Z: z} }
+
+func (v V3) Add(w V3) V3        { return V3{X: v.X + w.X, Y: v.Y + w.Y, Z: v.Z + w.Z} }
+func (v V3) Sub(w V3) V3        { return V3{X: v.X - w.X, Y: v.Y - w.Y, Z: v.Z - w.Z} }
+func (v V3) Scale(s float32) V3 { return V3{X: v.X * s, Y: v.Y * s, Z: v.Z * s} }
+func (v V3) Dot(w V3) float32   { return v.X*w.X + v.Y*w.Y + v.Z*w.Z }
+
+func (v V3) Cross(w V3) V3 {
+	return V3{
+		X: v.Y*w.Z - v.Z*w.Y,
+		Y: v.Z*w.X - v.X*w.Z,
+		Z: v.X*w.Y - v.Y*w.X,
+	}
+}
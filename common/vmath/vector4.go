@@ -5,3 +5,7 @@ type V4 struct {
 }
 
 func NewV4(x, y, z, w float32) V4 { return V4{X: x, Y: y, Z: z, W: w} }
+
+func (v V4) Add(w V4) V4        { return V4{X: v.X + w.X, Y: v.Y + w.Y, Z: v.Z + w.Z, W: v.W + w.W} }
+func (v V4) Scale(s float32) V4 { return V4{X: v.X * s, Y: v.Y * s, Z: v.Z * s, W: v.W * s} }
+func (v V4) Dot(w V4) float32   { return v.X*w.X + v.Y*w.Y + v.Z*w.Z + v.W*w.W }
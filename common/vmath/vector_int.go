@@ -0,0 +1,65 @@
+package vmath
+
+// Integer vectors correspond to the WGSL vecN<i32> and vecN<u32> types.
+
+type V2i struct {
+	X, Y int32
+}
+
+func NewV2i(x, y int32) V2i { return V2i{X: x, Y: y} }
+
+func (v V2i) Add(w V2i) V2i { return V2i{X: v.X + w.X, Y: v.Y + w.Y} }
+func (v V2i) Sub(w V2i) V2i { return V2i{X: v.X - w.X, Y: v.Y - w.Y} }
+func (v V2i) Mul(w V2i) V2i { return V2i{X: v.X * w.X, Y: v.Y * w.Y} }
+func (v V2i) ToV2() V2      { return V2{X: float32(v.X), Y: float32(v.Y)} }
+
+type V3i struct {
+	X, Y, Z int32
+}
+
+func NewV3i(x, y, z int32) V3i { return V3i{X: x, Y: y, Z: z} }
+
+func (v V3i) Add(w V3i) V3i { return V3i{X: v.X + w.X, Y: v.Y + w.Y, Z: v.Z + w.Z} }
+func (v V3i) Sub(w V3i) V3i { return V3i{X: v.X - w.X, Y: v.Y - w.Y, Z: v.Z - w.Z} }
+func (v V3i) Mul(w V3i) V3i { return V3i{X: v.X * w.X, Y: v.Y * w.Y, Z: v.Z * w.Z} }
+
+type V4i struct {
+	X, Y, Z, W int32
+}
+
+func NewV4i(x, y, z, w int32) V4i { return V4i{X: x, Y: y, Z: z, W: w} }
+
+func (v V4i) Add(w V4i) V4i { return V4i{X: v.X + w.X, Y: v.Y + w.Y, Z: v.Z + w.Z, W: v.W + w.W} }
+func (v V4i) Sub(w V4i) V4i { return V4i{X: v.X - w.X, Y: v.Y - w.Y, Z: v.Z - w.Z, W: v.W - w.W} }
+func (v V4i) Mul(w V4i) V4i { return V4i{X: v.X * w.X, Y: v.Y * w.Y, Z: v.Z * w.Z, W: v.W * w.W} }
+
+type V2u struct {
+	X, Y uint32
+}
+
+func NewV2u(x, y uint32) V2u { return V2u{X: x, Y: y} }
+
+func (v V2u) Add(w V2u) V2u { return V2u{X: v.X + w.X, Y: v.Y + w.Y} }
+func (v V2u) Sub(w V2u) V2u { return V2u{X: v.X - w.X, Y: v.Y - w.Y} }
+func (v V2u) Mul(w V2u) V2u { return V2u{X: v.X * w.X, Y: v.Y * w.Y} }
+func (v V2u) ToV2() V2      { return V2{X: float32(v.X), Y: float32(v.Y)} }
+
+type V3u struct {
+	X, Y, Z uint32
+}
+
+func NewV3u(x, y, z uint32) V3u { return V3u{X: x, Y: y, Z: z} }
+
+func (v V3u) Add(w V3u) V3u { return V3u{X: v.X + w.X, Y: v.Y + w.Y, Z: v.Z + w.Z} }
+func (v V3u) Sub(w V3u) V3u { return V3u{X: v.X - w.X, Y: v.Y - w.Y, Z: v.Z - w.Z} }
+func (v V3u) Mul(w V3u) V3u { return V3u{X: v.X * w.X, Y: v.Y * w.Y, Z: v.Z * w.Z} }
+
+type V4u struct {
+	X, Y, Z, W uint32
+}
+
+func NewV4u(x, y, z, w uint32) V4u { return V4u{X: x, Y: y, Z: z, W: w} }
+
+func (v V4u) Add(w V4u) V4u { return V4u{X: v.X + w.X, Y: v.Y + w.Y, Z: v.Z + w.Z, W: v.W + w.W} }
+func (v V4u) Sub(w V4u) V4u { return V4u{X: v.X - w.X, Y: v.Y - w.Y, Z: v.Z - w.Z, W: v.W - w.W} }
+func (v V4u) Mul(w V4u) V4u { return V4u{X: v.X * w.X, Y: v.Y * w.Y, Z: v.Z * w.Z, W: v.W * w.W} }
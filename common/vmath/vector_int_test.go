@@ -0,0 +1,39 @@
+package vmath
+
+import "testing"
+
+func TestV2iArithmetic(t *testing.T) {
+	v := NewV2i(1, -2)
+	w := NewV2i(3, 4)
+	if got, want := v.Add(w), NewV2i(4, 2); got != want {
+		t.Errorf("(%v).Add(%v) = %v, want %v", v, w, got, want)
+	}
+	if got, want := v.Sub(w), NewV2i(-2, -6); got != want {
+		t.Errorf("(%v).Sub(%v) = %v, want %v", v, w, got, want)
+	}
+	if got, want := v.Mul(w), NewV2i(3, -8); got != want {
+		t.Errorf("(%v).Mul(%v) = %v, want %v", v, w, got, want)
+	}
+	if got, want := v.ToV2(), NewV2(1, -2); got != want {
+		t.Errorf("(%v).ToV2() = %v, want %v", v, got, want)
+	}
+}
+
+func TestV2uWrapsOnOverflow(t *testing.T) {
+	// Matches WGSL's u32 semantics, which hash functions such as hash22 rely on.
+	v := NewV2u(0xffffffff, 1664525)
+	w := NewV2u(1, 1013904223)
+	if got, want := v.Add(w), NewV2u(0, 1664525+1013904223); got != want {
+		t.Errorf("(%v).Add(%v) = %v, want %v", v, w, got, want)
+	}
+	if got, want := NewV2u(0, 1).Sub(NewV2u(1, 1)), NewV2u(0xffffffff, 0); got != want {
+		t.Errorf("Sub() = %v, want %v", got, want)
+	}
+}
+
+func TestV4uMul(t *testing.T) {
+	v := NewV4u(1, 2, 3, 4)
+	if got, want := v.Mul(v), NewV4u(1, 4, 9, 16); got != want {
+		t.Errorf("(%v).Mul(%v) = %v, want %v", v, v, got, want)
+	}
+}
@@ -0,0 +1,172 @@
+// Package wgsl extracts information from WGSL source without needing a full parser.
+package wgsl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AccessMode is the access mode of a variable in the storage address space.
+type AccessMode string
+
+const (
+	AccessRead      AccessMode = "read"
+	AccessWrite     AccessMode = "write"
+	AccessReadWrite AccessMode = "read_write"
+)
+
+// A Binding is a resource variable declared with @group and @binding attributes.
+type Binding struct {
+	// Group is the value of the @group attribute.
+	Group int
+	// Binding is the value of the @binding attribute.
+	Binding int
+	// Name is the name of the variable.
+	Name string
+	// AddressSpace is the variable's address space, e.g. "uniform" or "storage".
+	AddressSpace string
+	// Access is the variable's access mode. Variables in the uniform address space are always AccessRead,
+	// and storage variables default to AccessRead if no access mode is specified.
+	Access AccessMode
+	// Type is the WGSL type of the variable, with whitespace removed.
+	Type string
+	// Line is the 1-based line number of the declaration.
+	Line int
+}
+
+func (b Binding) String() string {
+	return fmt.Sprintf("@group(%d) @binding(%d) var<%s, %s> %s : %s", b.Group, b.Binding, b.AddressSpace, b.Access, b.Name, b.Type)
+}
+
+var (
+	// varDeclRegexp matches a var declaration with an address space, and the attributes which precede it.
+	varDeclRegexp = regexp.MustCompile(`((?:@\w+\s*(?:\([^)]*\))?\s*)*)\bvar\s*<\s*(\w+)\s*(?:,\s*(\w+)\s*)?>\s*(\w+)\s*:\s*([^;=]+);`)
+	attrRegexp    = regexp.MustCompile(`@(\w+)\s*(?:\(\s*([^)]*?)\s*\))?`)
+	spaceRegexp   = regexp.MustCompile(`\s+`)
+)
+
+// ParseBindings returns all the uniform and storage buffer variables declared in src, sorted by group and binding.
+// Variables in other address spaces (e.g. private and workgroup) are ignored.
+func ParseBindings(src string) ([]Binding, error) {
+	src = StripComments(src)
+
+	var bindings []Binding
+	seen := make(map[[2]int]Binding)
+	for _, m := range varDeclRegexp.FindAllStringSubmatchIndex(src, -1) {
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return src[m[2*i]:m[2*i+1]]
+		}
+		space := group(2)
+		if space != "uniform" && space != "storage" {
+			continue
+		}
+
+		b := Binding{
+			Group:        -1,
+			Binding:      -1,
+			Name:         group(4),
+			AddressSpace: space,
+			Access:       AccessMode(group(3)),
+			Type:         spaceRegexp.ReplaceAllString(group(5), ""),
+			Line:         LineOf(src, m[0]),
+		}
+		if b.Access == "" || space == "uniform" {
+			b.Access = AccessRead
+		}
+		for _, attr := range attrRegexp.FindAllStringSubmatch(group(1), -1) {
+			var dst *int
+			switch attr[1] {
+			case "group":
+				dst = &b.Group
+			case "binding":
+				dst = &b.Binding
+			default:
+				continue
+			}
+			v, err := strconv.Atoi(attr[2])
+			if err != nil {
+				return nil, lineErrorf(b.Line, "variable %q: unsupported @%s value %q", b.Name, attr[1], attr[2])
+			}
+			*dst = v
+		}
+		if b.Group < 0 || b.Binding < 0 {
+			return nil, lineErrorf(b.Line, "variable %q: missing @group or @binding attribute", b.Name)
+		}
+
+		key := [2]int{b.Group, b.Binding}
+		if prev, ok := seen[key]; ok {
+			err := lineErrorf(b.Line, "variable %q: @group(%d) @binding(%d) is already used by %q", b.Name, b.Group, b.Binding, prev.Name)
+			err.Related = prev.Line
+			return nil, err
+		}
+		seen[key] = b
+		bindings = append(bindings, b)
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].Group != bindings[j].Group {
+			return bindings[i].Group < bindings[j].Group
+		}
+		return bindings[i].Binding < bindings[j].Binding
+	})
+	return bindings, nil
+}
+
+// A LineError is an error at a particular line of WGSL source.
+type LineError struct {
+	// Line is the 1-based line number of the error.
+	Line int
+	// Msg describes the error.
+	Msg string
+	// Related is the 1-based line number of a related declaration, or 0 if there is none.
+	Related int
+}
+
+func (e *LineError) Error() string {
+	if e.Related > 0 {
+		return fmt.Sprintf("line %d: %s on line %d", e.Line, e.Msg, e.Related)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+func lineErrorf(line int, format string, args ...any) *LineError {
+	return &LineError{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// StripComments replaces line and block comments in src with spaces.
+// Newlines are preserved so offsets and line numbers are unchanged.
+func StripComments(src string) string {
+	out := []byte(src)
+	depth := 0
+	for i := 0; i < len(out); i++ {
+		switch {
+		case depth == 0 && strings.HasPrefix(src[i:], "//"):
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			// Block comments can be nested.
+			depth++
+			out[i], out[i+1] = ' ', ' '
+			i++
+		case depth > 0 && strings.HasPrefix(src[i:], "*/"):
+			depth--
+			out[i], out[i+1] = ' ', ' '
+			i++
+		case depth > 0 && out[i] != '\n':
+			out[i] = ' '
+		}
+	}
+	return string(out)
+}
+
+// LineOf returns the 1-based line number of the byte at offset in src.
+func LineOf(src string, offset int) int {
+	return strings.Count(src[:offset], "\n") + 1
+}
@@ -0,0 +1,106 @@
+package wgsl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseBindings(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    []Binding
+		wantErr string
+	}{
+		{
+			name: "empty",
+			src:  "fn main() {}",
+			want: nil,
+		},
+		{
+			name: "uniform and storage",
+			src: `
+@binding(0) @group(0) var<uniform> params : SimParams;
+@binding(1) @group(0) var<storage, read_write> gBodies : array<Body>;
+@group(0) @binding(2) var<storage> gRead : array<vec2<f32>, 4>;
+`,
+			want: []Binding{
+				{Group: 0, Binding: 0, Name: "params", AddressSpace: "uniform", Access: AccessRead, Type: "SimParams", Line: 2},
+				{Group: 0, Binding: 1, Name: "gBodies", AddressSpace: "storage", Access: AccessReadWrite, Type: "array<Body>", Line: 3},
+				{Group: 0, Binding: 2, Name: "gRead", AddressSpace: "storage", Access: AccessRead, Type: "array<vec2<f32>,4>", Line: 4},
+			},
+		},
+		{
+			name: "sorted by group and binding",
+			src: `
+@group(1) @binding(0) var<storage, read> b : array<u32>;
+@group(0) @binding(3) var<storage, read> a : array<u32>;
+@group(0)
+@binding(1)
+var<storage, read_write> c : array<u32>;
+`,
+			want: []Binding{
+				{Group: 0, Binding: 1, Name: "c", AddressSpace: "storage", Access: AccessReadWrite, Type: "array<u32>", Line: 4},
+				{Group: 0, Binding: 3, Name: "a", AddressSpace: "storage", Access: AccessRead, Type: "array<u32>", Line: 3},
+				{Group: 1, Binding: 0, Name: "b", AddressSpace: "storage", Access: AccessRead, Type: "array<u32>", Line: 2},
+			},
+		},
+		{
+			name: "comments and other address spaces are ignored",
+			src: `
+// @group(0) @binding(0) var<uniform> commented : SimParams;
+/* @group(0) @binding(1) var<uniform> block : SimParams;
+   /* nested */ @group(0) @binding(2) var<uniform> stillComment : SimParams; */
+var<private> rng : u32;
+var<workgroup> tile : array<f32, 64>;
+@group(0) @binding(0) var<uniform> real : SimParams; // trailing comment
+`,
+			want: []Binding{
+				{Group: 0, Binding: 0, Name: "real", AddressSpace: "uniform", Access: AccessRead, Type: "SimParams", Line: 7},
+			},
+		},
+		{
+			name:    "missing binding",
+			src:     `@group(0) var<uniform> params : SimParams;`,
+			wantErr: `line 1: variable "params": missing @group or @binding attribute`,
+		},
+		{
+			name: "duplicate binding",
+			src: `@group(0) @binding(0) var<uniform> a : SimParams;
+@group(0) @binding(0) var<uniform> b : SimParams;`,
+			wantErr: `line 2: variable "b": @group(0) @binding(0) is already used by "a" on line 1`,
+		},
+		{
+			name:    "non-literal binding",
+			src:     `@group(0) @binding(idx) var<uniform> a : SimParams;`,
+			wantErr: `unsupported @binding value "idx"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseBindings(tc.src)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("ParseBindings() error = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseBindings() = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("diff mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	src := "a // b\nc /* d\n/* e */ f */ g"
+	want := "a     \nc     \n             g"
+	if got := StripComments(src); got != want {
+		t.Errorf("StripComments(%q) = %q, want %q", src, got, want)
+	}
+}
@@ -0,0 +1,477 @@
+package wgsl
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+// PrologueFile is the file name used for lines of the prologue generated from Go declarations.
+const PrologueFile = "<prologue>"
+
+// A Diagnostic is a problem found in a shader.
+type Diagnostic struct {
+	// Location is the original location of the problem.
+	Location Location
+	// Message describes the problem.
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Location, d.Message)
+}
+
+// ModuleSource returns the complete source of a shader module: src with the declarations of structs,
+// all the structs they depend on, and consts prepended.
+func ModuleSource(src Source, structs []wgsltypes.Struct, consts []wgsltypes.Const) Source {
+	return src.WithPrologue(PrologueFile, wgsltypes.Prologue(structs, consts))
+}
+
+// Check looks for problems in the source of a shader module which would otherwise only be reported
+// when the browser compiles it.
+//
+// Check doesn't fully parse WGSL. It reports unbalanced brackets, malformed binding and entry point
+// declarations, binding collisions, redeclared and undeclared identifiers, and accesses to fields that
+// don't exist through module-scope variables. Scoping isn't modelled, so an identifier declared anywhere
+// in the module is treated as declared everywhere.
+func Check(src Source) []Diagnostic {
+	c := checker{
+		src:     src,
+		toks:    lex(StripComments(src.Code)),
+		structs: map[string]map[string]string{},
+		vars:    map[string]string{},
+	}
+	c.checkBrackets()
+	c.checkDeclarations()
+	c.checkIdentifiers()
+	c.checkFieldAccesses()
+
+	sort.SliceStable(c.diags, func(i, j int) bool { return c.diags[i].line < c.diags[j].line })
+	diags := make([]Diagnostic, len(c.diags))
+	for i, d := range c.diags {
+		diags[i] = Diagnostic{Location: c.location(d.line), Message: d.msg}
+	}
+	return diags
+}
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenNumber
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// lex splits WGSL, with comments already stripped, into tokens.
+// Punctuation is returned one character at a time.
+func lex(src string) []token {
+	var toks []token
+	line := 1
+	for i := 0; i < len(src); {
+		r := src[i]
+		start := i
+		switch {
+		case r == '\n':
+			line++
+			i++
+			continue
+		case r == ' ' || r == '\t' || r == '\r':
+			i++
+			continue
+		case isIdentStart(rune(r)):
+			for i < len(src) && isIdentPart(rune(src[i])) {
+				i++
+			}
+			toks = append(toks, token{kind: tokenIdent, text: src[start:i], line: line})
+		case r >= '0' && r <= '9' || r == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			hex := strings.HasPrefix(src[i:], "0x") || strings.HasPrefix(src[i:], "0X")
+			for i < len(src) && (isIdentPart(rune(src[i])) || src[i] == '.') {
+				i++
+				// Consume the sign of a decimal exponent.
+				if e := src[i-1]; !hex && (e == 'e' || e == 'E') && i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+			}
+			toks = append(toks, token{kind: tokenNumber, text: src[start:i], line: line})
+		default:
+			i++
+			toks = append(toks, token{kind: tokenPunct, text: src[start:i], line: line})
+		}
+	}
+	return toks
+}
+
+type diag struct {
+	line int
+	msg  string
+}
+
+type checker struct {
+	src  Source
+	toks []token
+
+	diags []diag
+
+	// declared holds every identifier declared anywhere in the module.
+	declared map[string]bool
+	// structs maps struct names to their field names and types.
+	structs map[string]map[string]string
+	// vars maps module-scope variable names to their types.
+	vars map[string]string
+}
+
+func (c *checker) errorf(line int, format string, args ...any) {
+	c.diags = append(c.diags, diag{line: line, msg: fmt.Sprintf(format, args...)})
+}
+
+// location returns the original location of the line in the checked code.
+func (c *checker) location(line int) Location {
+	if loc, ok := c.src.Location(line); ok {
+		return loc
+	}
+	return Location{File: PrologueFile, Line: line}
+}
+
+func (c *checker) tok(i int) token {
+	if i < 0 || i >= len(c.toks) {
+		return token{kind: tokenPunct}
+	}
+	return c.toks[i]
+}
+
+// isAttribute returns true if the i'th token is the name of an attribute.
+func (c *checker) isAttribute(i int) bool {
+	return c.tok(i-1).text == "@"
+}
+
+// isMember returns true if the i'th token is the name of a member being accessed.
+func (c *checker) isMember(i int) bool {
+	return c.tok(i-1).text == "."
+}
+
+var closingBrackets = map[string]string{"(": ")", "[": "]", "{": "}"}
+
+func (c *checker) checkBrackets() {
+	var open []token
+	for _, t := range c.toks {
+		switch t.text {
+		case "(", "[", "{":
+			open = append(open, t)
+		case ")", "]", "}":
+			if len(open) == 0 {
+				c.errorf(t.line, "unexpected %q", t.text)
+				continue
+			}
+			last := open[len(open)-1]
+			open = open[:len(open)-1]
+			if want := closingBrackets[last.text]; t.text != want {
+				c.errorf(t.line, "unexpected %q, want %q to close %q opened at %s", t.text, want, last.text, c.location(last.line))
+			}
+		}
+	}
+	for _, t := range open {
+		c.errorf(t.line, "unclosed %q", t.text)
+	}
+}
+
+func (c *checker) checkDeclarations() {
+	code := c.src.Code
+	_, err := ParseBindings(code)
+	c.addParseError("bindings", err)
+	_, err = ParseEntryPoints(code)
+	c.addParseError("entry points", err)
+	_, err = ParseConstants(code)
+	c.addParseError("constants", err)
+
+	c.declared = map[string]bool{}
+	moduleScope := map[string]int{}
+	declare := func(i int, moduleLevel bool) {
+		t := c.tok(i)
+		if t.kind != tokenIdent {
+			c.errorf(c.tok(i-1).line, "expected a name after %q", c.tok(i-1).text)
+			return
+		}
+		c.declared[t.text] = true
+		if !moduleLevel {
+			return
+		}
+		if prev, ok := moduleScope[t.text]; ok {
+			c.errorf(t.line, "%q is already declared at %s", t.text, c.location(prev))
+			return
+		}
+		moduleScope[t.text] = t.line
+	}
+
+	depth := 0
+	for i := 0; i < len(c.toks); i++ {
+		t := c.toks[i]
+		switch t.text {
+		case "{":
+			depth++
+			continue
+		case "}":
+			depth--
+			continue
+		}
+		if t.kind != tokenIdent || c.isAttribute(i) || c.isMember(i) {
+			continue
+		}
+		switch t.text {
+		case "fn", "alias", "override":
+			declare(i+1, depth == 0)
+		case "struct":
+			declare(i+1, true)
+			c.parseStruct(i + 1)
+		case "const", "let":
+			declare(i+1, depth == 0)
+		case "var":
+			j := i + 1
+			if c.tok(j).text == "<" {
+				j = c.skipTemplate(j)
+			}
+			declare(j, depth == 0)
+			if depth == 0 && c.tok(j+1).text == ":" {
+				c.vars[c.tok(j).text] = c.typeAt(j + 2)
+			}
+		default:
+			// Parameters, struct members and explicitly typed declarations.
+			if c.tok(i+1).text == ":" {
+				c.declared[t.text] = true
+			}
+		}
+	}
+}
+
+func (c *checker) addParseError(what string, err error) {
+	if err == nil {
+		return
+	}
+	var lineErr *LineError
+	if errors.As(err, &lineErr) {
+		if lineErr.Related > 0 {
+			c.errorf(lineErr.Line, "%s at %s", lineErr.Msg, c.location(lineErr.Related))
+			return
+		}
+		c.errorf(lineErr.Line, "%s", lineErr.Msg)
+		return
+	}
+	c.errorf(1, "parsing %s: %v", what, err)
+}
+
+// skipTemplate returns the index of the token after the template list starting at the '<' at i.
+func (c *checker) skipTemplate(i int) int {
+	depth := 0
+	for ; i < len(c.toks); i++ {
+		switch c.toks[i].text {
+		case "<":
+			depth++
+		case ">":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case ";", "{", "=":
+			return i
+		}
+	}
+	return i
+}
+
+// typeAt returns the type starting at token i, with whitespace removed.
+func (c *checker) typeAt(i int) string {
+	var b strings.Builder
+	depth := 0
+	for ; i < len(c.toks); i++ {
+		t := c.toks[i].text
+		switch t {
+		case "<":
+			depth++
+		case ">":
+			depth--
+		case ",", ";", "=", "}", ")", "{":
+			if depth <= 0 {
+				return b.String()
+			}
+		}
+		if depth < 0 {
+			return b.String()
+		}
+		b.WriteString(t)
+	}
+	return b.String()
+}
+
+// parseStruct records the fields of the struct whose name is at token i.
+func (c *checker) parseStruct(i int) {
+	name := c.tok(i).text
+	if c.tok(i+1).text != "{" {
+		return
+	}
+	fields := map[string]string{}
+	c.structs[name] = fields
+	for j := i + 2; j < len(c.toks) && c.toks[j].text != "}"; j++ {
+		t := c.toks[j]
+		if t.kind == tokenIdent && !c.isAttribute(j) && c.tok(j+1).text == ":" {
+			fields[t.text] = c.typeAt(j + 2)
+		}
+	}
+}
+
+func (c *checker) checkIdentifiers() {
+	reported := map[string]bool{}
+	for i, t := range c.toks {
+		if t.kind != tokenIdent || c.isAttribute(i) || c.isMember(i) {
+			continue
+		}
+		if c.declared[t.text] || builtinIdents[t.text] || reported[t.text] {
+			continue
+		}
+		reported[t.text] = true
+		c.errorf(t.line, "undeclared identifier %q", t.text)
+	}
+}
+
+func (c *checker) checkFieldAccesses() {
+	for i, t := range c.toks {
+		typ, ok := c.vars[t.text]
+		if !ok || t.kind != tokenIdent || c.isMember(i) || c.isAttribute(i) || c.tok(i+1).text == ":" {
+			continue
+		}
+		expr := t.text
+		for j := i + 1; typ != ""; {
+			switch c.tok(j).text {
+			case "[":
+				j = c.skipBrackets(j)
+				typ = elemType(typ)
+				expr += "[]"
+				continue
+			case ".":
+				field := c.tok(j + 1)
+				fields, isStruct := c.structs[typ]
+				if !isStruct {
+					typ = ""
+					continue
+				}
+				fieldType, ok := fields[field.text]
+				if !ok {
+					c.errorf(field.line, "%s (of type %s) has no field %q", expr, typ, field.text)
+					typ = ""
+					continue
+				}
+				typ = fieldType
+				expr += "." + field.text
+				j += 2
+				continue
+			}
+			break
+		}
+	}
+}
+
+// skipBrackets returns the index of the token after the brackets which open at i.
+func (c *checker) skipBrackets(i int) int {
+	depth := 0
+	for ; i < len(c.toks); i++ {
+		switch c.toks[i].text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// elemType returns the element type of an array type, or "" for other types.
+func elemType(typ string) string {
+	if !strings.HasPrefix(typ, "array<") || !strings.HasSuffix(typ, ">") {
+		return ""
+	}
+	args := splitTemplateArgs(typ[len("array<") : len(typ)-1])
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// splitTemplateArgs splits a comma separated list of template arguments, ignoring commas in nested lists.
+func splitTemplateArgs(s string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(args, s[start:])
+}
+
+// builtinIdents are the predeclared identifiers and context-dependent names which can appear in WGSL.
+var builtinIdents = makeSet(`
+	alias break case const const_assert continue continuing default diagnostic discard else enable
+	false fn for if let loop override requires return struct switch true var while
+
+	function private workgroup uniform storage handle read write read_write
+
+	bool i32 u32 f32 f16 array atomic ptr sampler sampler_comparison
+	vec2 vec3 vec4 vec2i vec3i vec4i vec2u vec3u vec4u vec2f vec3f vec4f vec2h vec3h vec4h
+	mat2x2 mat2x3 mat2x4 mat3x2 mat3x3 mat3x4 mat4x2 mat4x3 mat4x4
+	mat2x2f mat2x3f mat2x4f mat3x2f mat3x3f mat3x4f mat4x2f mat4x3f mat4x4f
+	mat2x2h mat2x3h mat2x4h mat3x2h mat3x3h mat3x4h mat4x2h mat4x3h mat4x4h
+	texture_1d texture_2d texture_2d_array texture_3d texture_cube texture_cube_array
+	texture_multisampled_2d texture_external texture_storage_1d texture_storage_2d
+	texture_storage_2d_array texture_storage_3d texture_depth_2d texture_depth_2d_array
+	texture_depth_cube texture_depth_cube_array texture_depth_multisampled_2d
+	rgba8unorm rgba8snorm rgba8uint rgba8sint rgba16uint rgba16sint rgba16float
+	r32uint r32sint r32float rg32uint rg32sint rg32float rgba32uint rgba32sint rgba32float bgra8unorm
+
+	vertex_index instance_index position front_facing frag_depth sample_index sample_mask
+	local_invocation_id local_invocation_index global_invocation_id workgroup_id num_workgroups
+	perspective linear flat center centroid sample first either
+
+	abs acos acosh all any arrayLength asin asinh atan atan2 atanh bitcast ceil clamp cos cosh
+	countLeadingZeros countOneBits countTrailingZeros cross degrees determinant distance dot
+	dot4U8Packed dot4I8Packed exp exp2 extractBits faceForward firstLeadingBit firstTrailingBit
+	floor fma fract frexp insertBits inverseSqrt ldexp length log log2 max min mix modf normalize
+	pow quantizeToF16 radians reflect refract reverseBits round saturate select sign sin sinh
+	smoothstep sqrt step tan tanh transpose trunc
+	dpdx dpdxCoarse dpdxFine dpdy dpdyCoarse dpdyFine fwidth fwidthCoarse fwidthFine
+	atomicLoad atomicStore atomicAdd atomicSub atomicMax atomicMin atomicAnd atomicOr atomicXor
+	atomicExchange atomicCompareExchangeWeak
+	pack4x8snorm pack4x8unorm pack4xI8 pack4xU8 pack4xI8Clamp pack4xU8Clamp pack2x16snorm
+	pack2x16unorm pack2x16float unpack4x8snorm unpack4x8unorm unpack4xI8 unpack4xU8
+	unpack2x16snorm unpack2x16unorm unpack2x16float
+	storageBarrier textureBarrier workgroupBarrier workgroupUniformLoad
+	textureDimensions textureGather textureGatherCompare textureLoad textureNumLayers
+	textureNumLevels textureNumSamples textureSample textureSampleBias textureSampleCompare
+	textureSampleCompareLevel textureSampleGrad textureSampleLevel textureSampleBaseClampToEdge
+	textureStore
+`)
+
+func makeSet(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, f := range strings.Fields(s) {
+		set[f] = true
+	}
+	return set
+}
@@ -0,0 +1,125 @@
+package wgsl
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+type checkItem struct {
+	pos   vmath.V2
+	count uint32
+	pad   uint32
+}
+
+type checkContainer struct {
+	total uint32 `atomic:"true"`
+	pad   uint32
+	items [4]checkItem `runtimeArray:"true"`
+}
+
+func TestCheck(t *testing.T) {
+	wgsltypes.MustRegisterStruct[checkItem]()
+	container := wgsltypes.MustRegisterStruct[checkContainer]()
+	structs := []wgsltypes.Struct{container}
+	consts := []wgsltypes.Const{wgsltypes.MustNewConst("limit", uint32(4))}
+
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "valid",
+			src: `@group(0) @binding(0) var<storage, read_write> gContainer : checkContainer;
+
+override workgroupSize : u32 = 64;
+
+fn scale(v : vec2f, s : f32) -> vec2f {
+  return v * s;
+}
+
+@compute @workgroup_size(workgroupSize)
+fn main(@builtin(global_invocation_id) id : vec3<u32>) {
+  let idx = id.x % limit;
+  var p = gContainer.items[idx].pos;
+  gContainer.items[idx].pos = scale(p, 2.0e-1);
+  atomicAdd(&gContainer.total, 1u);
+}
+`,
+		},
+		{
+			name: "unbalanced brackets",
+			src: `fn f() -> f32 {
+  return max(1.0, 2.0];
+}
+fn g() {
+`,
+			want: []string{
+				`main.wgsl:2: unexpected "]", want ")" to close "(" opened at main.wgsl:2`,
+				`main.wgsl:4: unclosed "{"`,
+			},
+		},
+		{
+			name: "undeclared",
+			src: `fn f(a : f32) -> f32 {
+  let b = a * twoPi;
+  return b + twoPi + lenght(vec2f(b));
+}
+`,
+			want: []string{
+				`main.wgsl:2: undeclared identifier "twoPi"`,
+				`main.wgsl:3: undeclared identifier "lenght"`,
+			},
+		},
+		{
+			name: "redeclared struct",
+			src: `struct checkItem {
+  pos : vec2f,
+}
+`,
+			want: []string{
+				`main.wgsl:1: "checkItem" is already declared at <prologue>:1`,
+			},
+		},
+		{
+			name: "binding collision",
+			src: `@group(0) @binding(0) var<uniform> a : vec4f;
+@group(0) @binding(0) var<uniform> b : vec4f;
+`,
+			want: []string{
+				`main.wgsl:2: variable "b": @group(0) @binding(0) is already used by "a" at main.wgsl:1`,
+			},
+		},
+		{
+			name: "missing field",
+			src: `@group(0) @binding(0) var<storage, read_write> gContainer : checkContainer;
+
+fn f(i : u32) -> f32 {
+  return gContainer.items[gContainer.items[i].count].position.x + f32(gContainer.size);
+}
+`,
+			want: []string{
+				`main.wgsl:4: gContainer.items[] (of type checkItem) has no field "position"`,
+				`main.wgsl:4: gContainer (of type checkContainer) has no field "size"`,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src, err := Preprocessor{}.Process("main.wgsl", tc.src)
+			if err != nil {
+				t.Fatalf("Process() = %v", err)
+			}
+			var got []string
+			for _, d := range Check(ModuleSource(src, structs, consts)) {
+				got = append(got, d.String())
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Check() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package wgsl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A Stage is the shader stage of an entry point.
+type Stage string
+
+const (
+	StageVertex   Stage = "vertex"
+	StageFragment Stage = "fragment"
+	StageCompute  Stage = "compute"
+)
+
+// An EntryPoint is a function declared with a shader stage attribute.
+type EntryPoint struct {
+	// Name is the name of the function.
+	Name string
+	// Stage is the shader stage of the entry point.
+	Stage Stage
+	// WorkgroupSize holds the expressions for the x, y and z dimensions of
+	// the @workgroup_size attribute of compute entry points. Omitted dimensions are "1".
+	WorkgroupSize [3]string
+	// Line is the 1-based line number of the declaration.
+	Line int
+}
+
+// A Constant is a module-scope const or override declaration.
+type Constant struct {
+	// Name is the name of the constant.
+	Name string
+	// Override is true if the constant is declared with override, so its value can be set when the pipeline is created.
+	Override bool
+	// ID is the value of the @id attribute of an override, or -1 if it has none.
+	ID int
+	// Type is the declared type of the constant, or empty if the type is inferred.
+	Type string
+	// Init is the initializer expression, or empty if an override has no default value.
+	Init string
+	// Line is the 1-based line number of the declaration.
+	Line int
+}
+
+var (
+	fnDeclRegexp    = regexp.MustCompile(`((?:@\w+\s*(?:\([^)]*\))?\s*)+)\bfn\s+(\w+)`)
+	constDeclRegexp = regexp.MustCompile(`(@id\s*\(\s*(\d+)\s*\)\s*)?\b(const|override)\s+(\w+)\s*(?::\s*([^=;]+?)\s*)?(?:=\s*([^;]+?)\s*)?;`)
+)
+
+// ParseEntryPoints returns all the entry points declared in src, in declaration order.
+func ParseEntryPoints(src string) ([]EntryPoint, error) {
+	src = StripComments(src)
+
+	var entryPoints []EntryPoint
+	for _, m := range fnDeclRegexp.FindAllStringSubmatchIndex(src, -1) {
+		ep := EntryPoint{
+			Name:          src[m[4]:m[5]],
+			WorkgroupSize: [3]string{"1", "1", "1"},
+			Line:          LineOf(src, m[0]),
+		}
+		hasWorkgroupSize := false
+		for _, attr := range attrRegexp.FindAllStringSubmatch(src[m[2]:m[3]], -1) {
+			switch name := attr[1]; name {
+			case "vertex", "fragment", "compute":
+				if ep.Stage != "" {
+					return nil, lineErrorf(ep.Line, "function %q: has both @%s and @%s attributes", ep.Name, ep.Stage, name)
+				}
+				ep.Stage = Stage(name)
+			case "workgroup_size":
+				args := splitArgs(attr[2])
+				if len(args) < 1 || len(args) > 3 {
+					return nil, lineErrorf(ep.Line, "function %q: @workgroup_size must have 1 to 3 arguments, got %q", ep.Name, attr[2])
+				}
+				copy(ep.WorkgroupSize[:], args)
+				hasWorkgroupSize = true
+			}
+		}
+		if ep.Stage == "" {
+			continue
+		}
+		if ep.Stage == StageCompute && !hasWorkgroupSize {
+			return nil, lineErrorf(ep.Line, "compute entry point %q: missing @workgroup_size attribute", ep.Name)
+		}
+		if ep.Stage != StageCompute && hasWorkgroupSize {
+			return nil, lineErrorf(ep.Line, "%s entry point %q: @workgroup_size is only valid for compute entry points", ep.Stage, ep.Name)
+		}
+		entryPoints = append(entryPoints, ep)
+	}
+	return entryPoints, nil
+}
+
+// FindEntryPoint returns the entry point with the given name.
+func FindEntryPoint(entryPoints []EntryPoint, name string) (EntryPoint, bool) {
+	for _, ep := range entryPoints {
+		if ep.Name == name {
+			return ep, true
+		}
+	}
+	return EntryPoint{}, false
+}
+
+// ParseConstants returns all the module-scope const and override declarations in src, in declaration order.
+func ParseConstants(src string) ([]Constant, error) {
+	src = StripComments(src)
+	depths := braceDepths(src)
+
+	var constants []Constant
+	for _, m := range constDeclRegexp.FindAllStringSubmatchIndex(src, -1) {
+		if depths[m[0]] > 0 {
+			continue
+		}
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return src[m[2*i]:m[2*i+1]]
+		}
+		c := Constant{
+			Name:     group(4),
+			Override: group(3) == "override",
+			ID:       -1,
+			Type:     spaceRegexp.ReplaceAllString(group(5), ""),
+			Init:     strings.TrimSpace(group(6)),
+			Line:     LineOf(src, m[0]),
+		}
+		if id := group(2); id != "" {
+			if !c.Override {
+				return nil, lineErrorf(c.Line, "const %q: @id is only valid for overrides", c.Name)
+			}
+			c.ID, _ = strconv.Atoi(id)
+		}
+		if !c.Override && c.Init == "" {
+			return nil, lineErrorf(c.Line, "const %q: missing initializer", c.Name)
+		}
+		constants = append(constants, c)
+	}
+	return constants, nil
+}
+
+// ResolveWorkgroupSize evaluates the entry point's @workgroup_size expressions.
+// Identifiers are resolved using constants, and overrides takes precedence over the default values of override declarations.
+func (e EntryPoint) ResolveWorkgroupSize(constants []Constant, overrides map[string]float64) ([3]int, error) {
+	var size [3]int
+	for i, expr := range e.WorkgroupSize {
+		v, err := EvalInt(expr, constants, overrides)
+		if err != nil {
+			return size, fmt.Errorf("entry point %q: @workgroup_size: %v", e.Name, err)
+		}
+		if v < 1 {
+			return size, fmt.Errorf("entry point %q: @workgroup_size: dimension %d is %d, must be at least 1", e.Name, i, v)
+		}
+		size[i] = v
+	}
+	return size, nil
+}
+
+// splitArgs splits a comma separated list of arguments, ignoring commas nested in parentheses.
+func splitArgs(s string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	// A trailing comma is allowed.
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		args = append(args, last)
+	}
+	return args
+}
+
+// braceDepths returns the nesting depth of braces at each byte offset in src.
+func braceDepths(src string) []int {
+	depths := make([]int, len(src)+1)
+	depth := 0
+	for i := 0; i < len(src); i++ {
+		depths[i] = depth
+		switch src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+	}
+	depths[len(src)] = depth
+	return depths
+}
@@ -0,0 +1,162 @@
+package wgsl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const entryPointsSrc = `
+override blockSize : u32 = 8;
+@id(3) override rows : u32;
+const total = blockSize * 4u;
+
+fn helper(x : u32) -> u32 {
+  const local = 1;
+  return x + local;
+}
+
+@compute @workgroup_size(64)
+fn linear(@builtin(global_invocation_id) id : vec3<u32>) {}
+
+// @compute @workgroup_size(1) fn commented() {}
+
+@compute
+@workgroup_size(blockSize, blockSize * 2,)
+fn tiled() {}
+
+@compute @workgroup_size(total, rows)
+fn overridden() {}
+
+@vertex
+fn vertex_main() -> @builtin(position) vec4<f32> { return vec4(0.0); }
+
+@fragment fn fragment_main() -> @location(0) vec4<f32> { return vec4(1.0); }
+`
+
+func TestParseEntryPoints(t *testing.T) {
+	got, err := ParseEntryPoints(entryPointsSrc)
+	if err != nil {
+		t.Fatalf("ParseEntryPoints() = %v", err)
+	}
+	want := []EntryPoint{
+		{Name: "linear", Stage: StageCompute, WorkgroupSize: [3]string{"64", "1", "1"}, Line: 11},
+		{Name: "tiled", Stage: StageCompute, WorkgroupSize: [3]string{"blockSize", "blockSize * 2", "1"}, Line: 16},
+		{Name: "overridden", Stage: StageCompute, WorkgroupSize: [3]string{"total", "rows", "1"}, Line: 20},
+		{Name: "vertex_main", Stage: StageVertex, WorkgroupSize: [3]string{"1", "1", "1"}, Line: 23},
+		{Name: "fragment_main", Stage: StageFragment, WorkgroupSize: [3]string{"1", "1", "1"}, Line: 26},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, ok := FindEntryPoint(got, "tiled"); !ok {
+		t.Errorf("FindEntryPoint(%q) failed, want success", "tiled")
+	}
+	if _, ok := FindEntryPoint(got, "helper"); ok {
+		t.Errorf("FindEntryPoint(%q) succeeded, want failure", "helper")
+	}
+}
+
+func TestParseEntryPointsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{
+			name:    "missing workgroup size",
+			src:     "@compute fn main() {}",
+			wantErr: `compute entry point "main": missing @workgroup_size`,
+		},
+		{
+			name:    "workgroup size on vertex",
+			src:     "@vertex @workgroup_size(1) fn main() {}",
+			wantErr: "only valid for compute entry points",
+		},
+		{
+			name:    "too many dimensions",
+			src:     "@compute @workgroup_size(1, 2, 3, 4) fn main() {}",
+			wantErr: "must have 1 to 3 arguments",
+		},
+		{
+			name:    "multiple stages",
+			src:     "@vertex @fragment fn main() {}",
+			wantErr: "has both @vertex and @fragment",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseEntryPoints(tc.src)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("ParseEntryPoints() error = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseConstants(t *testing.T) {
+	got, err := ParseConstants(entryPointsSrc)
+	if err != nil {
+		t.Fatalf("ParseConstants() = %v", err)
+	}
+	want := []Constant{
+		{Name: "blockSize", Override: true, ID: -1, Type: "u32", Init: "8", Line: 2},
+		{Name: "rows", Override: true, ID: 3, Type: "u32", Line: 3},
+		{Name: "total", ID: -1, Init: "blockSize * 4u", Line: 4},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ParseConstants("@id(1) const x = 1;"); err == nil {
+		t.Errorf("ParseConstants() with @id on const succeeded, want error")
+	}
+}
+
+func TestResolveWorkgroupSize(t *testing.T) {
+	entryPoints, err := ParseEntryPoints(entryPointsSrc)
+	if err != nil {
+		t.Fatalf("ParseEntryPoints() = %v", err)
+	}
+	constants, err := ParseConstants(entryPointsSrc)
+	if err != nil {
+		t.Fatalf("ParseConstants() = %v", err)
+	}
+
+	tests := []struct {
+		entryPoint string
+		overrides  map[string]float64
+		want       [3]int
+		wantErr    string
+	}{
+		{entryPoint: "linear", want: [3]int{64, 1, 1}},
+		{entryPoint: "tiled", want: [3]int{8, 16, 1}},
+		{entryPoint: "tiled", overrides: map[string]float64{"blockSize": 4}, want: [3]int{4, 8, 1}},
+		{entryPoint: "overridden", overrides: map[string]float64{"rows": 2}, want: [3]int{32, 2, 1}},
+		{entryPoint: "overridden", wantErr: `override "rows" has no default value`},
+		{entryPoint: "overridden", overrides: map[string]float64{"rows": 0}, wantErr: "dimension 1 is 0"},
+		{entryPoint: "overridden", overrides: map[string]float64{"rows": 1.5}, wantErr: "non-integer value"},
+	}
+	for _, tc := range tests {
+		ep, ok := FindEntryPoint(entryPoints, tc.entryPoint)
+		if !ok {
+			t.Fatalf("FindEntryPoint(%q) failed", tc.entryPoint)
+		}
+		got, err := ep.ResolveWorkgroupSize(constants, tc.overrides)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s.ResolveWorkgroupSize(%v) error = %v, want error containing %q", tc.entryPoint, tc.overrides, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s.ResolveWorkgroupSize(%v) = %v", tc.entryPoint, tc.overrides, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s.ResolveWorkgroupSize(%v) = %v, want %v", tc.entryPoint, tc.overrides, got, tc.want)
+		}
+	}
+}
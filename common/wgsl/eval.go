@@ -0,0 +1,198 @@
+package wgsl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxEvalDepth limits how many constants can be followed when evaluating an expression, to catch cycles.
+const maxEvalDepth = 32
+
+// EvalInt evaluates a constant integer expression, such as an argument to @workgroup_size.
+// Expressions can contain integer literals, identifiers, parentheses and the + - * / % operators.
+// Identifiers are looked up in overrides, then in constants.
+func EvalInt(expr string, constants []Constant, overrides map[string]float64) (int, error) {
+	e := &evaluator{constants: constants, overrides: overrides}
+	return e.eval(expr, 0)
+}
+
+type evaluator struct {
+	constants []Constant
+	overrides map[string]float64
+}
+
+func (e *evaluator) eval(expr string, depth int) (int, error) {
+	if depth > maxEvalDepth {
+		return 0, fmt.Errorf("too many nested constants evaluating %q", expr)
+	}
+	p := &exprParser{e: e, tokens: tokenize(expr), depth: depth}
+	v, err := p.parseSum()
+	if err != nil {
+		return 0, fmt.Errorf("evaluating %q: %v", expr, err)
+	}
+	if p.pos != len(p.tokens) {
+		return 0, fmt.Errorf("evaluating %q: unexpected %q", expr, p.tokens[p.pos])
+	}
+	return v, nil
+}
+
+func (e *evaluator) lookup(name string, depth int) (int, error) {
+	if v, ok := e.overrides[name]; ok {
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("override %q has non-integer value %v", name, v)
+		}
+		return int(v), nil
+	}
+	for _, c := range e.constants {
+		if c.Name != name {
+			continue
+		}
+		if c.Init == "" {
+			return 0, fmt.Errorf("override %q has no default value", name)
+		}
+		return e.eval(c.Init, depth+1)
+	}
+	return 0, fmt.Errorf("unknown identifier %q", name)
+}
+
+type exprParser struct {
+	e      *evaluator
+	tokens []string
+	pos    int
+	depth  int
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) parseSum() (int, error) {
+	v, err := p.parseProduct()
+	if err != nil {
+		return 0, err
+	}
+	for op := p.peek(); op == "+" || op == "-"; op = p.peek() {
+		p.pos++
+		rhs, err := p.parseProduct()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			v += rhs
+		} else {
+			v -= rhs
+		}
+	}
+	return v, nil
+}
+
+func (p *exprParser) parseProduct() (int, error) {
+	v, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for op := p.peek(); op == "*" || op == "/" || op == "%"; op = p.peek() {
+		p.pos++
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case op == "*":
+			v *= rhs
+		case rhs == 0:
+			return 0, fmt.Errorf("division by zero")
+		case op == "/":
+			v /= rhs
+		default:
+			v %= rhs
+		}
+	}
+	return v, nil
+}
+
+func (p *exprParser) parseUnary() (int, error) {
+	tok := p.peek()
+	switch {
+	case tok == "":
+		return 0, fmt.Errorf("unexpected end of expression")
+	case tok == "-":
+		p.pos++
+		v, err := p.parseUnary()
+		return -v, err
+	case tok == "(":
+		p.pos++
+		v, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ")" {
+			return 0, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return v, nil
+	case unicode.IsDigit(rune(tok[0])):
+		p.pos++
+		return parseIntLiteral(tok)
+	case isIdentStart(rune(tok[0])):
+		p.pos++
+		return p.e.lookup(tok, p.depth)
+	}
+	return 0, fmt.Errorf("unexpected %q", tok)
+}
+
+// parseIntLiteral parses a WGSL integer literal, e.g. 64, 64u or 0x40i.
+func parseIntLiteral(tok string) (int, error) {
+	s := strings.TrimRight(tok, "iu")
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer literal %q", tok)
+	}
+	return int(v), nil
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r)
+}
+
+// isExponentSign reports whether r is the sign of the exponent of the numeric literal tok, e.g. in 1e-3 or 0x1p+4.
+func isExponentSign(tok []rune, r rune) bool {
+	if (r != '+' && r != '-') || len(tok) < 2 || !(unicode.IsDigit(tok[0]) || tok[0] == '.') {
+		return false
+	}
+	last := unicode.ToLower(tok[len(tok)-1])
+	isHex := unicode.ToLower(tok[1]) == 'x'
+	return !isHex && last == 'e' || isHex && last == 'p'
+}
+
+// tokenize splits an expression into literals, identifiers and single character operators.
+func tokenize(expr string) []string {
+	var tokens []string
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case isIdentPart(r) || r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			start := i
+			for i < len(runes) && (isIdentPart(runes[i]) || runes[i] == '.' || isExponentSign(runes[start:i], runes[i])) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,51 @@
+package wgsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvalInt(t *testing.T) {
+	constants := []Constant{
+		{Name: "a", Init: "4"},
+		{Name: "b", Init: "a * 2"},
+		{Name: "loop", Init: "loop + 1"},
+	}
+	tests := []struct {
+		expr    string
+		want    int
+		wantErr string
+	}{
+		{expr: "64", want: 64},
+		{expr: "64u", want: 64},
+		{expr: "0x10i", want: 16},
+		{expr: "1 + 2 * 3", want: 7},
+		{expr: "(1 + 2) * 3", want: 9},
+		{expr: "-2 + 10 / 3 % 2", want: -1},
+		{expr: "b + a", want: 12},
+		{expr: "", wantErr: "unexpected end of expression"},
+		{expr: "1 +", wantErr: "unexpected end of expression"},
+		{expr: "(1", wantErr: "missing )"},
+		{expr: "1 2", wantErr: `unexpected "2"`},
+		{expr: "1.5", wantErr: "invalid integer literal"},
+		{expr: "4 / 0", wantErr: "division by zero"},
+		{expr: "c", wantErr: `unknown identifier "c"`},
+		{expr: "loop", wantErr: "too many nested constants"},
+	}
+	for _, tc := range tests {
+		got, err := EvalInt(tc.expr, constants, nil)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("EvalInt(%q) error = %v, want error containing %q", tc.expr, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("EvalInt(%q) = %v", tc.expr, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("EvalInt(%q) = %d, want %d", tc.expr, got, tc.want)
+		}
+	}
+}
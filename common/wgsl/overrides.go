@@ -0,0 +1,273 @@
+package wgsl
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Overrides holds values for override declarations, keyed by name.
+// As in WebGPU, values are float64s and are converted to the declared type of the override.
+type Overrides map[string]float64
+
+// PipelineConstants validates the overrides against the override declarations in constants and
+// returns them keyed by pipeline constant identifier, i.e. the @id of an override if it has one and its name otherwise.
+// It returns an error if a name doesn't match an override, a value can't be represented by the override's type,
+// or an override without a default value isn't set.
+func (o Overrides) PipelineConstants(constants []Constant) (map[string]float64, error) {
+	decls := make(map[string]Constant)
+	for _, c := range constants {
+		if c.Override {
+			decls[c.Name] = c
+		}
+	}
+
+	names := make([]string, 0, len(o))
+	for name := range o {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make(map[string]float64, len(o))
+	for _, name := range names {
+		c, ok := decls[name]
+		if !ok {
+			return nil, fmt.Errorf("%q is not an override declared in the shader", name)
+		}
+		v := o[name]
+		typ, err := c.scalarType(constants)
+		if err != nil {
+			return nil, fmt.Errorf("override %q: %v", name, err)
+		}
+		if err := checkOverrideValue(typ, v); err != nil {
+			return nil, fmt.Errorf("override %q: %v", name, err)
+		}
+		result[c.pipelineConstantID()] = v
+	}
+
+	for _, c := range constants {
+		if _, ok := o[c.Name]; c.Override && !ok && c.Init == "" {
+			return nil, fmt.Errorf("override %q has no default value and must be set", c.Name)
+		}
+	}
+	return result, nil
+}
+
+// OverridesFromPipelineConstants is the inverse of PipelineConstants: it returns the overrides, keyed by name,
+// set by the constants of a pipeline's programmable stage. Constants which don't identify an override are ignored.
+func OverridesFromPipelineConstants(constants []Constant, pipelineConstants map[string]float64) Overrides {
+	o := make(Overrides)
+	for _, c := range constants {
+		if !c.Override {
+			continue
+		}
+		if v, ok := pipelineConstants[c.pipelineConstantID()]; ok {
+			o[c.Name] = v
+		}
+	}
+	return o
+}
+
+// WithDefaults returns a copy of the overrides with the default value of each override declared in constants
+// which isn't set. It returns an error if an override without a default isn't set, or a default can't be evaluated:
+// defaults must be bool or floating point literals, or integer expressions as accepted by EvalInt.
+func (o Overrides) WithDefaults(constants []Constant) (Overrides, error) {
+	result := make(Overrides, len(o))
+	for name, v := range o {
+		result[name] = v
+	}
+	for _, c := range constants {
+		if _, ok := o[c.Name]; !c.Override || ok {
+			continue
+		}
+		if c.Init == "" {
+			return nil, fmt.Errorf("override %q has no default value and must be set", c.Name)
+		}
+		v, err := c.evalDefault(constants, o)
+		if err != nil {
+			return nil, fmt.Errorf("override %q: %v", c.Name, err)
+		}
+		result[c.Name] = v
+	}
+	return result, nil
+}
+
+// evalDefault evaluates the initializer of an override, looking up identifiers in overrides then constants.
+func (c Constant) evalDefault(constants []Constant, overrides Overrides) (float64, error) {
+	typ, err := c.scalarType(constants)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case c.Init == "true":
+		return 1, nil
+	case c.Init == "false":
+		return 0, nil
+	case typ == "f32" || typ == "f16":
+		if v, err := strconv.ParseFloat(strings.TrimRight(c.Init, "fh"), 64); err == nil {
+			return v, nil
+		}
+	}
+	v, err := EvalInt(c.Init, constants, overrides)
+	return float64(v), err
+}
+
+// pipelineConstantID returns the key WebGPU uses to identify the override.
+func (c Constant) pipelineConstantID() string {
+	if c.ID >= 0 {
+		return strconv.Itoa(c.ID)
+	}
+	return c.Name
+}
+
+// scalarType returns the declared type of the constant, or the type WGSL infers from its initializer.
+// Abstract numeric initializers are concretized as i32 or f32, as WGSL does.
+func (c Constant) scalarType(constants []Constant) (string, error) {
+	if c.Type != "" {
+		return c.Type, nil
+	}
+	typ, err := exprType(c.Init, constants, 0)
+	if err != nil {
+		return "", err
+	}
+	switch typ {
+	case abstractInt:
+		return "i32", nil
+	case abstractFloat:
+		return "f32", nil
+	}
+	return typ, nil
+}
+
+// The types of abstract numeric literals, and expressions which only use them.
+const (
+	abstractInt   = "abstract-int"
+	abstractFloat = "abstract-float"
+)
+
+var (
+	intLiteralRegexp   = regexp.MustCompile(`^(?:0[xX][0-9a-fA-F]+|[0-9]+)([iu]?)$`)
+	floatLiteralRegexp = regexp.MustCompile(`^(?:0[xX](?:[0-9a-fA-F]*\.[0-9a-fA-F]*(?:[pP][+-]?[0-9]+)?|[0-9a-fA-F]+[pP][+-]?[0-9]+)|[0-9]*\.[0-9]*(?:[eE][+-]?[0-9]+)?|[0-9]+[eE][+-]?[0-9]+|[0-9]+)([fh]?)$`)
+)
+
+// exprType returns the type of a constant expression: bool, a concrete scalar type, or abstractInt or
+// abstractFloat if it only uses abstract literals. Identifiers are looked up in constants.
+// It returns an error if the type can't be inferred, e.g. because the expression calls a function or compares values.
+func exprType(expr string, constants []Constant, depth int) (string, error) {
+	if depth > maxEvalDepth {
+		return "", fmt.Errorf("too many nested constants finding the type of %q", expr)
+	}
+	if expr == "true" || expr == "false" {
+		return "bool", nil
+	}
+	typ := ""
+	for _, tok := range tokenize(expr) {
+		var tokType string
+		switch {
+		case strings.Contains("+-*/%&|^~()", tok):
+			continue
+		case unicode.IsDigit(rune(tok[0])) || tok[0] == '.':
+			t, err := literalType(tok)
+			if err != nil {
+				return "", err
+			}
+			tokType = t
+		case isIdentStart(rune(tok[0])):
+			i := slices.IndexFunc(constants, func(c Constant) bool { return c.Name == tok })
+			if i < 0 {
+				return "", fmt.Errorf("can't infer the type of %q: unknown identifier %q, declare the type explicitly", expr, tok)
+			}
+			c := constants[i]
+			if c.Type != "" {
+				tokType = c.Type
+			} else {
+				t, err := exprType(c.Init, constants, depth+1)
+				if err != nil {
+					return "", err
+				}
+				tokType = t
+			}
+		default:
+			return "", fmt.Errorf("can't infer the type of %q: unsupported %q, declare the type explicitly", expr, tok)
+		}
+		// Abstract values convert to the concrete types they're combined with, and abstract ints to floats.
+		isFloat := func(t string) bool { return t == "f32" || t == "f16" }
+		switch {
+		case typ == "" || typ == tokType:
+			typ = tokType
+		case tokType == abstractInt && typ != "bool":
+		case typ == abstractInt && tokType != "bool":
+			typ = tokType
+		case tokType == abstractFloat && isFloat(typ):
+		case typ == abstractFloat && isFloat(tokType):
+			typ = tokType
+		default:
+			return "", fmt.Errorf("can't infer the type of %q: it mixes %s and %s", expr, typ, tokType)
+		}
+	}
+	if typ == "" {
+		return "", fmt.Errorf("can't infer the type of %q", expr)
+	}
+	return typ, nil
+}
+
+// literalType returns the type of a WGSL numeric literal, e.g. i32 for 1i, or abstractFloat for 1.5.
+func literalType(tok string) (string, error) {
+	if m := intLiteralRegexp.FindStringSubmatch(tok); m != nil {
+		switch m[1] {
+		case "i":
+			return "i32", nil
+		case "u":
+			return "u32", nil
+		}
+		return abstractInt, nil
+	}
+	if m := floatLiteralRegexp.FindStringSubmatch(tok); m != nil && tok != "." {
+		switch m[1] {
+		case "f":
+			return "f32", nil
+		case "h":
+			return "f16", nil
+		}
+		return abstractFloat, nil
+	}
+	return "", fmt.Errorf("invalid numeric literal %q", tok)
+}
+
+// checkOverrideValue returns an error if v can't be represented by the scalar type typ.
+func checkOverrideValue(typ string, v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("value %v is not finite", v)
+	}
+	isInt := v == math.Trunc(v)
+	switch typ {
+	case "bool":
+		if v != 0 && v != 1 {
+			return fmt.Errorf("value %v is not a bool, must be 0 or 1", v)
+		}
+	case "i32":
+		if !isInt || v < math.MinInt32 || v > math.MaxInt32 {
+			return fmt.Errorf("value %v is not representable as i32", v)
+		}
+	case "u32":
+		if !isInt || v < 0 || v > math.MaxUint32 {
+			return fmt.Errorf("value %v is not representable as u32", v)
+		}
+	case "f32":
+		if math.Abs(v) > math.MaxFloat32 {
+			return fmt.Errorf("value %v is not representable as f32", v)
+		}
+	case "f16":
+		if math.Abs(v) > 65504 {
+			return fmt.Errorf("value %v is not representable as f16", v)
+		}
+	default:
+		return fmt.Errorf("unsupported type %q", typ)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package wgsl
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPipelineConstants(t *testing.T) {
+	constants, err := ParseConstants(`
+const notOverridable = 1;
+override gain : f32 = 3.0;
+override inferredFloat = 1.5;
+override inferredInt = 2;
+override count : u32 = 64u;
+override enabled : bool = true;
+@id(7) override scale : i32 = -1;
+override required : f32;
+`)
+	if err != nil {
+		t.Fatalf("ParseConstants() = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		overrides Overrides
+		want      map[string]float64
+		wantErr   string
+	}{
+		{
+			name:      "required only",
+			overrides: Overrides{"required": 2},
+			want:      map[string]float64{"required": 2},
+		},
+		{
+			name:      "keyed by id",
+			overrides: Overrides{"required": 0, "scale": -5, "count": 128, "enabled": 0, "gain": 0.5},
+			want:      map[string]float64{"required": 0, "7": -5, "count": 128, "enabled": 0, "gain": 0.5},
+		},
+		{
+			name:      "inferred types",
+			overrides: Overrides{"required": 0, "inferredFloat": 0.25, "inferredInt": -3},
+			want:      map[string]float64{"required": 0, "inferredFloat": 0.25, "inferredInt": -3},
+		},
+		{
+			name:    "missing required",
+			wantErr: `override "required" has no default value`,
+		},
+		{
+			name:      "unknown",
+			overrides: Overrides{"required": 0, "unknown": 1},
+			wantErr:   `"unknown" is not an override`,
+		},
+		{
+			name:      "const",
+			overrides: Overrides{"required": 0, "notOverridable": 1},
+			wantErr:   `"notOverridable" is not an override`,
+		},
+		{
+			name:      "negative u32",
+			overrides: Overrides{"required": 0, "count": -1},
+			wantErr:   `override "count": value -1 is not representable as u32`,
+		},
+		{
+			name:      "fractional i32",
+			overrides: Overrides{"required": 0, "inferredInt": 0.5},
+			wantErr:   `override "inferredInt": value 0.5 is not representable as i32`,
+		},
+		{
+			name:      "bool",
+			overrides: Overrides{"required": 0, "enabled": 2},
+			wantErr:   `override "enabled": value 2 is not a bool`,
+		},
+		{
+			name:      "f32 overflow",
+			overrides: Overrides{"required": 1e39},
+			wantErr:   `override "required": value 1e+39 is not representable as f32`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.overrides.PipelineConstants(constants)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("PipelineConstants() error = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PipelineConstants() = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("PipelineConstants() diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.overrides, OverridesFromPipelineConstants(constants, got)); diff != "" {
+				t.Errorf("OverridesFromPipelineConstants() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	constants, err := ParseConstants(`
+const size = 8;
+override gain : f32 = 3.0;
+override half = 0.5f;
+override count : u32 = 64u;
+override enabled : bool = true;
+override scaled : i32 = count * size;
+override required : f32;
+`)
+	if err != nil {
+		t.Fatalf("ParseConstants() = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		overrides Overrides
+		want      Overrides
+		wantErr   string
+	}{
+		{
+			name:      "defaults",
+			overrides: Overrides{"required": 2},
+			want:      Overrides{"required": 2, "gain": 3, "half": 0.5, "count": 64, "enabled": 1, "scaled": 512},
+		},
+		{
+			name:      "set values are kept",
+			overrides: Overrides{"required": 2, "gain": 1, "count": 4, "enabled": 0},
+			want:      Overrides{"required": 2, "gain": 1, "half": 0.5, "count": 4, "enabled": 0, "scaled": 32},
+		},
+		{
+			name:    "missing required",
+			wantErr: `override "required" has no default value`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.overrides.WithDefaults(constants)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("WithDefaults() error = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WithDefaults() = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("WithDefaults() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestScalarType(t *testing.T) {
+	constants, err := ParseConstants(`
+const baseSize = 8;
+const unsigned = 4u;
+const ratio = 0.5;
+override typed : f16 = 1.0h;
+override groupSize = baseSize * 2;
+override unsignedSize = unsigned * 2;
+override scaled = baseSize * ratio;
+override typedScaled = typed * 2;
+override exponent = 1e-3;
+override leadingDot = .5f;
+override hexFloat = 0x1p+4;
+override hex = 0x1f;
+override negated = -(baseSize + 1i);
+override enabled = true;
+override unknown = missing * 2;
+override called = max(1, 2);
+override mixed = unsigned + 1i;
+override floatToInt = ratio * 1u;
+`)
+	if err != nil {
+		t.Fatalf("ParseConstants() = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "typed", want: "f16"},
+		{name: "groupSize", want: "i32"},
+		{name: "unsignedSize", want: "u32"},
+		{name: "scaled", want: "f32"},
+		{name: "typedScaled", want: "f16"},
+		{name: "exponent", want: "f32"},
+		{name: "leadingDot", want: "f32"},
+		{name: "hexFloat", want: "f32"},
+		{name: "hex", want: "i32"},
+		{name: "negated", want: "i32"},
+		{name: "enabled", want: "bool"},
+		{name: "unknown", wantErr: `unknown identifier "missing"`},
+		{name: "called", wantErr: `unknown identifier "max"`},
+		{name: "mixed", wantErr: "it mixes u32 and i32"},
+		{name: "floatToInt", wantErr: "it mixes abstract-float and u32"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			i := slices.IndexFunc(constants, func(c Constant) bool { return c.Name == tc.name })
+			got, err := constants[i].scalarType(constants)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("scalarType() error = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("scalarType() = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("scalarType() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	// An integer override can't be set to a fractional value.
+	if _, err := (Overrides{"groupSize": 2.5}).PipelineConstants(constants[:6]); err == nil {
+		t.Errorf("PipelineConstants() with fractional groupSize = nil error, want error")
+	}
+}
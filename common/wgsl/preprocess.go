@@ -0,0 +1,248 @@
+package wgsl
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// A FileLoader returns the contents of the named file.
+type FileLoader func(name string) ([]byte, error)
+
+// FSLoader returns a FileLoader which reads files from fsys, e.g. an embed.FS.
+func FSLoader(fsys fs.FS) FileLoader {
+	return func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}
+}
+
+// A Location is a line in an original source file.
+type Location struct {
+	// File is the name of the file.
+	File string
+	// Line is the 1-based line number in the file.
+	Line int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// Source is the output of the preprocessor.
+type Source struct {
+	// Code is the preprocessed WGSL.
+	Code string
+	// Lines maps each line of Code to the line it came from, so Lines[i] is the location of line i+1.
+	Lines []Location
+}
+
+// Location returns the original location of the 1-based line in Code.
+func (s Source) Location(line int) (Location, bool) {
+	if line < 1 || line > len(s.Lines) {
+		return Location{}, false
+	}
+	return s.Lines[line-1], true
+}
+
+// WithPrologue returns the source with prologue prepended, separated by a newline.
+// Lines of the prologue are attributed to the file called name.
+func (s Source) WithPrologue(name, prologue string) Source {
+	n := strings.Count(prologue, "\n") + 1
+	lines := make([]Location, 0, n+len(s.Lines))
+	for i := 1; i <= n; i++ {
+		lines = append(lines, Location{File: name, Line: i})
+	}
+	return Source{
+		Code:  prologue + "\n" + s.Code,
+		Lines: append(lines, s.Lines...),
+	}
+}
+
+// A Preprocessor expands directives in WGSL source. The supported directives are:
+//
+//	#include "file.wgsl"   inserts the file, relative to the including file
+//	#pragma once          skips the file if it has already been included
+//	#define NAME          defines NAME
+//	#undef NAME           undefines NAME
+//	#ifdef NAME           includes the following lines if NAME is defined
+//	#ifndef NAME          includes the following lines if NAME isn't defined
+//	#else
+//	#endif
+//
+// Defines are only used by conditionals; values should be declared as WGSL consts.
+type Preprocessor struct {
+	// Load is used to load included files. Includes are errors if it's nil.
+	Load FileLoader
+	// Defines are the names which are defined before processing starts.
+	Defines []string
+}
+
+var directiveRegexp = regexp.MustCompile(`^\s*#\s*(\w+)\s*(.*?)\s*$`)
+
+// maxIncludeDepth limits how deeply files can be included.
+const maxIncludeDepth = 32
+
+// ProcessFile loads and preprocesses the named file.
+func (p Preprocessor) ProcessFile(name string) (Source, error) {
+	if p.Load == nil {
+		return Source{}, fmt.Errorf("loading %q: no loader", name)
+	}
+	data, err := p.Load(name)
+	if err != nil {
+		return Source{}, fmt.Errorf("loading %q: %v", name, err)
+	}
+	return p.Process(name, string(data))
+}
+
+// resolveInclude returns the name of file, included by the file called name. If name is a URL, such as one
+// shaders are fetched from, file is resolved as a URL reference to it, and otherwise as a path relative to it.
+func resolveInclude(name, file string) string {
+	if strings.Contains(name, "://") {
+		base, err := url.Parse(name)
+		ref, refErr := url.Parse(file)
+		if err == nil && refErr == nil {
+			return base.ResolveReference(ref).String()
+		}
+	}
+	return path.Join(path.Dir(name), file)
+}
+
+// Process preprocesses src. Name is used to resolve relative includes and in the line map.
+func (p Preprocessor) Process(name, src string) (Source, error) {
+	st := &preprocessState{
+		p:        p,
+		defines:  make(map[string]bool),
+		included: make(map[string]bool),
+	}
+	for _, d := range p.Defines {
+		st.defines[d] = true
+	}
+	if err := st.process(name, src, nil); err != nil {
+		return Source{}, err
+	}
+	return Source{Code: st.output.String(), Lines: st.lines}, nil
+}
+
+type preprocessState struct {
+	p        Preprocessor
+	defines  map[string]bool
+	included map[string]bool
+
+	output strings.Builder
+	lines  []Location
+}
+
+// conditional tracks the state of an #ifdef block.
+type conditional struct {
+	loc       Location
+	directive string
+	active    bool
+	seenElse  bool
+}
+
+func (st *preprocessState) process(name, src string, stack []string) error {
+	for _, s := range stack {
+		if s == name {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), name)
+		}
+	}
+	if len(stack) >= maxIncludeDepth {
+		return fmt.Errorf("%s: includes nested more than %d deep", name, maxIncludeDepth)
+	}
+	stack = append(stack, name)
+
+	var conds []conditional
+	active := func() bool {
+		return len(conds) == 0 || conds[len(conds)-1].active
+	}
+
+	for i, line := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+		loc := Location{File: name, Line: i + 1}
+		m := directiveRegexp.FindStringSubmatch(line)
+		if m == nil {
+			if active() {
+				st.output.WriteString(line)
+				st.output.WriteString("\n")
+				st.lines = append(st.lines, loc)
+			}
+			continue
+		}
+
+		directive, arg := m[1], m[2]
+		switch directive {
+		case "ifdef", "ifndef":
+			if arg == "" {
+				return fmt.Errorf("%v: #%s requires a name", loc, directive)
+			}
+			cond := st.defines[arg] == (directive == "ifdef")
+			conds = append(conds, conditional{loc: loc, directive: directive, active: active() && cond})
+			continue
+		case "else":
+			if len(conds) == 0 {
+				return fmt.Errorf("%v: #else without #ifdef", loc)
+			}
+			c := &conds[len(conds)-1]
+			if c.seenElse {
+				return fmt.Errorf("%v: multiple #else for #%s on line %d", loc, c.directive, c.loc.Line)
+			}
+			c.seenElse = true
+			parentActive := len(conds) == 1 || conds[len(conds)-2].active
+			c.active = parentActive && !c.active
+			continue
+		case "endif":
+			if len(conds) == 0 {
+				return fmt.Errorf("%v: #endif without #ifdef", loc)
+			}
+			conds = conds[:len(conds)-1]
+			continue
+		}
+
+		if !active() {
+			continue
+		}
+		switch directive {
+		case "include":
+			file, ok := strings.CutPrefix(arg, `"`)
+			file, ok2 := strings.CutSuffix(file, `"`)
+			if !ok || !ok2 || file == "" {
+				return fmt.Errorf("%v: #include requires a quoted file name, got %q", loc, arg)
+			}
+			file = resolveInclude(name, file)
+			if st.p.Load == nil {
+				return fmt.Errorf("%v: can't include %q without a loader", loc, file)
+			}
+			data, err := st.p.Load(file)
+			if err != nil {
+				return fmt.Errorf("%v: including %q: %v", loc, file, err)
+			}
+			if err := st.process(file, string(data), stack); err != nil {
+				return err
+			}
+		case "pragma":
+			if arg != "once" {
+				return fmt.Errorf("%v: unknown pragma %q", loc, arg)
+			}
+			if st.included[name] {
+				return nil
+			}
+			st.included[name] = true
+		case "define":
+			if arg == "" || strings.ContainsAny(arg, " \t") {
+				return fmt.Errorf("%v: #define requires a single name, got %q", loc, arg)
+			}
+			st.defines[arg] = true
+		case "undef":
+			delete(st.defines, arg)
+		default:
+			return fmt.Errorf("%v: unknown directive #%s", loc, directive)
+		}
+	}
+	if len(conds) > 0 {
+		c := conds[len(conds)-1]
+		return fmt.Errorf("%v: #%s without #endif", c.loc, c.directive)
+	}
+	return nil
+}
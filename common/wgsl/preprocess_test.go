@@ -0,0 +1,236 @@
+package wgsl
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testFS = fstest.MapFS{
+	"shaders/main.wgsl": {Data: []byte(`#include "lib/common.wgsl"
+#include "lib/common.wgsl"
+fn main() {
+#ifdef DEBUG
+  debug();
+#else
+  release();
+#endif
+}
+`)},
+	"shaders/lib/common.wgsl": {Data: []byte(`#pragma once
+#include "math.wgsl"
+const common = 1;
+`)},
+	"shaders/lib/math.wgsl": {Data: []byte(`#ifndef MATH_WGSL
+#define MATH_WGSL
+const pi = 3.14;
+#endif
+`)},
+	"cycle/a.wgsl": {Data: []byte(`#include "b.wgsl"`)},
+	"cycle/b.wgsl": {Data: []byte(`#include "a.wgsl"`)},
+}
+
+func TestProcessFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		defines   []string
+		wantCode  string
+		wantLines []Location
+	}{
+		{
+			name: "release",
+			wantCode: `const pi = 3.14;
+const common = 1;
+fn main() {
+  release();
+}
+`,
+			wantLines: []Location{
+				{File: "shaders/lib/math.wgsl", Line: 3},
+				{File: "shaders/lib/common.wgsl", Line: 3},
+				{File: "shaders/main.wgsl", Line: 3},
+				{File: "shaders/main.wgsl", Line: 7},
+				{File: "shaders/main.wgsl", Line: 9},
+			},
+		},
+		{
+			name:    "debug",
+			defines: []string{"DEBUG"},
+			wantCode: `const pi = 3.14;
+const common = 1;
+fn main() {
+  debug();
+}
+`,
+			wantLines: []Location{
+				{File: "shaders/lib/math.wgsl", Line: 3},
+				{File: "shaders/lib/common.wgsl", Line: 3},
+				{File: "shaders/main.wgsl", Line: 3},
+				{File: "shaders/main.wgsl", Line: 5},
+				{File: "shaders/main.wgsl", Line: 9},
+			},
+		},
+		{
+			name:    "guard already defined",
+			defines: []string{"MATH_WGSL"},
+			wantCode: `const common = 1;
+fn main() {
+  release();
+}
+`,
+			wantLines: []Location{
+				{File: "shaders/lib/common.wgsl", Line: 3},
+				{File: "shaders/main.wgsl", Line: 3},
+				{File: "shaders/main.wgsl", Line: 7},
+				{File: "shaders/main.wgsl", Line: 9},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Preprocessor{Load: FSLoader(testFS), Defines: tc.defines}
+			got, err := p.ProcessFile("shaders/main.wgsl")
+			if err != nil {
+				t.Fatalf("ProcessFile() = %v", err)
+			}
+			if diff := cmp.Diff(tc.wantCode, got.Code); diff != "" {
+				t.Errorf("Code diff mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantLines, got.Lines); diff != "" {
+				t.Errorf("Lines diff mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProcessURL(t *testing.T) {
+	files := map[string]string{
+		"https://example.com/shaders/main.wgsl?v=2":   "#include \"lib/common.wgsl\"\nfn main() {}\n",
+		"https://example.com/shaders/lib/common.wgsl": "#include \"../../shared/math.wgsl\"\nconst common = 1;\n",
+		"https://example.com/shared/math.wgsl":        "#include \"https://cdn.example.com/pi.wgsl\"\n",
+		"https://cdn.example.com/pi.wgsl":             "const pi = 3.14;\n",
+	}
+	load := func(name string) ([]byte, error) {
+		data, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%q not found", name)
+		}
+		return []byte(data), nil
+	}
+
+	got, err := Preprocessor{Load: load}.ProcessFile("https://example.com/shaders/main.wgsl?v=2")
+	if err != nil {
+		t.Fatalf("ProcessFile() = %v", err)
+	}
+	wantLines := []Location{
+		{File: "https://cdn.example.com/pi.wgsl", Line: 1},
+		{File: "https://example.com/shaders/lib/common.wgsl", Line: 2},
+		{File: "https://example.com/shaders/main.wgsl?v=2", Line: 2},
+	}
+	if diff := cmp.Diff(wantLines, got.Lines); diff != "" {
+		t.Errorf("Lines diff mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSourceLocation(t *testing.T) {
+	p := Preprocessor{Load: FSLoader(testFS)}
+	src, err := p.ProcessFile("shaders/main.wgsl")
+	if err != nil {
+		t.Fatalf("ProcessFile() = %v", err)
+	}
+	if got, ok := src.Location(2); !ok || got.String() != "shaders/lib/common.wgsl:3" {
+		t.Errorf("Location(2) = %v, %v, want shaders/lib/common.wgsl:3", got, ok)
+	}
+	for _, line := range []int{0, len(src.Lines) + 1} {
+		if _, ok := src.Location(line); ok {
+			t.Errorf("Location(%d) succeeded, want failure", line)
+		}
+	}
+}
+
+func TestWithPrologue(t *testing.T) {
+	src, err := Preprocessor{}.Process("main.wgsl", "a\nb\n")
+	if err != nil {
+		t.Fatalf("Process() = %v", err)
+	}
+	got := src.WithPrologue("<prologue>", "struct S {\n}\n")
+	if want := "struct S {\n}\n\na\nb\n"; got.Code != want {
+		t.Errorf("WithPrologue() code = %q, want %q", got.Code, want)
+	}
+	want := []Location{
+		{File: "<prologue>", Line: 1},
+		{File: "<prologue>", Line: 2},
+		{File: "<prologue>", Line: 3},
+		{File: "main.wgsl", Line: 1},
+		{File: "main.wgsl", Line: 2},
+	}
+	if diff := cmp.Diff(want, got.Lines); diff != "" {
+		t.Errorf("WithPrologue() lines diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		load    FileLoader
+		wantErr string
+	}{
+		{name: "missing endif", src: "#ifdef A\nfoo", wantErr: "test.wgsl:1: #ifdef without #endif"},
+		{name: "unmatched endif", src: "foo\n#endif", wantErr: "test.wgsl:2: #endif without #ifdef"},
+		{name: "unmatched else", src: "#else", wantErr: "#else without #ifdef"},
+		{name: "multiple else", src: "#ifndef A\n#else\n#else\n#endif", wantErr: "test.wgsl:3: multiple #else for #ifndef on line 1"},
+		{name: "unknown directive", src: "#version 300", wantErr: "unknown directive #version"},
+		{name: "unknown pragma", src: "#pragma twice", wantErr: `unknown pragma "twice"`},
+		{name: "define value", src: "#define A 1", wantErr: "#define requires a single name"},
+		{name: "unquoted include", src: "#include <foo.wgsl>", wantErr: "requires a quoted file name"},
+		{name: "no loader", src: `#include "foo.wgsl"`, wantErr: `can't include "foo.wgsl" without a loader`},
+		{name: "missing file", src: `#include "missing.wgsl"`, load: FSLoader(testFS), wantErr: `test.wgsl:1: including "missing.wgsl"`},
+		{name: "cycle", src: `#include "cycle/a.wgsl"`, load: FSLoader(testFS), wantErr: "include cycle: test.wgsl -> cycle/a.wgsl -> cycle/b.wgsl -> cycle/a.wgsl"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Preprocessor{Load: tc.load}
+			_, err := p.Process("test.wgsl", tc.src)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Process() error = %v, want error containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNestedConditionals(t *testing.T) {
+	src := `#ifdef A
+a
+#ifdef B
+ab
+#else
+a!b
+#endif
+#else
+#ifdef B
+!ab
+#endif
+#endif`
+	tests := []struct {
+		defines []string
+		want    string
+	}{
+		{defines: nil, want: ""},
+		{defines: []string{"A"}, want: "a\na!b\n"},
+		{defines: []string{"A", "B"}, want: "a\nab\n"},
+		{defines: []string{"B"}, want: "!ab\n"},
+	}
+	for _, tc := range tests {
+		got, err := Preprocessor{Defines: tc.defines}.Process("test.wgsl", src)
+		if err != nil {
+			t.Fatalf("Process() = %v", err)
+		}
+		if got.Code != tc.want {
+			t.Errorf("Process() with defines %v = %q, want %q", tc.defines, got.Code, tc.want)
+		}
+	}
+}
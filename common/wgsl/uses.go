@@ -0,0 +1,71 @@
+package wgsl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+var (
+	fnNameRegexp = regexp.MustCompile(`\bfn\s+(\w+)`)
+	// identRegexp matches identifiers, and any preceding '.' so member accesses can be skipped.
+	identRegexp = regexp.MustCompile(`(\.\s*)?\b([A-Za-z_]\w*)`)
+)
+
+// StaticUses returns the names of the identifiers referenced by the function fnName, and by the functions
+// it calls, sorted by name. Like the WGSL notion of static use, this includes identifiers in code which
+// is never executed. Struct members aren't included, but local names which shadow module-scope
+// declarations are, so the result may contain names which aren't really used.
+func StaticUses(src, fnName string) ([]string, error) {
+	src = StripComments(src)
+	depths := braceDepths(src)
+
+	// Find the span of each function, from its name to the end of its body.
+	bodies := make(map[string]string)
+	for _, m := range fnNameRegexp.FindAllStringSubmatchIndex(src, -1) {
+		name := src[m[2]:m[3]]
+		if _, ok := bodies[name]; ok {
+			return nil, lineErrorf(LineOf(src, m[0]), "function %q: redeclared", name)
+		}
+		end := m[3]
+		for end < len(src) && src[end] != '{' {
+			end++
+		}
+		for end < len(src) && !(src[end] == '}' && depths[end+1] == depths[m[0]]) {
+			end++
+		}
+		if end == len(src) {
+			return nil, lineErrorf(LineOf(src, m[0]), "function %q: missing body", name)
+		}
+		bodies[name] = src[m[3]:end]
+	}
+	if _, ok := bodies[fnName]; !ok {
+		return nil, fmt.Errorf("no function named %q", fnName)
+	}
+
+	uses := make(map[string]bool)
+	visited := map[string]bool{fnName: true}
+	pending := []string{fnName}
+	for len(pending) > 0 {
+		body := bodies[pending[0]]
+		pending = pending[1:]
+		for _, m := range identRegexp.FindAllStringSubmatch(body, -1) {
+			if m[1] != "" {
+				continue
+			}
+			name := m[2]
+			uses[name] = true
+			if _, ok := bodies[name]; ok && !visited[name] {
+				visited[name] = true
+				pending = append(pending, name)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(uses))
+	for name := range uses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
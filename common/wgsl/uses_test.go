@@ -0,0 +1,101 @@
+package wgsl
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const usesSrc = `
+struct Params {
+  scale : f32,
+  gOther : f32,
+}
+
+@group(0) @binding(0) var<uniform> params : Params;
+@group(1) @binding(0) var<storage, read_write> gValues : array<f32>;
+@group(2) @binding(0) var<storage, read_write> gOther : array<f32>;
+
+fn scaled(x : f32) -> f32 {
+  return x * params.scale;
+}
+
+fn recurse(x : f32) -> f32 {
+  // gOther isn't really used.
+  return scaled(x);
+}
+
+@compute @workgroup_size(64)
+fn main(@builtin(global_invocation_id) id : vec3<u32>) {
+  gValues[id.x] = recurse(gValues[id.x]) + params.gOther;
+}
+
+@compute @workgroup_size(64)
+fn other(@builtin(global_invocation_id) id : vec3<u32>) {
+  if (false) {
+    gOther[id.x] = 0.0;
+  }
+}
+`
+
+func TestStaticUses(t *testing.T) {
+	tests := []struct {
+		fnName string
+		want   []string
+	}{
+		{
+			fnName: "main",
+			want:   []string{"builtin", "f32", "gValues", "global_invocation_id", "id", "params", "recurse", "return", "scaled", "u32", "vec3", "x"},
+		},
+		{
+			fnName: "other",
+			want:   []string{"builtin", "false", "gOther", "global_invocation_id", "id", "if", "u32", "vec3"},
+		},
+		{
+			fnName: "scaled",
+			want:   []string{"f32", "params", "return", "x"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.fnName, func(t *testing.T) {
+			got, err := StaticUses(usesSrc, tc.fnName)
+			if err != nil {
+				t.Fatalf("StaticUses() = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("diff mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStaticUsesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		fnName string
+	}{
+		{
+			name:   "missing function",
+			src:    usesSrc,
+			fnName: "missing",
+		},
+		{
+			name:   "redeclared function",
+			src:    "fn f() {}\nfn f() {}\n",
+			fnName: "f",
+		},
+		{
+			name:   "missing body",
+			src:    "fn f() -> f32;\n",
+			fnName: "f",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, err := StaticUses(tc.src, tc.fnName); err == nil {
+				t.Errorf("StaticUses() = %v, want error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Package wgsltest provides helpers for testing the WGSL the engine generates for shaders.
+package wgsltest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden prologues in testdata")
+
+// CheckPrologue compares the prologue the engine prepends to the shader called name with the golden file
+// testdata/<name>.prologue, which wgslcheck can be given with -prologue. With -update it rewrites the file.
+func CheckPrologue(t testing.TB, name, prologue string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".prologue")
+	if *update {
+		if err := os.WriteFile(path, []byte(prologue), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v: run go test with -update to create it", err)
+	}
+	if diff := cmp.Diff(string(want), prologue); diff != "" {
+		t.Errorf("%s is stale: run go test with -update to rewrite it (-old +new):\n%s", path, diff)
+	}
+}
@@ -0,0 +1,18 @@
+package wgsltest
+
+import (
+	"testing"
+
+	"github.com/hulkholden/gowebgpu/common/wgsltypes"
+)
+
+type testParams struct {
+	count uint32
+	scale float32
+}
+
+func TestCheckPrologue(t *testing.T) {
+	s := wgsltypes.MustRegisterStruct[testParams]()
+	consts := []wgsltypes.Const{wgsltypes.MustNewConst("maxCount", uint32(8))}
+	CheckPrologue(t, "test.wgsl", wgsltypes.Prologue([]wgsltypes.Struct{s}, consts))
+}
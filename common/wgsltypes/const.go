@@ -0,0 +1,108 @@
+package wgsltypes
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Const is a Go constant which can be declared in WGSL.
+type Const struct {
+	// Name is the name of the constant as it appears in WGSL.
+	Name string
+	// Type is the WGSL type of the constant.
+	Type TypeName
+	// Value is the WGSL literal for the constant's value.
+	Value string
+}
+
+// MustNewConst is like NewConst but panics if the name is empty, or the value's type has no WGSL
+// equivalent or its value doesn't fit in it.
+func MustNewConst[T any](name string, value T) Const {
+	c, err := NewConst(name, value)
+	if err != nil {
+		panic(fmt.Sprintf("exporting %s: %v", name, err))
+	}
+	return c
+}
+
+// NewConst returns a Const for the value.
+// The WGSL type is determined by the value's underlying Go type: signed integers are
+// exported as i32, unsigned integers as u32, floats as f32 and bools as bool.
+func NewConst[T any](name string, value T) (Const, error) {
+	if name == "" {
+		return Const{}, fmt.Errorf("name must not be empty")
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		return Const{Name: name, Type: "bool", Value: strconv.FormatBool(v.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := v.Int()
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return Const{}, fmt.Errorf("value %d of type %T doesn't fit in i32", i, value)
+		}
+		return Const{Name: name, Type: "i32", Value: fmt.Sprintf("%di", i)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxUint32 {
+			return Const{}, fmt.Errorf("value %d of type %T doesn't fit in u32", u, value)
+		}
+		return Const{Name: name, Type: "u32", Value: fmt.Sprintf("%du", u)}, nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) || math.Abs(f) > math.MaxFloat32 {
+			return Const{}, fmt.Errorf("value %v of type %T can't be represented as f32", f, value)
+		}
+		return Const{Name: name, Type: "f32", Value: strconv.FormatFloat(f, 'g', -1, 32) + "f"}, nil
+	}
+	return Const{}, fmt.Errorf("unhandled type %T", value)
+}
+
+// MustNewEnumConsts is like NewEnumConsts but panics if any of the names is empty, or the values' type
+// has no WGSL equivalent or any of the values doesn't fit in it.
+func MustNewEnumConsts[T any](values map[string]T) []Const {
+	consts, err := NewEnumConsts(values)
+	if err != nil {
+		var zero T
+		panic(fmt.Sprintf("exporting %T: %v", zero, err))
+	}
+	return consts
+}
+
+// NewEnumConsts returns a Const for each value of an enum, keyed by WGSL name.
+// The consts are sorted by name so the generated WGSL is deterministic.
+func NewEnumConsts[T any](values map[string]T) ([]Const, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	consts := make([]Const, len(names))
+	for i, name := range names {
+		c, err := NewConst(name, values[name])
+		if err != nil {
+			return nil, err
+		}
+		consts[i] = c
+	}
+	return consts, nil
+}
+
+// ToWGSL returns a string representing the constant as a WGSL const declaration.
+func (c Const) ToWGSL() string {
+	return fmt.Sprintf("const %s : %s = %s;\n", c.Name, c.Type, c.Value)
+}
+
+// ConstsToWGSL returns the WGSL declarations for all the consts.
+func ConstsToWGSL(consts []Const) string {
+	var output strings.Builder
+	for _, c := range consts {
+		output.WriteString(c.ToWGSL())
+	}
+	return output.String()
+}
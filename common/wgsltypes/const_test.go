@@ -0,0 +1,85 @@
+package wgsltypes
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testEnum uint8
+
+const (
+	testEnumA testEnum = iota
+	testEnumB
+	testEnumC
+)
+
+func TestNewConst(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    Const
+		wantErr string
+	}{
+		{name: "u", value: uint32(7), want: Const{Name: "u", Type: "u32", Value: "7u"}},
+		{name: "enum", value: testEnumC, want: Const{Name: "enum", Type: "u32", Value: "2u"}},
+		{name: "i", value: int32(-3), want: Const{Name: "i", Type: "i32", Value: "-3i"}},
+		{name: "untyped", value: 5, want: Const{Name: "untyped", Type: "i32", Value: "5i"}},
+		{name: "f", value: float32(1.5), want: Const{Name: "f", Type: "f32", Value: "1.5f"}},
+		{name: "whole", value: 100.0, want: Const{Name: "whole", Type: "f32", Value: "100f"}},
+		{name: "big", value: float32(1e20), want: Const{Name: "big", Type: "f32", Value: "1e+20f"}},
+		{name: "b", value: true, want: Const{Name: "b", Type: "bool", Value: "true"}},
+		{name: "too big", value: int64(math.MaxInt32 + 1), wantErr: "doesn't fit in i32"},
+		{name: "too big unsigned", value: uint64(math.MaxUint32 + 1), wantErr: "doesn't fit in u32"},
+		{name: "inf", value: math.Inf(1), wantErr: "can't be represented as f32"},
+		{name: "string", value: "foo", wantErr: "unhandled type"},
+		{name: "", value: 1, wantErr: "must not be empty"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewConst(tc.name, tc.value)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("NewConst(%q, %v) error = %v, want error containing %q", tc.name, tc.value, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConst(%q, %v) = %v", tc.name, tc.value, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("diff mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEnumConstsToWGSL(t *testing.T) {
+	consts := MustNewEnumConsts(map[string]testEnum{
+		"testEnumC": testEnumC,
+		"testEnumA": testEnumA,
+		"testEnumB": testEnumB,
+	})
+	consts = append(consts, MustNewConst("testScale", float32(0.25)))
+
+	got := ConstsToWGSL(consts)
+	want := `const testEnumA : u32 = 0u;
+const testEnumB : u32 = 1u;
+const testEnumC : u32 = 2u;
+const testScale : f32 = 0.25f;
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMustNewEnumConstsPanicsOnError(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("MustNewEnumConsts() did not panic")
+		}
+	}()
+	MustNewEnumConsts(map[string]int64{"big": math.MaxInt64})
+}
@@ -0,0 +1,43 @@
+package wgsltypes
+
+// Dependencies returns the structs referenced by the struct's fields, in field order.
+// Structs nested inside arrays and atomics are included, and each struct is returned at most once.
+func (s Struct) Dependencies() []Struct {
+	var deps []Struct
+	seen := make(map[GoTypeName]bool)
+	for _, fieldName := range s.Fields {
+		t := s.FieldMap[fieldName].WGSLType
+		for t.Elem != nil {
+			t = *t.Elem
+		}
+		if t.Kind != KindStruct || seen[t.GoName] {
+			continue
+		}
+		seen[t.GoName] = true
+		deps = append(deps, registeredGoStructs[t.GoName])
+	}
+	return deps
+}
+
+// ReachableStructs returns every struct reachable from roots, with each struct
+// appearing once and after all of its dependencies, so the definitions can be
+// declared in order.
+func ReachableStructs(roots []Struct) []Struct {
+	var result []Struct
+	visited := make(map[GoTypeName]bool)
+	var visit func(s Struct)
+	visit = func(s Struct) {
+		if visited[s.GoName] {
+			return
+		}
+		visited[s.GoName] = true
+		for _, dep := range s.Dependencies() {
+			visit(dep)
+		}
+		result = append(result, s)
+	}
+	for _, s := range roots {
+		visit(s)
+	}
+	return result
+}
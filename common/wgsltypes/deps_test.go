@@ -0,0 +1,108 @@
+package wgsltypes
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+)
+
+type depsLeaf struct {
+	v vmath.V2
+}
+
+type depsMiddle struct {
+	leaf  depsLeaf
+	count uint32
+	pad   uint32
+}
+
+type depsRoot struct {
+	middles [2]depsMiddle
+	leaf    depsLeaf
+	other   depsLeaf
+}
+
+type depsOtherRoot struct {
+	leaves [4]depsLeaf `runtimeArray:"true"`
+}
+
+func goNames(structs []Struct) []GoTypeName {
+	var names []GoTypeName
+	for _, s := range structs {
+		names = append(names, s.GoName)
+	}
+	return names
+}
+
+func TestDependencies(t *testing.T) {
+	leaf := MustRegisterStruct[depsLeaf]()
+	middle := MustRegisterStruct[depsMiddle]()
+	root := MustRegisterStruct[depsRoot]()
+
+	tests := []struct {
+		name string
+		s    Struct
+		want []GoTypeName
+	}{
+		{name: "leaf", s: leaf, want: nil},
+		{name: "middle", s: middle, want: []GoTypeName{leaf.GoName}},
+		{name: "root", s: root, want: []GoTypeName{middle.GoName, leaf.GoName}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := goNames(tc.s.Dependencies())
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Dependencies() diff mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReachableStructs(t *testing.T) {
+	leaf := MustRegisterStruct[depsLeaf]()
+	middle := MustRegisterStruct[depsMiddle]()
+	root := MustRegisterStruct[depsRoot]()
+	otherRoot := MustRegisterStruct[depsOtherRoot]()
+	simple := MustRegisterStruct[simpleStruct]()
+
+	tests := []struct {
+		name  string
+		roots []Struct
+		want  []GoTypeName
+	}{
+		{
+			name:  "no roots",
+			roots: nil,
+			want:  nil,
+		},
+		{
+			name:  "single struct",
+			roots: []Struct{simple},
+			want:  []GoTypeName{simple.GoName},
+		},
+		{
+			name:  "dependencies come first",
+			roots: []Struct{root},
+			want:  []GoTypeName{leaf.GoName, middle.GoName, root.GoName},
+		},
+		{
+			name:  "duplicates are removed",
+			roots: []Struct{leaf, root, middle, root},
+			want:  []GoTypeName{leaf.GoName, middle.GoName, root.GoName},
+		},
+		{
+			name:  "shared dependencies",
+			roots: []Struct{otherRoot, simple, root},
+			want:  []GoTypeName{leaf.GoName, otherRoot.GoName, simple.GoName, middle.GoName, root.GoName},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := goNames(ReachableStructs(tc.roots))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ReachableStructs() diff mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
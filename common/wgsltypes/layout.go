@@ -0,0 +1,305 @@
+package wgsltypes
+
+import (
+	"fmt"
+	"go/format"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// An AddressSpace is a WGSL address space that a struct can be used in.
+// The uniform address space has stricter layout constraints than storage
+// (see https://www.w3.org/TR/WGSL/#address-space-layout-constraints).
+type AddressSpace int
+
+const (
+	AddressSpaceStorage AddressSpace = iota
+	AddressSpaceUniform
+)
+
+func (a AddressSpace) String() string {
+	switch a {
+	case AddressSpaceStorage:
+		return "storage"
+	case AddressSpaceUniform:
+		return "uniform"
+	}
+	return fmt.Sprintf("AddressSpace(%d)", int(a))
+}
+
+// requiredAlignOf returns the alignment required for fields of type t in the address space.
+func (a AddressSpace) requiredAlignOf(t Type) int {
+	if a == AddressSpaceUniform && (t.Kind == KindStruct || t.Kind == KindArray || t.Kind == KindRuntimeArray) {
+		return roundUp(16, t.AlignOf)
+	}
+	return t.AlignOf
+}
+
+// minSpan returns the minimum number of bytes between the start of a field of type t and the start of the next field.
+func (a AddressSpace) minSpan(t Type) int {
+	if a == AddressSpaceUniform && t.Kind == KindStruct {
+		return roundUp(16, t.SizeOf)
+	}
+	return t.SizeOf
+}
+
+// checkType returns an error if values of type t can't be used in the address space.
+func (a AddressSpace) checkType(t Type) error {
+	if a != AddressSpaceUniform {
+		return nil
+	}
+	switch t.Kind {
+	case KindAtomic:
+		return fmt.Errorf("atomic types can't be used in the %s address space", a)
+	case KindRuntimeArray:
+		return fmt.Errorf("runtime-sized arrays can't be used in the %s address space", a)
+	case KindArray:
+		if stride := arrayStride(t.Elem.AlignOf, t.Elem.SizeOf); stride%16 != 0 {
+			return fmt.Errorf("array element stride is %d bytes but the %s address space requires a multiple of 16", stride, a)
+		}
+		return a.checkType(*t.Elem)
+	case KindStruct:
+		if s, ok := registeredGoStructs[t.GoName]; ok {
+			if err := s.Validate(a); err != nil {
+				return fmt.Errorf("struct %q: %v", s.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// A FieldLayout describes where a field is placed in Go and in WGSL.
+type FieldLayout struct {
+	// Name is the name of the field.
+	Name string
+	// Type is the WGSL type of the field.
+	Type Type
+	// GoOffset is the offset of the field in the Go struct.
+	GoOffset int
+	// WGSLOffset is the offset of the field in the equivalent WGSL struct.
+	WGSLOffset int
+	// Padding is the number of bytes WGSL inserts between the previous field and this one.
+	Padding int
+	// RequiredAlignOf is the alignment the address space requires for the field's offset.
+	RequiredAlignOf int
+}
+
+// Matches returns true if the field has the same offset in Go and WGSL, and that offset is valid for the address space.
+func (f FieldLayout) Matches() bool {
+	return f.GoOffset == f.WGSLOffset && f.WGSLOffset%f.RequiredAlignOf == 0
+}
+
+// A Layout describes how a Go struct is laid out in a WGSL address space.
+type Layout struct {
+	// Name is the name of the struct as it appears in WGSL.
+	Name TypeName
+	// GoName is the name of the struct as it appears in Go.
+	GoName GoTypeName
+	// AddressSpace is the address space the layout applies to.
+	AddressSpace AddressSpace
+
+	// Fields describes the layout of each field, in declaration order.
+	Fields []FieldLayout
+
+	// GoSize is the size of the Go struct, in bytes.
+	GoSize int
+	// AlignOf is the alignment of the struct in WGSL.
+	AlignOf int
+	// SizeOf is the size of the struct in WGSL.
+	SizeOf int
+	// TrailingPadding is the number of bytes WGSL inserts after the last field.
+	TrailingPadding int
+}
+
+// MissingPadding returns the number of bytes that need to be added to the end of the Go struct for its size to match WGSL.
+func (l Layout) MissingPadding() int {
+	return max(0, l.SizeOf-l.GoSize)
+}
+
+// WastedBytes returns the total number of padding bytes in the WGSL struct.
+func (l Layout) WastedBytes() int {
+	wasted := l.TrailingPadding
+	for _, f := range l.Fields {
+		wasted += f.Padding
+	}
+	return wasted
+}
+
+func (l Layout) String() string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("struct %q (%s), Go size %d, WGSL size %d, align %d\n", l.GoName, l.AddressSpace, l.GoSize, l.SizeOf, l.AlignOf))
+	w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "  \tfield\ttype\tgo offset\twgsl offset\talign\tpadding\t")
+	for _, f := range l.Fields {
+		marker := ""
+		if !f.Matches() {
+			marker = "!"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t\n", marker, f.Name, f.Type.Name, f.GoOffset, f.WGSLOffset, f.RequiredAlignOf, f.Padding)
+	}
+	w.Flush()
+	output.WriteString(fmt.Sprintf("trailing padding %d bytes, missing padding %d bytes, wasted %d bytes\n", l.TrailingPadding, l.MissingPadding(), l.WastedBytes()))
+	return output.String()
+}
+
+// Layout returns the layout of the struct in the given address space.
+func (s Struct) Layout(space AddressSpace) Layout {
+	l := Layout{
+		Name:         s.Name,
+		GoName:       s.GoName,
+		AddressSpace: space,
+		GoSize:       s.Size,
+		AlignOf:      1,
+	}
+	end := 0
+	for _, fieldName := range s.Fields {
+		f := s.FieldMap[fieldName]
+		offset := roundUp(f.WGSLType.AlignOf, end)
+		l.Fields = append(l.Fields, FieldLayout{
+			Name:            f.Name,
+			Type:            f.WGSLType,
+			GoOffset:        int(f.Offset),
+			WGSLOffset:      offset,
+			Padding:         offset - end,
+			RequiredAlignOf: space.requiredAlignOf(f.WGSLType),
+		})
+		end = offset + f.WGSLType.SizeOf
+		l.AlignOf = max(l.AlignOf, f.WGSLType.AlignOf)
+	}
+	l.SizeOf = roundUp(l.AlignOf, end)
+	l.TrailingPadding = l.SizeOf - end
+	return l
+}
+
+// Validate returns an error if the GPU would lay out the struct differently from Go in the given address space.
+// The error identifies the first field that violates the address space's layout constraints.
+func (s Struct) Validate(space AddressSpace) error {
+	l := s.Layout(space)
+	for i, f := range l.Fields {
+		if err := space.checkType(f.Type); err != nil {
+			return fmt.Errorf("field %q: %v", f.Name, err)
+		}
+		if f.GoOffset != f.WGSLOffset {
+			return fmt.Errorf("field %q: Go offset is %d but wgsl offset is %d", f.Name, f.GoOffset, f.WGSLOffset)
+		}
+		if f.WGSLOffset%f.RequiredAlignOf != 0 {
+			return fmt.Errorf("field %q: offset %d is not a multiple of %d, as required for %s in the %s address space", f.Name, f.WGSLOffset, f.RequiredAlignOf, f.Type.Name, space)
+		}
+		if i+1 < len(l.Fields) {
+			next := l.Fields[i+1]
+			if span, minSpan := next.WGSLOffset-f.WGSLOffset, space.minSpan(f.Type); span < minSpan {
+				return fmt.Errorf("field %q: next field %q starts %d bytes after it but the %s address space requires at least %d", f.Name, next.Name, span, space, minSpan)
+			}
+		}
+	}
+	return nil
+}
+
+// ExplainLayout returns the layout of T in the given address space.
+// Unlike RegisterStruct it doesn't fail if the Go layout differs from WGSL, so it can be used to diagnose those errors.
+func ExplainLayout[T any](space AddressSpace) (Layout, error) {
+	var t T
+	s, err := newStruct(reflect.TypeOf(t))
+	if err != nil {
+		return Layout{}, err
+	}
+	return s.Layout(space), nil
+}
+
+// A FieldSpec describes a field to include in a struct generated by GeneratePaddedStruct.
+type FieldSpec struct {
+	// Name is the name of the field.
+	Name string
+	// Type is the Go type of the field.
+	Type reflect.Type
+	// Tag is an optional struct tag, e.g. `atomic:"true"`.
+	Tag reflect.StructTag
+}
+
+// GeneratePaddedStruct returns the Go source for a struct with the provided fields,
+// with padding fields inserted so the Go layout matches WGSL in the given address space.
+// Field types are qualified with their package name. Gaps which aren't a multiple of 4 bytes
+// are padded with vmath.F16, so it returns an error if they're needed but no field uses f16:
+// the padding would otherwise make the struct require `enable f16;`.
+func GeneratePaddedStruct(name string, fields []FieldSpec, space AddressSpace) (string, error) {
+	usedNames := make(map[string]bool)
+	for _, f := range fields {
+		usedNames[f.Name] = true
+	}
+	usesF16 := false
+	padIdx := 0
+	nextPadName := func() string {
+		for {
+			name := fmt.Sprintf("pad%d", padIdx)
+			padIdx++
+			if !usedNames[name] {
+				return name
+			}
+		}
+	}
+
+	var output strings.Builder
+	writePadding := func(offset, n int) error {
+		for n > 0 {
+			if offset%4 != 0 || n < 4 {
+				if !usesF16 {
+					return fmt.Errorf("%d byte gap at offset %d can only be padded with f16, which no field uses", n, offset)
+				}
+				output.WriteString(fmt.Sprintf("\t%s vmath.F16\n", nextPadName()))
+				offset += 2
+				n -= 2
+				continue
+			}
+			output.WriteString(fmt.Sprintf("\t%s uint32\n", nextPadName()))
+			offset += 4
+			n -= 4
+		}
+		return nil
+	}
+
+	types := make([]Type, len(fields))
+	for i, spec := range fields {
+		t, err := lookupFieldType(reflect.StructField{Name: spec.Name, Type: spec.Type, Tag: spec.Tag})
+		if err != nil {
+			return "", fmt.Errorf("field %q: %v", spec.Name, err)
+		}
+		types[i] = t
+		usesF16 = usesF16 || f16Regexp.MatchString(string(t.Name))
+	}
+
+	output.WriteString(fmt.Sprintf("type %s struct {\n", name))
+	end, alignOf := 0, 1
+	for i, spec := range fields {
+		t := types[i]
+		if err := space.checkType(t); err != nil {
+			return "", fmt.Errorf("field %q: %v", spec.Name, err)
+		}
+		// Padding can't be inserted inside nested structs or between array elements.
+		if goSize := int(spec.Type.Size()); goSize != t.SizeOf {
+			return "", fmt.Errorf("field %q: Go size is %d bytes but WGSL size is %d bytes", spec.Name, goSize, t.SizeOf)
+		}
+
+		offset := roundUp(space.requiredAlignOf(t), end)
+		if err := writePadding(end, offset-end); err != nil {
+			return "", fmt.Errorf("field %q: %v", spec.Name, err)
+		}
+		if spec.Tag != "" {
+			output.WriteString(fmt.Sprintf("\t%s %s `%s`\n", spec.Name, spec.Type, spec.Tag))
+		} else {
+			output.WriteString(fmt.Sprintf("\t%s %s\n", spec.Name, spec.Type))
+		}
+		end = offset + space.minSpan(t)
+		alignOf = max(alignOf, t.AlignOf)
+	}
+	if err := writePadding(end, roundUp(alignOf, end)-end); err != nil {
+		return "", fmt.Errorf("trailing padding: %v", err)
+	}
+	output.WriteString("}\n")
+
+	src, err := format.Source([]byte(output.String()))
+	if err != nil {
+		return "", fmt.Errorf("formatting generated struct: %v", err)
+	}
+	return string(src), nil
+}
@@ -0,0 +1,404 @@
+package wgsltypes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+)
+
+// halfInner uses f16, but a struct with it as a field doesn't.
+type halfInner struct {
+	h vmath.F16
+}
+
+// unpaddedLayoutStruct is missing padding after vec3 and at the end.
+type unpaddedLayoutStruct struct {
+	a vmath.V3
+	b vmath.V2
+	c float32
+}
+
+func TestExplainLayout(t *testing.T) {
+	got, err := ExplainLayout[unpaddedLayoutStruct](AddressSpaceStorage)
+	if err != nil {
+		t.Fatalf("ExplainLayout() = %v", err)
+	}
+	want := Layout{
+		Name:         "unpaddedLayoutStruct",
+		GoName:       "github.com/hulkholden/gowebgpu/common/wgsltypes.unpaddedLayoutStruct",
+		AddressSpace: AddressSpaceStorage,
+		Fields: []FieldLayout{
+			{Name: "a", Type: Type{Name: "vec3<f32>", AlignOf: 16, SizeOf: 12}, GoOffset: 0, WGSLOffset: 0, Padding: 0, RequiredAlignOf: 16},
+			{Name: "b", Type: Type{Name: "vec2<f32>", AlignOf: 8, SizeOf: 8}, GoOffset: 12, WGSLOffset: 16, Padding: 4, RequiredAlignOf: 8},
+			{Name: "c", Type: Type{Name: "f32", AlignOf: 4, SizeOf: 4}, GoOffset: 20, WGSLOffset: 24, Padding: 0, RequiredAlignOf: 4},
+		},
+		GoSize:          24,
+		AlignOf:         16,
+		SizeOf:          32,
+		TrailingPadding: 4,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff mismatch (-want +got):\n%s", diff)
+	}
+	if got.Fields[0].Matches() != true || got.Fields[1].Matches() != false {
+		t.Errorf("Matches() = %v, %v, want true, false", got.Fields[0].Matches(), got.Fields[1].Matches())
+	}
+	if got.MissingPadding() != 8 {
+		t.Errorf("MissingPadding() = %d, want 8", got.MissingPadding())
+	}
+	if got.WastedBytes() != 8 {
+		t.Errorf("WastedBytes() = %d, want 8", got.WastedBytes())
+	}
+
+	str := got.String()
+	for _, want := range []string{"unpaddedLayoutStruct", "vec3<f32>", "missing padding 8 bytes"} {
+		if !strings.Contains(str, want) {
+			t.Errorf("String() = %q, want it to contain %q", str, want)
+		}
+	}
+}
+
+func TestExplainLayoutUnhandledType(t *testing.T) {
+	if _, err := ExplainLayout[unsupportedFieldStruct](AddressSpaceStorage); err == nil {
+		t.Errorf("ExplainLayout[unsupportedFieldStruct]() succeeded, want error")
+	}
+}
+
+func TestLayoutRequiredAlignment(t *testing.T) {
+	MustRegisterStruct[paddedInner]()
+	s := MustRegisterStruct[nestedPaddedOuter]()
+
+	storage := s.Layout(AddressSpaceStorage)
+	uniform := s.Layout(AddressSpaceUniform)
+	const innerIdx = 2
+	if got := storage.Fields[innerIdx].RequiredAlignOf; got != 8 {
+		t.Errorf("storage RequiredAlignOf = %d, want 8", got)
+	}
+	if got := uniform.Fields[innerIdx].RequiredAlignOf; got != 16 {
+		t.Errorf("uniform RequiredAlignOf = %d, want 16", got)
+	}
+	if !storage.Fields[innerIdx].Matches() {
+		t.Errorf("storage field %q doesn't match, want match", storage.Fields[innerIdx].Name)
+	}
+	if uniform.Fields[innerIdx].Matches() {
+		t.Errorf("uniform field %q matches, want mismatch", uniform.Fields[innerIdx].Name)
+	}
+}
+
+func TestGeneratePaddedStruct(t *testing.T) {
+	MustRegisterStruct[paddedInner]()
+	MustRegisterStruct[halfInner]()
+
+	tests := []struct {
+		name    string
+		fields  []FieldSpec
+		space   AddressSpace
+		want    string
+		wantErr string
+	}{
+		{
+			name: "no padding needed",
+			fields: []FieldSpec{
+				{Name: "pos", Type: reflect.TypeOf(vmath.V2{})},
+				{Name: "vel", Type: reflect.TypeOf(vmath.V2{})},
+			},
+			space: AddressSpaceStorage,
+			want: `type Foo struct {
+	pos vmath.V2
+	vel vmath.V2
+}
+`,
+		},
+		{
+			name: "vec3 and trailing padding",
+			fields: []FieldSpec{
+				{Name: "a", Type: reflect.TypeOf(vmath.V3{})},
+				{Name: "b", Type: reflect.TypeOf(vmath.V2{})},
+				{Name: "c", Type: reflect.TypeOf(float32(0))},
+			},
+			space: AddressSpaceStorage,
+			want: `type Foo struct {
+	a    vmath.V3
+	pad0 uint32
+	b    vmath.V2
+	c    float32
+	pad1 uint32
+}
+`,
+		},
+		{
+			name: "tags are preserved",
+			fields: []FieldSpec{
+				{Name: "count", Type: reflect.TypeOf(uint32(0)), Tag: `atomic:"true"`},
+				{Name: "elements", Type: reflect.TypeOf([4]vmath.V2{}), Tag: `runtimeArray:"true"`},
+			},
+			space: AddressSpaceStorage,
+			want: `type Foo struct {
+	count    uint32 ` + "`" + `atomic:"true"` + "`" + `
+	pad0     uint32
+	elements [4]vmath.V2 ` + "`" + `runtimeArray:"true"` + "`" + `
+}
+`,
+		},
+		{
+			name: "f16 padding",
+			fields: []FieldSpec{
+				{Name: "h", Type: reflect.TypeOf(vmath.F16(0))},
+				{Name: "f", Type: reflect.TypeOf(float32(0))},
+			},
+			space: AddressSpaceStorage,
+			want: `type Foo struct {
+	h    vmath.F16
+	pad0 vmath.F16
+	f    float32
+}
+`,
+		},
+		{
+			name: "f16 padding without f16 fields",
+			fields: []FieldSpec{
+				{Name: "inner", Type: reflect.TypeOf(halfInner{})},
+				{Name: "f", Type: reflect.TypeOf(float32(0))},
+			},
+			space:   AddressSpaceStorage,
+			wantErr: `field "f": 2 byte gap at offset 2 can only be padded with f16, which no field uses`,
+		},
+		{
+			name: "nested struct in storage",
+			fields: []FieldSpec{
+				{Name: "f", Type: reflect.TypeOf(float32(0))},
+				{Name: "inner", Type: reflect.TypeOf(paddedInner{})},
+				{Name: "g", Type: reflect.TypeOf(float32(0))},
+			},
+			space: AddressSpaceStorage,
+			want: `type Foo struct {
+	f     float32
+	pad0  uint32
+	inner wgsltypes.paddedInner
+	g     float32
+	pad1  uint32
+}
+`,
+		},
+		{
+			name: "nested struct in uniform",
+			fields: []FieldSpec{
+				{Name: "f", Type: reflect.TypeOf(float32(0))},
+				{Name: "inner", Type: reflect.TypeOf(paddedInner{})},
+				{Name: "g", Type: reflect.TypeOf(float32(0))},
+			},
+			space: AddressSpaceUniform,
+			want: `type Foo struct {
+	f     float32
+	pad0  uint32
+	pad1  uint32
+	pad2  uint32
+	inner wgsltypes.paddedInner
+	g     float32
+	pad3  uint32
+}
+`,
+		},
+		{
+			name: "pad names avoid existing fields",
+			fields: []FieldSpec{
+				{Name: "pad0", Type: reflect.TypeOf(float32(0))},
+				{Name: "v", Type: reflect.TypeOf(vmath.V2{})},
+			},
+			space: AddressSpaceStorage,
+			want: `type Foo struct {
+	pad0 float32
+	pad1 uint32
+	v    vmath.V2
+}
+`,
+		},
+		{
+			name: "vec3 array stride",
+			fields: []FieldSpec{
+				{Name: "values", Type: reflect.TypeOf([2]vmath.V3{})},
+				{Name: "g", Type: reflect.TypeOf(float32(0))},
+			},
+			space:   AddressSpaceStorage,
+			wantErr: "Go size is 24 bytes but WGSL size is 32 bytes",
+		},
+		{
+			name: "uniform array stride",
+			fields: []FieldSpec{
+				{Name: "values", Type: reflect.TypeOf([4]float32{})},
+			},
+			space:   AddressSpaceUniform,
+			wantErr: "multiple of 16",
+		},
+		{
+			name: "uniform atomic",
+			fields: []FieldSpec{
+				{Name: "count", Type: reflect.TypeOf(uint32(0)), Tag: `atomic:"true"`},
+			},
+			space:   AddressSpaceUniform,
+			wantErr: "atomic types",
+		},
+		{
+			name: "unhandled type",
+			fields: []FieldSpec{
+				{Name: "f", Type: reflect.TypeOf(float64(0))},
+			},
+			space:   AddressSpaceStorage,
+			wantErr: "unhandled type",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := GeneratePaddedStruct("Foo", tc.fields, tc.space)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("GeneratePaddedStruct() error = %v, want error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GeneratePaddedStruct() = %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("diff mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// generatedUniformStruct is the output of GeneratePaddedStruct for the "nested struct in uniform" case.
+type generatedUniformStruct struct {
+	f     float32
+	pad0  uint32
+	pad1  uint32
+	pad2  uint32
+	inner paddedInner
+	g     float32
+	pad3  uint32
+}
+
+func TestGeneratedStructRegisters(t *testing.T) {
+	MustRegisterStruct[paddedInner]()
+	s, err := RegisterStruct[generatedUniformStruct]()
+	if err != nil {
+		t.Fatalf("RegisterStruct[generatedUniformStruct]() = %v", err)
+	}
+	l := s.Layout(AddressSpaceUniform)
+	for _, f := range l.Fields {
+		if !f.Matches() {
+			t.Errorf("field %q doesn't match in uniform layout:\n%v", f.Name, l)
+		}
+	}
+	if l.MissingPadding() != 0 {
+		t.Errorf("MissingPadding() = %d, want 0", l.MissingPadding())
+	}
+}
+
+type scalarArrayStruct struct {
+	values [4]float32
+}
+
+type vec4ArrayStruct struct {
+	values [4]vmath.V4
+}
+
+type atomicCountStruct struct {
+	count uint32 `atomic:"true"`
+}
+
+type runtimeArrayStruct struct {
+	values [4]vmath.V4 `runtimeArray:"true"`
+}
+
+// shortSpanOuter is valid in storage, but uniform requires 16 bytes between inner and f.
+type shortSpanOuter struct {
+	inner nestedInner
+	f     float32
+	g     float32
+}
+
+// invalidNestedOuter contains a struct which can't be used in uniform.
+type invalidNestedOuter struct {
+	inner scalarArrayStruct
+}
+
+func TestValidate(t *testing.T) {
+	MustRegisterStruct[paddedInner]()
+	MustRegisterStruct[nestedInner]()
+	MustRegisterStruct[scalarArrayStruct]()
+
+	tests := []struct {
+		name           string
+		s              Struct
+		wantStorageErr string
+		wantUniformErr string
+	}{
+		{
+			name: "scalars",
+			s:    MustRegisterStruct[simpleStruct](),
+		},
+		{
+			name: "vec4 array",
+			s:    MustRegisterStruct[vec4ArrayStruct](),
+		},
+		{
+			name: "generated uniform struct",
+			s:    MustRegisterStruct[generatedUniformStruct](),
+		},
+		{
+			name:           "nested struct alignment",
+			s:              MustRegisterStruct[nestedPaddedOuter](),
+			wantUniformErr: `field "inner": offset 8 is not a multiple of 16`,
+		},
+		{
+			name:           "scalar array stride",
+			s:              MustRegisterStruct[scalarArrayStruct](),
+			wantUniformErr: `field "values": array element stride is 4 bytes`,
+		},
+		{
+			name:           "atomic",
+			s:              MustRegisterStruct[atomicCountStruct](),
+			wantUniformErr: `field "count": atomic types can't be used in the uniform address space`,
+		},
+		{
+			name:           "runtime array",
+			s:              MustRegisterStruct[runtimeArrayStruct](),
+			wantUniformErr: `field "values": runtime-sized arrays can't be used in the uniform address space`,
+		},
+		{
+			name:           "nested struct span",
+			s:              MustRegisterStruct[shortSpanOuter](),
+			wantUniformErr: `field "inner": next field "f" starts 8 bytes after it but the uniform address space requires at least 16`,
+		},
+		{
+			name:           "invalid nested struct",
+			s:              MustRegisterStruct[invalidNestedOuter](),
+			wantUniformErr: `field "inner": struct "scalarArrayStruct": field "values": array element stride is 4 bytes`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, c := range []struct {
+				space   AddressSpace
+				wantErr string
+			}{
+				{space: AddressSpaceStorage, wantErr: tc.wantStorageErr},
+				{space: AddressSpaceUniform, wantErr: tc.wantUniformErr},
+			} {
+				err := tc.s.Validate(c.space)
+				if c.wantErr == "" {
+					if err != nil {
+						t.Errorf("Validate(%v) = %v, want nil error", c.space, err)
+					}
+					continue
+				}
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("Validate(%v) = %v, want error containing %q", c.space, err, c.wantErr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package wgsltypes
+
+import "strings"
+
+// Prologue returns the WGSL declarations for structs, all the structs they depend on, and consts.
+// It enables f16 if any of the structs use it.
+func Prologue(structs []Struct, consts []Const) string {
+	structs = ReachableStructs(structs)
+	defs := make([]string, len(structs))
+	usesF16 := false
+	for i, s := range structs {
+		defs[i] = s.ToWGSL()
+		usesF16 = usesF16 || s.UsesF16()
+	}
+	prologue := strings.Join(defs, "\n")
+	if len(consts) > 0 {
+		prologue += "\n" + ConstsToWGSL(consts)
+	}
+	if usesF16 {
+		// Enable directives must appear before any declarations.
+		prologue = "enable f16;\n\n" + prologue
+	}
+	return prologue
+}
@@ -0,0 +1,66 @@
+package wgsltypes
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hulkholden/gowebgpu/common/vmath"
+)
+
+type prologueLeaf struct {
+	v vmath.V2
+}
+
+type prologueRoot struct {
+	leaf  prologueLeaf
+	count uint32
+	pad   uint32
+}
+
+type prologueHalf struct {
+	h vmath.V2h
+}
+
+func TestPrologue(t *testing.T) {
+	MustRegisterStruct[prologueLeaf]()
+	root := MustRegisterStruct[prologueRoot]()
+	half := MustRegisterStruct[prologueHalf]()
+	limit := MustNewConst("limit", uint32(4))
+
+	tests := []struct {
+		name    string
+		structs []Struct
+		consts  []Const
+		want    string
+	}{
+		{
+			name: "empty",
+			want: "",
+		},
+		{
+			name:    "dependencies first",
+			structs: []Struct{root},
+			want: "struct prologueLeaf {\n  v : vec2<f32>,\n}\n" +
+				"\n" +
+				"struct prologueRoot {\n  leaf : prologueLeaf,\n  count : u32,\n  pad : u32,\n}\n",
+		},
+		{
+			name:   "consts",
+			consts: []Const{limit},
+			want:   "\nconst limit : u32 = 4u;\n",
+		},
+		{
+			name:    "f16",
+			structs: []Struct{half},
+			want:    "enable f16;\n\nstruct prologueHalf {\n  h : vec2<f16>,\n}\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Prologue(tc.structs, tc.consts)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Prologue() diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package wgsltypes
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 )
 
@@ -22,8 +23,31 @@ var goToTypeMap = map[GoTypeName]TypeName{
 	"github.com/hulkholden/gowebgpu/common/vmath.V2": "vec2<f32>",
 	"github.com/hulkholden/gowebgpu/common/vmath.V3": "vec3<f32>",
 	"github.com/hulkholden/gowebgpu/common/vmath.V4": "vec4<f32>",
+
+	"github.com/hulkholden/gowebgpu/common/vmath.V2i": "vec2<i32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.V3i": "vec3<i32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.V4i": "vec4<i32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.V2u": "vec2<u32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.V3u": "vec3<u32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.V4u": "vec4<u32>",
+
+	"github.com/hulkholden/gowebgpu/common/vmath.F16": "f16",
+	"github.com/hulkholden/gowebgpu/common/vmath.V2h": "vec2<f16>",
+	"github.com/hulkholden/gowebgpu/common/vmath.V4h": "vec4<f16>",
+
+	"github.com/hulkholden/gowebgpu/common/vmath.M2":   "mat2x2<f32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.M2x3": "mat2x3<f32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.M2x4": "mat2x4<f32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.M3x2": "mat3x2<f32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.M3":   "mat3x3<f32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.M3x4": "mat3x4<f32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.M4x2": "mat4x2<f32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.M4x3": "mat4x3<f32>",
+	"github.com/hulkholden/gowebgpu/common/vmath.M4":   "mat4x4<f32>",
 }
 
+// builtinTypeMap describes the layout of WGSL builtin types.
+// bool and vecN<bool> are not host-shareable so can't be used in buffers and have no entry here.
 var builtinTypeMap = map[TypeName]Type{
 	"f32":       {Name: "f32", AlignOf: 4, SizeOf: 4},
 	"i32":       {Name: "i32", AlignOf: 4, SizeOf: 4},
@@ -31,8 +55,40 @@ var builtinTypeMap = map[TypeName]Type{
 	"vec2<f32>": {Name: "vec2<f32>", AlignOf: 8, SizeOf: 8},
 	"vec3<f32>": {Name: "vec3<f32>", AlignOf: 16, SizeOf: 12},
 	"vec4<f32>": {Name: "vec4<f32>", AlignOf: 16, SizeOf: 16},
+	"vec2<i32>": {Name: "vec2<i32>", AlignOf: 8, SizeOf: 8},
+	"vec3<i32>": {Name: "vec3<i32>", AlignOf: 16, SizeOf: 12},
+	"vec4<i32>": {Name: "vec4<i32>", AlignOf: 16, SizeOf: 16},
+	"vec2<u32>": {Name: "vec2<u32>", AlignOf: 8, SizeOf: 8},
+	"vec3<u32>": {Name: "vec3<u32>", AlignOf: 16, SizeOf: 12},
+	"vec4<u32>": {Name: "vec4<u32>", AlignOf: 16, SizeOf: 16},
+
+	// f16 types require `enable f16;` and the shader-f16 device feature.
+	"f16":       {Name: "f16", AlignOf: 2, SizeOf: 2},
+	"vec2<f16>": {Name: "vec2<f16>", AlignOf: 4, SizeOf: 4},
+	"vec4<f16>": {Name: "vec4<f16>", AlignOf: 8, SizeOf: 8},
+
+	// Matrices are laid out as an array of column vectors, so matCx3 columns are padded to 16 bytes.
+	"mat2x2<f32>": {Name: "mat2x2<f32>", AlignOf: 8, SizeOf: 16},
+	"mat2x3<f32>": {Name: "mat2x3<f32>", AlignOf: 16, SizeOf: 32},
+	"mat2x4<f32>": {Name: "mat2x4<f32>", AlignOf: 16, SizeOf: 32},
+	"mat3x2<f32>": {Name: "mat3x2<f32>", AlignOf: 8, SizeOf: 24},
+	"mat3x3<f32>": {Name: "mat3x3<f32>", AlignOf: 16, SizeOf: 48},
+	"mat3x4<f32>": {Name: "mat3x4<f32>", AlignOf: 16, SizeOf: 48},
+	"mat4x2<f32>": {Name: "mat4x2<f32>", AlignOf: 8, SizeOf: 32},
+	"mat4x3<f32>": {Name: "mat4x3<f32>", AlignOf: 16, SizeOf: 64},
+	"mat4x4<f32>": {Name: "mat4x4<f32>", AlignOf: 16, SizeOf: 64},
+}
+
+// packedTypeMap maps Go types which are stored as a packed u32 in WGSL.
+var packedTypeMap = map[GoTypeName]Type{
+	"github.com/hulkholden/gowebgpu/common/vmath.Unorm8x4":  {Name: "u32", AlignOf: 4, SizeOf: 4, Packing: "unorm8x4"},
+	"github.com/hulkholden/gowebgpu/common/vmath.Snorm8x4":  {Name: "u32", AlignOf: 4, SizeOf: 4, Packing: "snorm8x4"},
+	"github.com/hulkholden/gowebgpu/common/vmath.Unorm16x2": {Name: "u32", AlignOf: 4, SizeOf: 4, Packing: "unorm16x2"},
+	"github.com/hulkholden/gowebgpu/common/vmath.Snorm16x2": {Name: "u32", AlignOf: 4, SizeOf: 4, Packing: "snorm16x2"},
 }
 
+var f16Regexp = regexp.MustCompile(`\bf16\b`)
+
 // registeredGoStructs stores all the Go types that have been registered.
 var registeredGoStructs = map[GoTypeName]Struct{}
 
@@ -43,8 +99,29 @@ type Type struct {
 	AlignOf int
 	// Size if the WGSL type (see https://www.w3.org/TR/WGSL/#sizeof).
 	SizeOf int
+	// Packing describes how the components of a packed u32 are stored (e.g. "unorm8x4").
+	// It is empty for types which aren't packed.
+	Packing string
+	// Kind describes what sort of type this is.
+	Kind Kind
+	// Elem is the element type of array and atomic types.
+	Elem *Type
+	// GoName is the name of the Go struct for struct types.
+	GoName GoTypeName
 }
 
+// Kind describes the category of a WGSL type.
+type Kind int
+
+const (
+	// KindBuiltin is used for scalar, vector and matrix types.
+	KindBuiltin Kind = iota
+	KindStruct
+	KindArray
+	KindRuntimeArray
+	KindAtomic
+)
+
 // A Struct provides information about a Go struct.
 type Struct struct {
 	// Name is the name of the struct as it appears in WGSL.
@@ -54,6 +131,13 @@ type Struct struct {
 	// Size of the structure, in bytes.
 	Size int
 
+	// AlignOf is the alignment of the struct in WGSL, i.e. the maximum
+	// alignment of its fields (see https://www.w3.org/TR/WGSL/#alignment-and-size).
+	AlignOf int
+	// SizeOf is the size of the struct in WGSL. This can be larger than Size
+	// if the Go struct is missing trailing padding.
+	SizeOf int
+
 	// Fields is a slice of the struct's fields, in declaration order.
 	Fields []string
 	// FieldMap maps field names to Fields.
@@ -85,6 +169,39 @@ func RegisterStruct[T any]() (Struct, error) {
 	var t T
 	structType := reflect.TypeOf(t)
 
+	s, err := newStruct(structType)
+	if err != nil {
+		return Struct{}, err
+	}
+
+	layout := s.Layout(AddressSpaceStorage)
+	for i, fl := range layout.Fields {
+		field := structType.Field(i)
+		if err := validateOffset(field, fl.Type); err != nil {
+			return Struct{}, err
+		}
+		if err := validateWGSLOffset(field, fl.Type, fl.WGSLOffset); err != nil {
+			return Struct{}, err
+		}
+		if field.Type.Kind() == reflect.Array {
+			if err := validateArrayStride(field, field.Type.Elem(), fl.Type, field.Type.Len()); err != nil {
+				return Struct{}, err
+			}
+		}
+	}
+	if err := validateSize(layout); err != nil {
+		return Struct{}, err
+	}
+	s.AlignOf = layout.AlignOf
+	s.SizeOf = layout.SizeOf
+
+	registeredGoStructs[s.GoName] = s
+	return s, nil
+}
+
+// newStruct returns a Struct describing the Go struct type.
+// It doesn't check that the Go layout is compatible with WGSL.
+func newStruct(structType reflect.Type) (Struct, error) {
 	if structType.Kind() != reflect.Struct {
 		return Struct{}, fmt.Errorf("provided type is not a struct")
 	}
@@ -95,32 +212,12 @@ func RegisterStruct[T any]() (Struct, error) {
 		Size:     int(structType.Size()),
 		FieldMap: make(map[string]Field),
 	}
-
 	for i := 0; i < structType.NumField(); i++ {
 		field := structType.Field(i)
-
-		arrayLen := 0
-		fieldType := field.Type
-		runtimeArray := false
-		if fieldType.Kind() == reflect.Array {
-			arrayLen = fieldType.Len()
-			fieldType = fieldType.Elem()
-			runtimeArray = field.Tag.Get("runtimeArray") == "true"
-
-			// TODO: if this is a runtimeArray then verify it's the last field in the struct.
-		}
-
-		fieldTypeName := makeGoTypeName(fieldType)
-		isAtomic := field.Tag.Get("atomic") == "true"
-		wgslType, ok := lookupWGSLType(fieldTypeName, isAtomic, arrayLen, runtimeArray)
-		if !ok {
-			return Struct{}, fmt.Errorf("unhandled type: %q", fieldType.String())
-		}
-
-		if err := validateOffset(field, wgslType); err != nil {
+		wgslType, err := lookupFieldType(field)
+		if err != nil {
 			return Struct{}, err
 		}
-
 		s.Fields = append(s.Fields, field.Name)
 		s.FieldMap[field.Name] = Field{
 			Name:     field.Name,
@@ -128,12 +225,38 @@ func RegisterStruct[T any]() (Struct, error) {
 			WGSLType: wgslType,
 		}
 	}
-
-	registeredGoStructs[s.GoName] = s
 	return s, nil
 }
 
-// TODO: add test coverage for this.
+// LookupType returns the WGSL type to use for T.
+// If T is a struct it must have been registered.
+func LookupType[T any]() (Type, error) {
+	var t T
+	return lookupFieldType(reflect.StructField{Type: reflect.TypeOf(t)})
+}
+
+// lookupFieldType returns the WGSL type to use for a Go struct field.
+func lookupFieldType(field reflect.StructField) (Type, error) {
+	arrayLen := 0
+	fieldType := field.Type
+	runtimeArray := false
+	if fieldType.Kind() == reflect.Array {
+		arrayLen = fieldType.Len()
+		fieldType = fieldType.Elem()
+		runtimeArray = field.Tag.Get("runtimeArray") == "true"
+
+		// TODO: if this is a runtimeArray then verify it's the last field in the struct.
+	}
+
+	fieldTypeName := makeGoTypeName(fieldType)
+	isAtomic := field.Tag.Get("atomic") == "true"
+	wgslType, ok := lookupWGSLType(fieldTypeName, isAtomic, arrayLen, runtimeArray)
+	if !ok {
+		return Type{}, fmt.Errorf("unhandled type: %q", fieldType.String())
+	}
+	return wgslType, nil
+}
+
 func validateOffset(field reflect.StructField, wgslType Type) error {
 	if (field.Offset % uintptr(wgslType.AlignOf)) != 0 {
 		return fmt.Errorf("incompatible offset for field %q: Go offset is %d but wgsl requires aligment of %d bytes for fields of type %q", field.Name, field.Offset, wgslType.AlignOf, wgslType.Name)
@@ -141,6 +264,41 @@ func validateOffset(field reflect.StructField, wgslType Type) error {
 	return nil
 }
 
+// validateWGSLOffset checks that the field is placed at the same offset in Go as it would be in WGSL.
+// This catches fields which follow a nested struct that has no trailing padding in Go.
+func validateWGSLOffset(field reflect.StructField, wgslType Type, wgslOffset int) error {
+	if int(field.Offset) != wgslOffset {
+		return fmt.Errorf("incompatible offset for field %q: Go offset is %d but wgsl offset is %d for fields of type %q", field.Name, field.Offset, wgslOffset, wgslType.Name)
+	}
+	return nil
+}
+
+// validateArrayStride checks that the elements of an array field are spaced the same in Go and WGSL.
+func validateArrayStride(field reflect.StructField, elemType reflect.Type, arrayType Type, arrayLen int) error {
+	goStride := int(elemType.Size())
+	wgslStride := arrayType.SizeOf / arrayLen
+	if goStride != wgslStride {
+		return fmt.Errorf("incompatible array stride for field %q: Go stride is %d but wgsl stride is %d for elements of type %q", field.Name, goStride, wgslStride, elemType.String())
+	}
+	return nil
+}
+
+// validateSize returns an error if the Go struct's size differs from its WGSL size, as elements of a []T
+// would then have a different stride in Go and in WGSL.
+// Structs ending in a runtime-sized array are skipped, as their size depends on the buffer they're bound to.
+func validateSize(layout Layout) error {
+	if n := len(layout.Fields); n > 0 && layout.Fields[n-1].Type.Kind == KindRuntimeArray {
+		return nil
+	}
+	if missing := layout.MissingPadding(); missing > 0 {
+		return fmt.Errorf("incompatible size: Go size is %d but wgsl size is %d, so %d bytes of trailing padding are missing", layout.GoSize, layout.SizeOf, missing)
+	}
+	if layout.GoSize != layout.SizeOf {
+		return fmt.Errorf("incompatible size: Go size is %d but wgsl size is %d", layout.GoSize, layout.SizeOf)
+	}
+	return nil
+}
+
 func (s Struct) String() string {
 	var output strings.Builder
 	output.WriteString(fmt.Sprintf("struct %q, size %d\n", s.GoName, s.Size))
@@ -163,6 +321,17 @@ func (s Struct) ToWGSL() string {
 	return output.String()
 }
 
+// UsesF16 returns true if any of the struct's fields use f16 types.
+// Shaders which declare such a struct must start with `enable f16;`.
+func (s Struct) UsesF16() bool {
+	for _, f := range s.FieldMap {
+		if f16Regexp.MatchString(string(f.WGSLType.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
 // MustOffsetOf returns the offset of the specified field.
 // Panics if the field is not found.
 func (s *Struct) MustOffsetOf(fieldName string) int {
@@ -203,6 +372,9 @@ func lookupWGSLType(goTypeName GoTypeName, isAtomic bool, arrayLen int, runtimeA
 }
 
 func lookupPrimitiveWGSLType(goTypeName GoTypeName) (Type, bool) {
+	if wgslType, ok := packedTypeMap[goTypeName]; ok {
+		return wgslType, true
+	}
 	if tn, ok := goToTypeMap[goTypeName]; ok {
 		if wgslType, ok := builtinTypeMap[tn]; ok {
 			return wgslType, true
@@ -217,8 +389,10 @@ func lookupPrimitiveWGSLType(goTypeName GoTypeName) (Type, bool) {
 
 	wgslType := Type{
 		Name:    s.Name,
-		AlignOf: s.Size, // TODO: this should be max(fieldAlignment)
-		SizeOf:  s.Size,
+		AlignOf: s.AlignOf,
+		SizeOf:  s.SizeOf,
+		Kind:    KindStruct,
+		GoName:  s.GoName,
 	}
 	return wgslType, true
 }
@@ -228,6 +402,8 @@ func makeAtomic(t Type) Type {
 		Name:    TypeName(fmt.Sprintf("atomic<%s>", t.Name)),
 		AlignOf: t.AlignOf,
 		SizeOf:  t.SizeOf,
+		Kind:    KindAtomic,
+		Elem:    &t,
 	}
 }
 
@@ -235,7 +411,9 @@ func makeRuntimeArray(t Type, n int) Type {
 	return Type{
 		Name:    TypeName(fmt.Sprintf("array<%s>", t.Name)),
 		AlignOf: t.AlignOf,
-		SizeOf:  t.SizeOf * n,
+		SizeOf:  arrayStride(t.AlignOf, t.SizeOf) * n,
+		Kind:    KindRuntimeArray,
+		Elem:    &t,
 	}
 }
 
@@ -243,6 +421,18 @@ func makeArray(t Type, n int) Type {
 	return Type{
 		Name:    TypeName(fmt.Sprintf("array<%s, %d>", t.Name, n)),
 		AlignOf: t.AlignOf,
-		SizeOf:  t.SizeOf * n,
+		SizeOf:  arrayStride(t.AlignOf, t.SizeOf) * n,
+		Kind:    KindArray,
+		Elem:    &t,
 	}
 }
+
+// arrayStride returns the distance between elements of an array (see https://www.w3.org/TR/WGSL/#array-layout-rules).
+func arrayStride(alignOf, sizeOf int) int {
+	return roundUp(alignOf, sizeOf)
+}
+
+// roundUp rounds n up to the next multiple of k (see https://www.w3.org/TR/WGSL/#roundup).
+func roundUp(k, n int) int {
+	return ((n + k - 1) / k) * k
+}
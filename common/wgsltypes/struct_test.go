@@ -34,9 +34,11 @@ func TestNewStruct(t *testing.T) {
 		t.Fatalf("NewStruct() = %v, want nil error", err)
 	}
 	want := Struct{
-		Name:   "testStruct",
-		GoName: "github.com/hulkholden/gowebgpu/common/wgsltypes.testStruct",
-		Size:   76,
+		Name:    "testStruct",
+		GoName:  "github.com/hulkholden/gowebgpu/common/wgsltypes.testStruct",
+		Size:    76,
+		AlignOf: 16,
+		SizeOf:  80,
 		Fields: []string{
 			"vec4",
 			"vec3",
@@ -89,22 +91,22 @@ func TestNewStruct(t *testing.T) {
 			"atomicInt32Val": {
 				Name:     "atomicInt32Val",
 				Offset:   52,
-				WGSLType: Type{Name: "atomic<i32>", AlignOf: 4, SizeOf: 4},
+				WGSLType: Type{Name: "atomic<i32>", AlignOf: 4, SizeOf: 4, Kind: KindAtomic, Elem: &Type{Name: "i32", AlignOf: 4, SizeOf: 4}},
 			},
 			"atomicUint32Val": {
 				Name:     "atomicUint32Val",
 				Offset:   56,
-				WGSLType: Type{Name: "atomic<u32>", AlignOf: 4, SizeOf: 4},
+				WGSLType: Type{Name: "atomic<u32>", AlignOf: 4, SizeOf: 4, Kind: KindAtomic, Elem: &Type{Name: "u32", AlignOf: 4, SizeOf: 4}},
 			},
 			"arrayInt32Val": {
 				Name:     "arrayInt32Val",
 				Offset:   60,
-				WGSLType: Type{Name: "array<i32, 2>", AlignOf: 4, SizeOf: 8},
+				WGSLType: Type{Name: "array<i32, 2>", AlignOf: 4, SizeOf: 8, Kind: KindArray, Elem: &Type{Name: "i32", AlignOf: 4, SizeOf: 4}},
 			},
 			"runtimeArrayInt32Val": {
 				Name:     "runtimeArrayInt32Val",
 				Offset:   68,
-				WGSLType: Type{Name: "array<i32>", AlignOf: 4, SizeOf: 8},
+				WGSLType: Type{Name: "array<i32>", AlignOf: 4, SizeOf: 8, Kind: KindRuntimeArray, Elem: &Type{Name: "i32", AlignOf: 4, SizeOf: 4}},
 			},
 		},
 	}
@@ -302,3 +304,305 @@ func TestRegisterNestedStruct(t *testing.T) {
 		t.Errorf("nested field WGSL type = %q, want 'nestedInner'", innerField.WGSLType.Name)
 	}
 }
+
+// paddedInner has a vec2 so must be 8 byte aligned in WGSL, and includes trailing padding.
+type paddedInner struct {
+	v   vmath.V2
+	f   float32
+	pad uint32
+}
+
+// unpaddedInner is 12 bytes in Go but WGSL rounds its size up to 16.
+type unpaddedInner struct {
+	v vmath.V2
+	f float32
+}
+
+type vec4Inner struct {
+	v vmath.V4
+}
+
+type nestedPaddedOuter struct {
+	f     float32
+	pad   uint32
+	inner paddedInner
+	g     float32
+	pad2  uint32
+}
+
+// unpaddedVec3 is 12 bytes in Go but WGSL rounds its size up to the vec3's alignment of 16.
+type unpaddedVec3 struct {
+	p vmath.V3
+}
+
+type nestedMisalignedOuter struct {
+	f     float32
+	inner vec4Inner
+}
+
+type arrayOfPaddedOuter struct {
+	elems [4]paddedInner
+}
+
+type runtimeArrayOfPaddedOuter struct {
+	count uint32 `atomic:"true"`
+	pad   uint32
+	elems [4]paddedInner `runtimeArray:"true"`
+}
+
+type arrayOfVec3Outer struct {
+	elems [2]vmath.V3
+}
+
+func TestRegisterStructLayout(t *testing.T) {
+	MustRegisterStruct[paddedInner]()
+	MustRegisterStruct[vec4Inner]()
+
+	tests := []struct {
+		name        string
+		register    func() (Struct, error)
+		wantAlignOf int
+		wantSizeOf  int
+		wantErr     string
+	}{
+		{
+			name:        "padded inner",
+			register:    RegisterStruct[paddedInner],
+			wantAlignOf: 8,
+			wantSizeOf:  16,
+		},
+		{
+			name:     "missing trailing padding",
+			register: RegisterStruct[unpaddedInner],
+			wantErr:  "Go size is 12 but wgsl size is 16, so 4 bytes of trailing padding are missing",
+		},
+		{
+			name:     "vec3 without trailing padding",
+			register: RegisterStruct[unpaddedVec3],
+			wantErr:  "Go size is 12 but wgsl size is 16, so 4 bytes of trailing padding are missing",
+		},
+		{
+			name:        "vec4 inner",
+			register:    RegisterStruct[vec4Inner],
+			wantAlignOf: 16,
+			wantSizeOf:  16,
+		},
+		{
+			name:        "nested struct aligned to max field alignment",
+			register:    RegisterStruct[nestedPaddedOuter],
+			wantAlignOf: 8,
+			wantSizeOf:  32,
+		},
+		{
+			name:     "misaligned nested struct",
+			register: RegisterStruct[nestedMisalignedOuter],
+			wantErr:  "requires aligment of 16 bytes",
+		},
+		{
+			name:        "array of padded structs",
+			register:    RegisterStruct[arrayOfPaddedOuter],
+			wantAlignOf: 8,
+			wantSizeOf:  64,
+		},
+		{
+			name:        "runtime array of padded structs",
+			register:    RegisterStruct[runtimeArrayOfPaddedOuter],
+			wantAlignOf: 8,
+			wantSizeOf:  72,
+		},
+		{
+			name:     "array of vec3",
+			register: RegisterStruct[arrayOfVec3Outer],
+			wantErr:  "Go stride is 12 but wgsl stride is 16",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.register()
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("RegisterStruct() succeeded, want error containing %q", tc.wantErr)
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("RegisterStruct() error = %q, want it to contain %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RegisterStruct() = %v, want nil error", err)
+			}
+			if got.AlignOf != tc.wantAlignOf {
+				t.Errorf("AlignOf = %d, want %d", got.AlignOf, tc.wantAlignOf)
+			}
+			if got.SizeOf != tc.wantSizeOf {
+				t.Errorf("SizeOf = %d, want %d", got.SizeOf, tc.wantSizeOf)
+			}
+		})
+	}
+}
+
+type matrixStruct struct {
+	m2   vmath.M2
+	m4x2 vmath.M4x2
+	m3   vmath.M3
+	m2x3 vmath.M2x3
+	m4   vmath.M4
+	m3x2 vmath.M3x2
+	f    float32
+	pad  uint32
+}
+
+type misalignedMatrixStruct struct {
+	f  float32
+	m3 vmath.M3
+}
+
+func TestRegisterMatrixStruct(t *testing.T) {
+	s, err := RegisterStruct[matrixStruct]()
+	if err != nil {
+		t.Fatalf("RegisterStruct[matrixStruct]() = %v", err)
+	}
+	got := s.ToWGSL()
+	want := `struct matrixStruct {
+  m2 : mat2x2<f32>,
+  m4x2 : mat4x2<f32>,
+  m3 : mat3x3<f32>,
+  m2x3 : mat2x3<f32>,
+  m4 : mat4x4<f32>,
+  m3x2 : mat3x2<f32>,
+  f : f32,
+  pad : u32,
+}
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diff mismatch (-want +got):\n%s", diff)
+	}
+	if s.AlignOf != 16 {
+		t.Errorf("AlignOf = %d, want 16", s.AlignOf)
+	}
+	if s.SizeOf != 224 {
+		t.Errorf("SizeOf = %d, want 224", s.SizeOf)
+	}
+
+	if _, err := RegisterStruct[misalignedMatrixStruct](); err == nil {
+		t.Errorf("RegisterStruct[misalignedMatrixStruct]() succeeded, want alignment error")
+	}
+}
+
+type intVectorStruct struct {
+	v4i vmath.V4i
+	v4u vmath.V4u
+	v3i vmath.V3i
+	i   int32
+	v3u vmath.V3u
+	u   uint32
+	v2i vmath.V2i
+	v2u vmath.V2u
+}
+
+func TestRegisterIntVectorStruct(t *testing.T) {
+	s, err := RegisterStruct[intVectorStruct]()
+	if err != nil {
+		t.Fatalf("RegisterStruct[intVectorStruct]() = %v", err)
+	}
+	tests := []struct {
+		field      string
+		wantType   Type
+		wantOffset uintptr
+	}{
+		{field: "v4i", wantType: Type{Name: "vec4<i32>", AlignOf: 16, SizeOf: 16}, wantOffset: 0},
+		{field: "v4u", wantType: Type{Name: "vec4<u32>", AlignOf: 16, SizeOf: 16}, wantOffset: 16},
+		{field: "v3i", wantType: Type{Name: "vec3<i32>", AlignOf: 16, SizeOf: 12}, wantOffset: 32},
+		{field: "i", wantType: Type{Name: "i32", AlignOf: 4, SizeOf: 4}, wantOffset: 44},
+		{field: "v3u", wantType: Type{Name: "vec3<u32>", AlignOf: 16, SizeOf: 12}, wantOffset: 48},
+		{field: "u", wantType: Type{Name: "u32", AlignOf: 4, SizeOf: 4}, wantOffset: 60},
+		{field: "v2i", wantType: Type{Name: "vec2<i32>", AlignOf: 8, SizeOf: 8}, wantOffset: 64},
+		{field: "v2u", wantType: Type{Name: "vec2<u32>", AlignOf: 8, SizeOf: 8}, wantOffset: 72},
+	}
+	for _, tc := range tests {
+		f := s.FieldMap[tc.field]
+		if diff := cmp.Diff(tc.wantType, f.WGSLType); diff != "" {
+			t.Errorf("field %q type mismatch (-want +got):\n%s", tc.field, diff)
+		}
+		if f.Offset != tc.wantOffset {
+			t.Errorf("field %q offset = %d, want %d", tc.field, f.Offset, tc.wantOffset)
+		}
+	}
+	if s.SizeOf != 80 {
+		t.Errorf("SizeOf = %d, want 80", s.SizeOf)
+	}
+}
+
+type halfStruct struct {
+	h   vmath.F16
+	h2  vmath.F16
+	v2h vmath.V2h
+	v4h vmath.V4h
+	col vmath.Unorm8x4
+	nrm vmath.Snorm16x2
+}
+
+func TestRegisterHalfAndPackedStruct(t *testing.T) {
+	s, err := RegisterStruct[halfStruct]()
+	if err != nil {
+		t.Fatalf("RegisterStruct[halfStruct]() = %v", err)
+	}
+	tests := []struct {
+		field string
+		want  Type
+	}{
+		{field: "h", want: Type{Name: "f16", AlignOf: 2, SizeOf: 2}},
+		{field: "v2h", want: Type{Name: "vec2<f16>", AlignOf: 4, SizeOf: 4}},
+		{field: "v4h", want: Type{Name: "vec4<f16>", AlignOf: 8, SizeOf: 8}},
+		{field: "col", want: Type{Name: "u32", AlignOf: 4, SizeOf: 4, Packing: "unorm8x4"}},
+		{field: "nrm", want: Type{Name: "u32", AlignOf: 4, SizeOf: 4, Packing: "snorm16x2"}},
+	}
+	for _, tc := range tests {
+		if diff := cmp.Diff(tc.want, s.FieldMap[tc.field].WGSLType); diff != "" {
+			t.Errorf("field %q type mismatch (-want +got):\n%s", tc.field, diff)
+		}
+	}
+	if !s.UsesF16() {
+		t.Errorf("UsesF16() = false, want true")
+	}
+}
+
+func TestUsesF16(t *testing.T) {
+	s, err := RegisterStruct[testStruct]()
+	if err != nil {
+		t.Fatalf("RegisterStruct failed: %v", err)
+	}
+	if s.UsesF16() {
+		t.Errorf("UsesF16() = true, want false")
+	}
+}
+
+func TestLookupType(t *testing.T) {
+	MustRegisterStruct[simpleStruct]()
+
+	tests := []struct {
+		name   string
+		lookup func() (Type, error)
+		want   TypeName
+	}{
+		{name: "scalar", lookup: LookupType[uint32], want: "u32"},
+		{name: "vector", lookup: LookupType[vmath.V2], want: "vec2<f32>"},
+		{name: "array", lookup: LookupType[[4]vmath.V4], want: "array<vec4<f32>, 4>"},
+		{name: "struct", lookup: LookupType[simpleStruct], want: "simpleStruct"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.lookup()
+			if err != nil {
+				t.Fatalf("LookupType() = %v", err)
+			}
+			if got.Name != tc.want {
+				t.Errorf("LookupType().Name = %q, want %q", got.Name, tc.want)
+			}
+		})
+	}
+
+	if _, err := LookupType[float64](); err == nil {
+		t.Errorf("LookupType[float64]() succeeded, want error")
+	}
+}
@@ -1,7 +1,18 @@
+// showError displays msg on the page. Messages are appended, so errors from
+// several sources (e.g. each shader module that fails to compile) are all shown.
 function showError(msg) {
   const el = document.getElementById("error");
   if (el) {
-    el.textContent = msg;
+    el.textContent += (el.textContent ? "\n" : "") + msg;
+    el.style.display = "block";
+  }
+}
+
+// showOverlay displays text over the canvas, replacing whatever it last showed.
+function showOverlay(text) {
+  const el = document.getElementById("overlay");
+  if (el) {
+    el.textContent = text;
     el.style.display = "block";
   }
 }
@@ -17,7 +28,16 @@ async function init() {
     showError("Couldn't request WebGPU adapter.");
     return;
   }
-  const device = await adapter.requestDevice();
+  // shader-f16 is needed for storage structs which use f16 types.
+  const requiredFeatures = [];
+  if (adapter.features.has("shader-f16")) {
+    requiredFeatures.push("shader-f16");
+  }
+  // timestamp-query is needed to time passes on the GPU. Without it they run untimed.
+  if (adapter.features.has("timestamp-query")) {
+    requiredFeatures.push("timestamp-query");
+  }
+  const device = await adapter.requestDevice({ requiredFeatures });
 
   const canvas = document.querySelector("#display");
   const context = canvas.getContext("webgpu");
@@ -40,8 +60,9 @@ async function init() {
   };
 }
 
-// Expose showError to Go/WASM so it can display errors in the UI.
+// Expose showError and showOverlay to Go/WASM so they can display errors and stats in the UI.
 window.showError = showError;
+window.showOverlay = showOverlay;
 
 // Capture unhandled errors (e.g. WASM panics).
 window.addEventListener("error", (e) => {